@@ -5,12 +5,15 @@ import (
 	"errors"
 	"log"
 	"net/http"
+	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
 	"github.com/scaleway/audit-sentinel/internal/api"
 	"github.com/scaleway/audit-sentinel/internal/config"
+	"github.com/scaleway/audit-sentinel/internal/metrics"
+	"github.com/scaleway/audit-sentinel/internal/tracing"
 )
 
 func main() {
@@ -20,6 +23,11 @@ func main() {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Observability)
+	if err != nil {
+		log.Fatalf("Failed to set up tracing: %v", err)
+	}
+
 	server, err := api.NewServer(cfg)
 	if err != nil {
 		log.Fatalf("Failed to create server: %v", err)
@@ -29,18 +37,69 @@ func main() {
 	defer stop()
 
 	go func() {
-		log.Printf("Server starting on %s:%s", cfg.Server.Host, cfg.Server.Port)
+		scheme := "http"
+		if cfg.Server.TLSEnabled() {
+			scheme = "https"
+		}
+		log.Printf("Server starting on %s://%s:%s", scheme, cfg.Server.Host, cfg.Server.Port)
 		if err := server.Start(); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Server failed to start: %v", err)
 		}
 	}()
 
+	var metricsServer *http.Server
+	if cfg.Observability.PrometheusEnabled {
+		metricsServer = metrics.NewServer(cfg.Observability.PrometheusPort)
+		go func() {
+			log.Printf("Metrics server starting on :%d", cfg.Observability.PrometheusPort)
+			if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Printf("Metrics server failed: %v", err)
+			}
+		}()
+	}
+
 	go func() {
-		if err := server.StartIngestion(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		if err := server.StartIngestion(ctx); err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, api.ErrShuttingDown) {
 			log.Printf("Ingestion stopped unexpectedly: %v", err)
 		}
 	}()
 
+	go func() {
+		if err := server.StartRetention(ctx); err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, api.ErrShuttingDown) {
+			log.Printf("Retention janitor stopped unexpectedly: %v", err)
+		}
+	}()
+
+	go func() {
+		if err := server.StartDBHealth(ctx); err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, api.ErrShuttingDown) {
+			log.Printf("Database health checker stopped unexpectedly: %v", err)
+		}
+	}()
+
+	go func() {
+		if err := server.StartDetectionRetry(ctx); err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, api.ErrShuttingDown) {
+			log.Printf("Detection retrier stopped unexpectedly: %v", err)
+		}
+	}()
+
+	go func() {
+		if err := server.StartThreatIntel(ctx); err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, api.ErrShuttingDown) {
+			log.Printf("Threat intel refresh stopped unexpectedly: %v", err)
+		}
+	}()
+
+	// SIGHUP reloads detection thresholds in place
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	defer signal.Stop(reload)
+	go func() {
+		for range reload {
+			if _, err := server.ReloadConfig(); err != nil {
+				log.Printf("Config reload rejected: %v", err)
+			}
+		}
+	}()
+
 	<-ctx.Done()
 
 	log.Println("Shutting down server...")
@@ -52,5 +111,18 @@ func main() {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
 
+	// Block until ingestion and other background workers have stopped
+	<-server.Done()
+
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(ctx); err != nil {
+			log.Printf("Metrics server shutdown failed: %v", err)
+		}
+	}
+
+	if err := shutdownTracing(ctx); err != nil {
+		log.Printf("Tracing shutdown failed: %v", err)
+	}
+
 	log.Println("Server exited")
 }
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	"github.com/scaleway/audit-sentinel/internal/config"
 	"github.com/scaleway/audit-sentinel/internal/storage"
@@ -12,8 +13,9 @@ import (
 
 func main() {
 	var (
-		command = flag.String("command", "", "Migration command: up, down, create")
-		name    = flag.String("name", "", "Migration name (for create)")
+		command = flag.String("command", "", "Migration command: up, down, down-to, status, version, create")
+		name    = flag.String("name", "", "Migration name (for create, or target for down-to)")
+		dir     = flag.String("dir", "", "Read migrations from this directory instead of the embedded set")
 	)
 	flag.Parse()
 
@@ -28,6 +30,10 @@ func main() {
 	}
 	defer migrator.Close()
 
+	if *dir != "" {
+		migrator.UseDirectory(*dir)
+	}
+
 	switch *command {
 	case "up":
 		if err := migrator.Up(); err != nil {
@@ -39,6 +45,36 @@ func main() {
 			log.Fatalf("Migration down failed: %v", err)
 		}
 		fmt.Println("Migrations rolled back successfully")
+	case "down-to":
+		if *name == "" {
+			log.Fatal("Target migration name is required for down-to command")
+		}
+		if err := migrator.DownTo(*name); err != nil {
+			log.Fatalf("Migration down-to failed: %v", err)
+		}
+		fmt.Printf("Rolled back to migration %s\n", *name)
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			log.Fatalf("Migration status failed: %v", err)
+		}
+		for _, status := range statuses {
+			if status.Applied {
+				fmt.Printf("[applied] %s (%s)\n", status.Name, status.AppliedAt.Format(time.RFC3339))
+			} else {
+				fmt.Printf("[pending] %s\n", status.Name)
+			}
+		}
+	case "version":
+		version, err := migrator.Version()
+		if err != nil {
+			log.Fatalf("Migration version failed: %v", err)
+		}
+		if version == "" {
+			fmt.Println("No migrations applied")
+		} else {
+			fmt.Println(version)
+		}
 	case "create":
 		if *name == "" {
 			log.Fatal("Migration name is required for create command")
@@ -48,7 +84,7 @@ func main() {
 		}
 		fmt.Printf("Migration created: %s\n", *name)
 	default:
-		fmt.Fprintf(os.Stderr, "Usage: %s -command [up|down|create] [-name migration_name]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s -command [up|down|down-to|status|version|create] [-name migration_name]\n", os.Args[0])
 		os.Exit(1)
 	}
 }
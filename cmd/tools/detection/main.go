@@ -19,7 +19,7 @@ func main() {
 	}
 
 	// Initialize storage
-	store, err := storage.NewStorage(cfg.Database.URL)
+	store, err := storage.NewStorage(cfg.Database)
 	if err != nil {
 		log.Fatalf("Failed to create storage: %v", err)
 	}
@@ -29,18 +29,27 @@ func main() {
 
 	// Create detection storage and engine
 	detectionStorage := detection.NewDetectionStorage(store.DB())
-	detectionEngine := detection.NewEngine(cfg, detectionStorage)
+	detectionEngine, err := detection.NewEngine(cfg, detectionStorage)
+	if err != nil {
+		log.Fatalf("Failed to create detection engine: %v", err)
+	}
 
 	// Create processor
 	processor := ingestion.NewProcessor(detectionEngine)
 
-	// Create Scaleway client (mock or real)
+	// Create Scaleway client (mock when SCALEWAY_MOCK is set)
 	client := scaleway.NewClient(
 		cfg.Scaleway.APIKey,
 		cfg.Scaleway.ProjectID,
 		cfg.Scaleway.OrganizationID,
 		cfg.Scaleway.APIURL,
 	)
+	client.SetMock(cfg.Scaleway.Mock)
+	client.SetEndpoints(scaleway.Endpoints{
+		Audit:          scaleway.Endpoint{Path: cfg.Scaleway.AuditEventsPath, ListKey: cfg.Scaleway.AuditEventsListKey},
+		Authentication: scaleway.Endpoint{Path: cfg.Scaleway.LoginLogsPath, ListKey: cfg.Scaleway.LoginLogsListKey},
+	})
+	client.SetMaxStalePages(cfg.Scaleway.MaxStalePages)
 
 	// Create ingestor
 	ingestor := ingestion.NewIngestor(cfg, client, eventRepo)
@@ -17,7 +17,7 @@ func main() {
 	}
 
 	// Initialize storage
-	store, err := storage.NewStorage(cfg.Database.URL)
+	store, err := storage.NewStorage(cfg.Database)
 	if err != nil {
 		log.Fatalf("Failed to create storage: %v", err)
 	}
@@ -25,14 +25,18 @@ func main() {
 
 	// Create detection storage and engine
 	detectionStorage := detection.NewDetectionStorage(store.DB())
-	detectionEngine := detection.NewEngine(cfg, detectionStorage)
+	detectionEngine, err := detection.NewEngine(cfg, detectionStorage)
+	if err != nil {
+		log.Fatalf("Failed to create detection engine: %v", err)
+	}
 
 	// Get event repository
 	eventRepo := storage.NewEventRepository(store.DB())
 
-	// Get all events from database
+	// Get events from database, oldest first so rules see them in the order
+	// they occurred
 	ctx := context.Background()
-	events, err := eventRepo.ListEvents(ctx, 100, 0, "", "")
+	events, err := eventRepo.ListEvents(ctx, 100, 0, storage.EventFilter{}, storage.EventOrderTimestampAsc)
 	if err != nil {
 		log.Fatalf("Failed to list events: %v", err)
 	}
@@ -53,7 +57,7 @@ func main() {
 
 	// Count alerts
 	alertRepo := storage.NewAlertRepository(store.DB())
-	alerts, err := alertRepo.ListAlerts(ctx, 100, 0, "", "", "")
+	alerts, err := alertRepo.ListAlerts(ctx, 100, 0, storage.AlertFilter{}, storage.AlertOrderCreatedDesc)
 	if err != nil {
 		log.Printf("Failed to list alerts: %v", err)
 	}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"time"
+
+	"github.com/scaleway/audit-sentinel/internal/config"
+	"github.com/scaleway/audit-sentinel/internal/storage"
+)
+
+func main() {
+	days := flag.Int("days", 0, "Delete events older than this many days (defaults to EVENT_RETENTION_DAYS)")
+	flag.Parse()
+
+	// Load config
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	retentionDays := cfg.Retention.EventRetentionDays
+	if *days > 0 {
+		retentionDays = *days
+	}
+	if retentionDays <= 0 {
+		log.Fatal("No retention period set: pass -days or set EVENT_RETENTION_DAYS")
+	}
+
+	// Initialize storage
+	store, err := storage.NewStorage(cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	eventRepo := storage.NewEventRepository(store.DB())
+
+	cutoff := time.Now().AddDate(0, 0, -retentionDays)
+	log.Printf("Purging events older than %s (%d days)", cutoff.Format(time.RFC3339), retentionDays)
+
+	deleted, err := eventRepo.DeleteEventsBefore(context.Background(), cutoff)
+	if err != nil {
+		log.Fatalf("Failed to purge events: %v", err)
+	}
+
+	log.Printf("Purged %d events", deleted)
+}
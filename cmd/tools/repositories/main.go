@@ -18,7 +18,7 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
-	store, err := storage.NewStorage(cfg.Database.URL)
+	store, err := storage.NewStorage(cfg.Database)
 	if err != nil {
 		log.Fatalf("Failed to create storage: %v", err)
 	}
@@ -101,7 +101,7 @@ func main() {
 	fmt.Printf("Alert retrieved: %s - %s\n", retrieved.AlertType, retrieved.Severity)
 
 	// List alerts
-	alerts, err := alertRepo.ListAlerts(ctx, 10, 0, "", "", "")
+	alerts, err := alertRepo.ListAlerts(ctx, 10, 0, storage.AlertFilter{}, storage.AlertOrderCreatedDesc)
 	if err != nil {
 		log.Fatalf("Failed to list alerts: %v", err)
 	}
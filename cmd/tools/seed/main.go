@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/scaleway/audit-sentinel/internal/config"
+	"github.com/scaleway/audit-sentinel/internal/detection"
+	"github.com/scaleway/audit-sentinel/internal/ingestion"
+	"github.com/scaleway/audit-sentinel/internal/models"
+	"github.com/scaleway/audit-sentinel/internal/storage"
+)
+
+// seeder builds demo events spread over [start, end)
+type seeder struct {
+	rng    *rand.Rand
+	start  time.Time
+	end    time.Time
+	events []*models.Event
+}
+
+func main() {
+	bruteForce := flag.Int("brute-force", 2, "Number of brute-force bursts (failed logins followed by a success)")
+	keyCreations := flag.Int("key-creations", 1, "Number of API key creation bursts")
+	forbidden := flag.Int("forbidden", 3, "Number of forbidden accesses to sensitive resources")
+	benign := flag.Int("benign", 20, "Number of ordinary successful logins")
+	span := flag.Duration("span", 10*time.Minute, "Spread events over this period ending now; keep it within the detection windows so alerts fire")
+	seed := flag.Int64("seed", 1, "Random seed, for repeatable data")
+	clean := flag.Bool("clean", false, "Delete all events, alerts and user profiles before seeding")
+	flag.Parse()
+
+	// Load config
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Initialize storage
+	store, err := storage.NewStorage(cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	if *clean {
+		// CASCADE also clears history, remediation logs and the outbox
+		if _, err := store.DB().ExecContext(ctx, `TRUNCATE events, alerts, user_profiles CASCADE`); err != nil {
+			log.Fatalf("Failed to clean tables: %v", err)
+		}
+		log.Println("Cleaned events, alerts and user profiles")
+	}
+
+	now := time.Now()
+	s := &seeder{rng: rand.New(rand.NewSource(*seed)), start: now.Add(-*span), end: now}
+	threshold := cfg.Detection.FailedLoginThreshold
+	for n := 0; n < *bruteForce; n++ {
+		s.bruteForce(fmt.Sprintf("victim%d@example.com", n+1), threshold+1)
+	}
+	for n := 0; n < *keyCreations; n++ {
+		s.keyBurst(fmt.Sprintf("dev%d@example.com", n+1), cfg.Detection.APIKeyBurstThreshold+1)
+	}
+	for n := 0; n < *forbidden; n++ {
+		s.forbidden(fmt.Sprintf("intern%d@example.com", n%3+1))
+	}
+	for n := 0; n < *benign; n++ {
+		s.benignLogin(fmt.Sprintf("user%d@example.com", n%5+1))
+	}
+
+	// Rules look at history, so store and detect in the order events occurred
+	sort.Slice(s.events, func(i, j int) bool {
+		return s.events[i].Timestamp.Before(s.events[j].Timestamp)
+	})
+
+	eventRepo := storage.NewEventRepository(store.DB())
+	detectionEngine, err := detection.NewEngine(cfg, detection.NewDetectionStorage(store.DB()))
+	if err != nil {
+		log.Fatalf("Failed to create detection engine: %v", err)
+	}
+
+	detectionStart := time.Now()
+	for _, event := range s.events {
+		if err := eventRepo.StoreEvent(ctx, event); err != nil {
+			log.Fatalf("Failed to store event %s: %v", event.EventID, err)
+		}
+		if err := detectionEngine.ProcessEvent(ctx, event); err != nil {
+			log.Printf("Failed to process event %s: %v", event.EventID, err)
+		}
+	}
+
+	summary, err := storage.NewAlertRepository(store.DB()).Summarize(ctx, &detectionStart, nil)
+	if err != nil {
+		log.Fatalf("Failed to summarize alerts: %v", err)
+	}
+	log.Printf("Seeded %d events, raising %d alerts: %v", len(s.events), summary.Total, summary.ByType)
+}
+
+// at returns a random time in the seeding period
+func (s *seeder) at() time.Time {
+	return s.start.Add(time.Duration(s.rng.Int63n(int64(s.end.Sub(s.start)))))
+}
+
+// ip returns a random documentation-range public IP
+func (s *seeder) ip() string {
+	return fmt.Sprintf("203.0.113.%d", s.rng.Intn(254)+1)
+}
+
+func (s *seeder) add(eventType, actor, resource, ip string, at time.Time, raw map[string]any) {
+	eventID := "evt_seed_" + uuid.NewString()
+	if raw == nil {
+		raw = map[string]any{}
+	}
+	raw["event_id"] = eventID
+	raw["type"] = eventType
+	raw["actor"] = actor
+	raw["source"] = "seed"
+
+	s.events = append(s.events, &models.Event{
+		ID:        uuid.New(),
+		EventID:   eventID,
+		Raw:       raw,
+		EventType: eventType,
+		Actor:     actor,
+		Resource:  resource,
+		IP:        ip,
+		Region:    "fr-par",
+		Timestamp: at,
+		CreatedAt: time.Now(),
+	})
+}
+
+// bruteForce adds failures failed logins a few seconds apart, followed by a
+// successful login from the same IP
+func (s *seeder) bruteForce(actor string, failures int) {
+	ip := s.ip()
+	at := s.at()
+	// Keep the whole burst inside the period
+	if latest := s.end.Add(-time.Duration(failures+1) * 5 * time.Second); at.After(latest) {
+		at = latest
+	}
+	for n := 0; n < failures; n++ {
+		s.add(ingestion.EventTypeAuthFailed, actor, "iam", ip, at, map[string]any{"reason": "invalid_credentials"})
+		at = at.Add(5 * time.Second)
+	}
+	s.add(ingestion.EventTypeAuthSuccess, actor, "iam", ip, at, nil)
+}
+
+// keyBurst adds count API key creations a minute apart
+func (s *seeder) keyBurst(actor string, count int) {
+	ip := s.ip()
+	at := s.at()
+	if latest := s.end.Add(-time.Duration(count) * time.Minute); at.After(latest) {
+		at = latest
+	}
+	for n := 0; n < count; n++ {
+		s.add(ingestion.EventTypeAPIKeyCreate, actor, "iam/api-keys", ip, at, nil)
+		at = at.Add(time.Minute)
+	}
+}
+
+// forbidden adds a denied access to a sensitive resource
+func (s *seeder) forbidden(actor string) {
+	resources := []string{"secrets/db-password", "kms/keys/master", "iam/policies/admin"}
+	resource := resources[s.rng.Intn(len(resources))]
+	s.add(ingestion.EventTypeForbidden, actor, resource, s.ip(), s.at(), map[string]any{"status_code": 403})
+}
+
+// benignLogin adds an ordinary successful login
+func (s *seeder) benignLogin(actor string) {
+	s.add(ingestion.EventTypeAuthSuccess, actor, "iam", s.ip(), s.at(), nil)
+}
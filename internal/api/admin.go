@@ -0,0 +1,62 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// AdminTokenHeader carries the admin token required by destructive admin
+// endpoints, on top of the regular API authentication
+const AdminTokenHeader = "X-Admin-Token"
+
+// adminOnly rejects requests without the configured admin token. With no
+// token configured the wrapped endpoint is disabled.
+func (s *Server) adminOnly(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		expected := strings.TrimSpace(s.config.Security.AdminToken)
+		if expected == "" {
+			writeJSONError(w, http.StatusForbidden, codeForbidden, "admin endpoints are disabled (set ADMIN_TOKEN)")
+			return
+		}
+
+		token := strings.TrimSpace(r.Header.Get(AdminTokenHeader))
+		if subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+			log.Printf("Rejected admin request %s %s from %s", r.Method, r.URL.Path, actorFromContext(r.Context()))
+			writeJSONError(w, http.StatusForbidden, codeForbidden, "missing or invalid admin token")
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// eraseUserData deletes every event, alert and risk profile of a user for a
+// data erasure request. The erasure is recorded with the requesting actor.
+func (s *Server) eraseUserData(w http.ResponseWriter, r *http.Request) {
+	userID := strings.TrimSpace(mux.Vars(r)["id"])
+	if userID == "" {
+		writeJSONError(w, http.StatusBadRequest, codeInvalidParameter, "user ID is required")
+		return
+	}
+
+	ctx := r.Context()
+	actor := actorFromContext(ctx)
+	result, err := s.erasureRepo.EraseUserData(ctx, userID, actor)
+	if err != nil {
+		log.Printf("Data erasure for %s requested by %s failed: %v", userID, actor, err)
+		writeJSONError(w, http.StatusInternalServerError, codeInternal, fmt.Sprintf("Failed to erase user data: %v", err))
+		return
+	}
+
+	log.Printf("Erased data of %s at the request of %s (erasure %s): %d events, %d alerts, %d profiles",
+		userID, actor, result.ID, result.EventsDeleted, result.AlertsDeleted, result.ProfilesDeleted)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
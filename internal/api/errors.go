@@ -0,0 +1,59 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Error codes returned in the "code" field of error responses. Clients may
+// branch on these, so existing values must not change.
+const (
+	codeForbidden              = "forbidden"
+	codeUnauthorized           = "unauthorized"
+	codeInvalidRequestBody     = "invalid_request_body"
+	codeRequestTooLarge        = "request_too_large"
+	codeInvalidParameter       = "invalid_parameter"
+	codeInvalidAlertID         = "invalid_alert_id"
+	codeInvalidEventID         = "invalid_event_id"
+	codeInvalidMuteID          = "invalid_mute_id"
+	codeInvalidWindowID        = "invalid_maintenance_window_id"
+	codeInvalidStatus          = "invalid_status"
+	codeInvalidStatusChange    = "invalid_status_transition"
+	codeInvalidConfig          = "invalid_config"
+	codeAlertNotFound          = "alert_not_found"
+	codeEventNotFound          = "event_not_found"
+	codeUserProfileNotFound    = "user_profile_not_found"
+	codeMuteNotFound           = "mute_not_found"
+	codeWindowNotFound         = "maintenance_window_not_found"
+	codeUnknownAction          = "unknown_action"
+	codeMissingTarget          = "missing_remediation_target"
+	codeRemediationBlocked     = "remediation_blocked"
+	codeRemediationFailed      = "remediation_failed"
+	codeIngestionRunning       = "ingestion_running"
+	codeIngestionFailed        = "ingestion_failed"
+	codeIdempotencyKeyTooLong  = "idempotency_key_too_long"
+	codeIdempotencyKeyMismatch = "idempotency_key_mismatch"
+	codeIdempotencyInProgress  = "idempotency_key_in_progress"
+	codeUnsupportedFormat      = "unsupported_format"
+	codeRateLimited            = "rate_limited"
+	codeNotImplemented         = "not_implemented"
+	codeInternal               = "internal_error"
+)
+
+// errorBody is the JSON shape of every API error response
+type errorBody struct {
+	Error errorDetail `json:"error"`
+}
+
+type errorDetail struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// writeJSONError writes a structured error response with the given status
+func writeJSONError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorBody{Error: errorDetail{Code: code, Message: message}})
+}
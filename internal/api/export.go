@@ -0,0 +1,125 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/scaleway/audit-sentinel/internal/models"
+)
+
+// exportFlushEvery controls how many rows are written between flushes to the client
+const exportFlushEvery = 500
+
+// eventCSVHeader is the header row of the CSV export
+var eventCSVHeader = []string{
+	"id", "event_id", "event_type", "actor", "resource", "ip", "region",
+	"timestamp", "ingest_failed", "created_at", "raw",
+}
+
+// exportEvents streams events matching the listEvents filters as CSV or NDJSON
+func (s *Server) exportEvents(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "ndjson"
+	}
+	if format != "csv" && format != "ndjson" {
+		writeJSONError(w, http.StatusBadRequest, codeUnsupportedFormat, fmt.Sprintf("Unsupported format: %s", format))
+		return
+	}
+
+	filter, err := parseEventFilter(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, codeInvalidParameter, err.Error())
+		return
+	}
+
+	// Large exports outlive the server's write timeout
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, codeInternal, "Streaming is not supported")
+		return
+	}
+
+	filename := fmt.Sprintf("events-%s.%s", time.Now().UTC().Format("20060102T150405Z"), format)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	flusher, _ := w.(http.Flusher)
+	flush := func() {
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	// write emits one event; flushFormat pushes any format-level buffering
+	// (the CSV writer) into the response
+	var write func(event *models.Event) error
+	var flushFormat func() error
+
+	switch format {
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		cw := csv.NewWriter(w)
+		if err := cw.Write(eventCSVHeader); err != nil {
+			return
+		}
+		write = func(event *models.Event) error {
+			rawJSON, err := json.Marshal(event.Raw)
+			if err != nil {
+				return fmt.Errorf("failed to marshal raw event: %w", err)
+			}
+			return cw.Write([]string{
+				event.ID.String(),
+				event.EventID,
+				event.EventType,
+				event.Actor,
+				event.Resource,
+				event.IP,
+				event.Region,
+				event.Timestamp.UTC().Format(time.RFC3339Nano),
+				strconv.FormatBool(event.IngestFailed),
+				event.CreatedAt.UTC().Format(time.RFC3339Nano),
+				string(rawJSON),
+			})
+		}
+		flushFormat = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	default:
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		encoder := json.NewEncoder(w)
+		write = func(event *models.Event) error {
+			return encoder.Encode(event)
+		}
+		flushFormat = func() error { return nil }
+	}
+
+	// Headers are committed with the first row, so errors past this point
+	// can only be logged and end the stream early.
+	count := 0
+	err = s.eventRepo.StreamEvents(r.Context(), filter, func(event *models.Event) error {
+		if err := write(event); err != nil {
+			return err
+		}
+		count++
+		if count%exportFlushEvery == 0 {
+			if err := flushFormat(); err != nil {
+				return err
+			}
+			flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = flushFormat()
+	}
+	if err != nil {
+		log.Printf("Event export aborted after %d events: %v", count, err)
+		return
+	}
+	flush()
+}
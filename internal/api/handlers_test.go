@@ -0,0 +1,164 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/scaleway/audit-sentinel/internal/config"
+	"github.com/scaleway/audit-sentinel/internal/storage"
+	"github.com/scaleway/audit-sentinel/internal/storage/storagetest"
+)
+
+// newTestServer returns a server whose repositories query db
+func newTestServer(db *storagetest.DB) *Server {
+	return &Server{
+		eventRepo: storage.NewEventRepository(db.DB),
+		alertRepo: storage.NewAlertRepository(db.DB),
+	}
+}
+
+// serve runs handler for a GET of target with the given route variables
+func serve(handler http.HandlerFunc, target string, vars map[string]string) *httptest.ResponseRecorder {
+	r := mux.SetURLVars(httptest.NewRequest(http.MethodGet, target, nil), vars)
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
+
+// errorCode returns the code of a writeJSONError response
+func errorCode(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body errorBody
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("invalid error body: %v", err)
+	}
+	return body.Error.Code
+}
+
+func TestGetByIDNotFound(t *testing.T) {
+	tests := []struct {
+		name       string
+		queryErr   error
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "missing", wantStatus: http.StatusNotFound},
+		{name: "query fails", queryErr: errors.New("connection reset by peer"), wantStatus: http.StatusInternalServerError, wantCode: codeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(storagetest.New(t, func(storagetest.Query) storagetest.Result {
+				return storagetest.Result{Err: tt.queryErr}
+			}))
+			id := uuid.NewString()
+
+			for _, route := range []struct {
+				handler      http.HandlerFunc
+				target       string
+				notFoundCode string
+			}{
+				{handler: s.getAlert, target: "/api/v1/alerts/" + id, notFoundCode: codeAlertNotFound},
+				{handler: s.getEvent, target: "/api/v1/events/" + id, notFoundCode: codeEventNotFound},
+			} {
+				w := serve(route.handler, route.target, map[string]string{"id": id})
+				wantCode := tt.wantCode
+				if wantCode == "" {
+					wantCode = route.notFoundCode
+				}
+				if w.Code != tt.wantStatus {
+					t.Errorf("GET %s = %d, want %d", route.target, w.Code, tt.wantStatus)
+				}
+				if code := errorCode(t, w); code != wantCode {
+					t.Errorf("GET %s error code = %q, want %q", route.target, code, wantCode)
+				}
+			}
+		})
+	}
+}
+
+func TestListAlertsFiltersByTypeAndStatus(t *testing.T) {
+	db := storagetest.New(t, nil)
+	s := newTestServer(db)
+
+	w := serve(s.listAlerts, "/api/v1/alerts?alert_type=failed_login_spike&status=OPEN&from=2024-03-01T00:00:00Z", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /api/v1/alerts = %d: %s", w.Code, w.Body)
+	}
+
+	queries := db.Queries()
+	if len(queries) != 1 {
+		t.Fatalf("ran %d queries, want 1", len(queries))
+	}
+	query := queries[0]
+	for _, clause := range []string{"status = $1", "alert_type = $2", "created_at >= $3"} {
+		if !strings.Contains(query.SQL, clause) {
+			t.Errorf("query lacks %q:\n%s", clause, query.SQL)
+		}
+	}
+	if len(query.Args) < 2 || query.Args[0] != "OPEN" || query.Args[1] != "failed_login_spike" {
+		t.Errorf("args = %v, want status OPEN then alert type failed_login_spike", query.Args)
+	}
+}
+
+func TestListEmptyTablesReturnArrays(t *testing.T) {
+	s := newTestServer(storagetest.New(t, nil))
+	tests := []struct {
+		handler http.HandlerFunc
+		target  string
+		want    string
+	}{
+		{handler: s.listEvents, target: "/api/v1/events", want: `{"events":[],"count":0}`},
+		{handler: s.listEvents, target: "/api/v1/events?include_raw=false", want: `{"events":[],"count":0}`},
+		{handler: s.listAlerts, target: "/api/v1/alerts", want: `{"alerts":[],"count":0}`},
+	}
+
+	for _, tt := range tests {
+		w := serve(tt.handler, tt.target, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s = %d: %s", tt.target, w.Code, w.Body)
+		}
+		var got, want map[string]any
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatalf("GET %s returned invalid JSON: %v", tt.target, err)
+		}
+		json.Unmarshal([]byte(tt.want), &want)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("GET %s = %s, want %s", tt.target, strings.TrimSpace(w.Body.String()), tt.want)
+		}
+	}
+}
+
+func TestIngestEventsLimits(t *testing.T) {
+	s := &Server{config: &config.Config{Server: config.ServerConfig{IngestMaxBodyBytes: 1024, IngestMaxEvents: 2}}}
+	event := `{"event_id": "evt-1", "event_type": "auth.failed", "timestamp": "2024-03-01T12:00:00Z"}`
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "body over the byte limit", body: `{"event_id": "` + strings.Repeat("a", 2048) + `"}`},
+		{name: "too many events", body: "[" + strings.Repeat(event+",", 2) + event + "]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/events/ingest", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			s.ingestEvents(w, r)
+
+			if w.Code != http.StatusRequestEntityTooLarge {
+				t.Errorf("status = %d, want 413", w.Code)
+			}
+			if code := errorCode(t, w); code != codeRequestTooLarge {
+				t.Errorf("error code = %q, want %q", code, codeRequestTooLarge)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// IdempotencyKeyHeader carries the client-chosen key identifying a request
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+const (
+	// maxIdempotencyKeyLength matches the idempotency_keys.key column
+	maxIdempotencyKeyLength = 255
+	// maxIdempotentBodySize bounds the request body read for hashing
+	maxIdempotentBodySize = 1 << 20
+)
+
+// responseRecorder passes a response through while keeping a copy of it
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	r.body.Write(p)
+	return r.ResponseWriter.Write(p)
+}
+
+// idempotent makes a handler safe to retry. When the request carries an
+// Idempotency-Key, the first response is stored and replayed for repeats of
+// the same request within the TTL; reusing the key for a different request,
+// or while the first is still running, is rejected with 409. Server errors
+// are not stored so the request can be retried.
+func (s *Server) idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" {
+			next(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			writeJSONError(w, http.StatusBadRequest, codeIdempotencyKeyTooLong, "Idempotency-Key is too long")
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxIdempotentBodySize))
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, codeInvalidRequestBody, "Invalid request body")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.New()
+		io.WriteString(hash, r.Method+" "+r.URL.Path+"\n")
+		hash.Write(body)
+		requestHash := hex.EncodeToString(hash.Sum(nil))
+
+		ctx := r.Context()
+		expiry := time.Now().Add(-s.config.Server.IdempotencyTTL)
+		record, reserved, err := s.idempotencyRepo.Reserve(ctx, key, requestHash, expiry)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, codeInternal, "Failed to check Idempotency-Key")
+			return
+		}
+
+		if !reserved {
+			switch {
+			case record.RequestHash != requestHash:
+				writeJSONError(w, http.StatusConflict, codeIdempotencyKeyMismatch, "Idempotency-Key was already used for a different request")
+			case record.StatusCode == 0:
+				writeJSONError(w, http.StatusConflict, codeIdempotencyInProgress, "A request with this Idempotency-Key is still in progress")
+			default:
+				if record.ContentType != "" {
+					w.Header().Set("Content-Type", record.ContentType)
+				}
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(record.StatusCode)
+				w.Write(record.ResponseBody)
+			}
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(recorder, r)
+
+		// The client may have gone away; the outcome must still be recorded
+		ctx = context.WithoutCancel(ctx)
+		if recorder.status >= http.StatusInternalServerError {
+			if err := s.idempotencyRepo.Release(ctx, key); err != nil {
+				log.Printf("Failed to release idempotency key: %v", err)
+			}
+			return
+		}
+		if err := s.idempotencyRepo.Complete(ctx, key, recorder.status, w.Header().Get("Content-Type"), recorder.body.Bytes()); err != nil {
+			log.Printf("Failed to store idempotent response: %v", err)
+		}
+	}
+}
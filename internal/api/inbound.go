@@ -0,0 +1,42 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/scaleway/audit-sentinel/pkg/scaleway"
+)
+
+// inboundRequiredFields are the fields every event pushed to /events/ingest
+// must carry as non-empty strings
+var inboundRequiredFields = []string{"event_id", "event_type", "timestamp"}
+
+// fieldError describes why one field of a pushed event was rejected
+type fieldError struct {
+	Field string `json:"field"`
+	Error string `json:"error"`
+}
+
+// validateInboundEvent checks a pushed event against the inbound schema and
+// returns every problem found. Unlike events fetched from Scaleway, whose
+// unparseable timestamps default to now, a pushed event with a malformed
+// timestamp is rejected.
+func validateInboundEvent(raw map[string]any) []fieldError {
+	var errs []fieldError
+	for _, field := range inboundRequiredFields {
+		value, present := raw[field]
+		str, isString := value.(string)
+		switch {
+		case !present || value == nil:
+			errs = append(errs, fieldError{Field: field, Error: "is required"})
+		case !isString:
+			errs = append(errs, fieldError{Field: field, Error: fmt.Sprintf("must be a string, got %T", value)})
+		case str == "":
+			errs = append(errs, fieldError{Field: field, Error: "must not be empty"})
+		case field == "timestamp":
+			if _, ok := scaleway.ParseTimestamp(str); !ok {
+				errs = append(errs, fieldError{Field: field, Error: fmt.Sprintf("invalid timestamp %q, expected RFC 3339 or a Unix epoch", str)})
+			}
+		}
+	}
+	return errs
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateInboundEvent(t *testing.T) {
+	valid := func() map[string]any {
+		return map[string]any{
+			"event_id":   "evt-1",
+			"event_type": "auth.failed",
+			"timestamp":  "2024-03-01T12:00:00Z",
+			"actor":      "alice@example.com",
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(raw map[string]any)
+		want   []fieldError
+	}{
+		{name: "valid", modify: func(raw map[string]any) {}},
+		{name: "epoch timestamp", modify: func(raw map[string]any) { raw["timestamp"] = "1709294400" }},
+		{
+			name:   "missing event_id",
+			modify: func(raw map[string]any) { delete(raw, "event_id") },
+			want:   []fieldError{{Field: "event_id", Error: "is required"}},
+		},
+		{
+			name:   "null event_type",
+			modify: func(raw map[string]any) { raw["event_type"] = nil },
+			want:   []fieldError{{Field: "event_type", Error: "is required"}},
+		},
+		{
+			name:   "empty event_type",
+			modify: func(raw map[string]any) { raw["event_type"] = "" },
+			want:   []fieldError{{Field: "event_type", Error: "must not be empty"}},
+		},
+		{
+			name:   "numeric event_id",
+			modify: func(raw map[string]any) { raw["event_id"] = 42.0 },
+			want:   []fieldError{{Field: "event_id", Error: "must be a string, got float64"}},
+		},
+		{
+			name:   "malformed timestamp",
+			modify: func(raw map[string]any) { raw["timestamp"] = "yesterday" },
+			want:   []fieldError{{Field: "timestamp", Error: `invalid timestamp "yesterday", expected RFC 3339 or a Unix epoch`}},
+		},
+		{
+			name: "every field missing",
+			modify: func(raw map[string]any) {
+				delete(raw, "event_id")
+				delete(raw, "event_type")
+				delete(raw, "timestamp")
+			},
+			want: []fieldError{
+				{Field: "event_id", Error: "is required"},
+				{Field: "event_type", Error: "is required"},
+				{Field: "timestamp", Error: "is required"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := valid()
+			tt.modify(raw)
+			if got := validateInboundEvent(raw); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validateInboundEvent = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
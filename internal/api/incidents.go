@@ -0,0 +1,77 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/scaleway/audit-sentinel/internal/storage"
+)
+
+const (
+	// defaultIncidentWindow is the longest gap between a user's alerts that
+	// keeps them in one incident
+	defaultIncidentWindow = time.Hour
+	// defaultIncidentLookback is how far back incidents are built from when
+	// no from is given
+	defaultIncidentLookback = 24 * time.Hour
+)
+
+// listIncidents groups recent alerts per user into incidents. Alerts of a
+// user belong to the same incident while each is raised within window of
+// the previous one.
+func (s *Server) listIncidents(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit := 50
+	if l, err := strconv.Atoi(query.Get("limit")); err == nil && l > 0 {
+		limit = l
+	}
+	offset := 0
+	if o, err := strconv.Atoi(query.Get("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+
+	filter := storage.IncidentFilter{
+		Gap:       defaultIncidentWindow,
+		UserID:    query.Get("user_id"),
+		ProjectID: query.Get("project_id"),
+	}
+	if value := query.Get("window"); value != "" {
+		gap, err := time.ParseDuration(value)
+		if err != nil || gap <= 0 {
+			writeJSONError(w, http.StatusBadRequest, codeInvalidParameter, fmt.Sprintf("invalid window %q: must be a positive duration such as 1h", value))
+			return
+		}
+		filter.Gap = gap
+	}
+
+	var err error
+	if filter.From, err = parseTimeParam(query.Get("from")); err != nil {
+		writeJSONError(w, http.StatusBadRequest, codeInvalidParameter, fmt.Sprintf("invalid from: %v", err))
+		return
+	}
+	if filter.To, err = parseTimeParam(query.Get("to")); err != nil {
+		writeJSONError(w, http.StatusBadRequest, codeInvalidParameter, fmt.Sprintf("invalid to: %v", err))
+		return
+	}
+	if filter.From == nil {
+		from := time.Now().Add(-defaultIncidentLookback)
+		filter.From = &from
+	}
+
+	incidents, err := s.alertRepo.ListIncidents(r.Context(), limit, offset, filter)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, codeInternal, fmt.Sprintf("Failed to list incidents: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"incidents": incidents,
+		"count":     len(incidents),
+		"window":    filter.Gap.String(),
+	})
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/scaleway/audit-sentinel/internal/models"
+	"github.com/scaleway/audit-sentinel/internal/storage"
+)
+
+// CreateMaintenanceWindowRequest schedules a maintenance window. An empty
+// AlertType covers every alert type; Mode defaults to "tag".
+type CreateMaintenanceWindowRequest struct {
+	StartsAt  time.Time              `json:"starts_at"`
+	EndsAt    time.Time              `json:"ends_at"`
+	AlertType string                 `json:"alert_type"`
+	Mode      models.MaintenanceMode `json:"mode"`
+	Reason    string                 `json:"reason"`
+}
+
+// createMaintenanceWindow schedules a window during which alerts are
+// suppressed or tagged as raised during maintenance
+func (s *Server) createMaintenanceWindow(w http.ResponseWriter, r *http.Request) {
+	var req CreateMaintenanceWindowRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, codeInvalidRequestBody, "Invalid request body")
+		return
+	}
+
+	if req.StartsAt.IsZero() || req.EndsAt.IsZero() {
+		writeJSONError(w, http.StatusBadRequest, codeInvalidParameter, "starts_at and ends_at are required")
+		return
+	}
+	if !req.EndsAt.After(req.StartsAt) {
+		writeJSONError(w, http.StatusBadRequest, codeInvalidParameter, "ends_at must be after starts_at")
+		return
+	}
+	if !req.EndsAt.After(time.Now()) {
+		writeJSONError(w, http.StatusBadRequest, codeInvalidParameter, "maintenance window must end in the future")
+		return
+	}
+
+	switch req.Mode {
+	case "":
+		req.Mode = models.MaintenanceTag
+	case models.MaintenanceTag, models.MaintenanceSuppress:
+	default:
+		writeJSONError(w, http.StatusBadRequest, codeInvalidParameter, fmt.Sprintf("invalid mode %q: must be tag or suppress", req.Mode))
+		return
+	}
+
+	ctx := r.Context()
+	window := &models.MaintenanceWindow{
+		StartsAt:  req.StartsAt,
+		EndsAt:    req.EndsAt,
+		AlertType: strings.TrimSpace(req.AlertType),
+		Mode:      req.Mode,
+		Reason:    req.Reason,
+		CreatedBy: actorFromContext(ctx),
+	}
+	if err := s.windowRepo.CreateWindow(ctx, window); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, codeInternal, fmt.Sprintf("Failed to create maintenance window: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(window)
+}
+
+// listMaintenanceWindows lists current and upcoming windows, or all windows
+// with include_past=true
+func (s *Server) listMaintenanceWindows(w http.ResponseWriter, r *http.Request) {
+	includePast := r.URL.Query().Get("include_past") == "true"
+
+	windows, err := s.windowRepo.ListWindows(r.Context(), includePast)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, codeInternal, fmt.Sprintf("Failed to list maintenance windows: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"maintenance_windows": windows,
+		"count":               len(windows),
+	})
+}
+
+// deleteMaintenanceWindow cancels a maintenance window
+func (s *Server) deleteMaintenanceWindow(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, codeInvalidWindowID, "Invalid maintenance window ID")
+		return
+	}
+
+	if err := s.windowRepo.DeleteWindow(r.Context(), id); err != nil {
+		if errors.Is(err, storage.ErrMaintenanceWindowNotFound) {
+			writeJSONError(w, http.StatusNotFound, codeWindowNotFound, "Maintenance window not found")
+			return
+		}
+		writeJSONError(w, http.StatusInternalServerError, codeInternal, fmt.Sprintf("Failed to delete maintenance window: %v", err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/scaleway/audit-sentinel/internal/models"
+	"github.com/scaleway/audit-sentinel/internal/storage"
+)
+
+// CreateMuteRequest creates an alert mute. Exactly one of ExpiresAt and
+// Duration (e.g. "24h") sets when the mute stops suppressing alerts.
+type CreateMuteRequest struct {
+	AlertType string     `json:"alert_type"`
+	UserID    string     `json:"user_id"`
+	Resource  string     `json:"resource"`
+	Reason    string     `json:"reason"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	Duration  string     `json:"duration"`
+}
+
+// createMute suppresses matching alerts until the mute expires
+func (s *Server) createMute(w http.ResponseWriter, r *http.Request) {
+	var req CreateMuteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, codeInvalidRequestBody, "Invalid request body")
+		return
+	}
+
+	req.AlertType = strings.TrimSpace(req.AlertType)
+	if req.AlertType == "" {
+		writeJSONError(w, http.StatusBadRequest, codeInvalidParameter, "alert_type is required")
+		return
+	}
+
+	now := time.Now()
+	var expiresAt time.Time
+	switch {
+	case req.ExpiresAt != nil && req.Duration != "":
+		writeJSONError(w, http.StatusBadRequest, codeInvalidParameter, "give either expires_at or duration, not both")
+		return
+	case req.ExpiresAt != nil:
+		expiresAt = *req.ExpiresAt
+	case req.Duration != "":
+		duration, err := time.ParseDuration(req.Duration)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, codeInvalidParameter, fmt.Sprintf("invalid duration: %v", err))
+			return
+		}
+		expiresAt = now.Add(duration)
+	default:
+		writeJSONError(w, http.StatusBadRequest, codeInvalidParameter, "expires_at or duration is required")
+		return
+	}
+	if !expiresAt.After(now) {
+		writeJSONError(w, http.StatusBadRequest, codeInvalidParameter, "mute must expire in the future")
+		return
+	}
+
+	ctx := r.Context()
+	mute := &models.AlertMute{
+		AlertType: req.AlertType,
+		UserID:    strings.TrimSpace(req.UserID),
+		Resource:  strings.TrimSpace(req.Resource),
+		Reason:    req.Reason,
+		CreatedBy: actorFromContext(ctx),
+		CreatedAt: now,
+		ExpiresAt: expiresAt,
+	}
+	if err := s.muteRepo.CreateMute(ctx, mute); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, codeInternal, fmt.Sprintf("Failed to create mute: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(mute)
+}
+
+// listMutes lists active mutes, or all mutes with include_expired=true
+func (s *Server) listMutes(w http.ResponseWriter, r *http.Request) {
+	includeExpired := r.URL.Query().Get("include_expired") == "true"
+
+	mutes, err := s.muteRepo.ListMutes(r.Context(), includeExpired)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, codeInternal, fmt.Sprintf("Failed to list mutes: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"mutes": mutes,
+		"count": len(mutes),
+	})
+}
+
+// getMute retrieves a single mute by ID
+func (s *Server) getMute(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, codeInvalidMuteID, "Invalid mute ID")
+		return
+	}
+
+	mute, err := s.muteRepo.GetMute(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, storage.ErrMuteNotFound) {
+			writeJSONError(w, http.StatusNotFound, codeMuteNotFound, "Mute not found")
+			return
+		}
+		writeJSONError(w, http.StatusInternalServerError, codeInternal, fmt.Sprintf("Failed to get mute: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mute)
+}
+
+// deleteMute removes a mute so matching alerts are raised again
+func (s *Server) deleteMute(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, codeInvalidMuteID, "Invalid mute ID")
+		return
+	}
+
+	if err := s.muteRepo.DeleteMute(r.Context(), id); err != nil {
+		if errors.Is(err, storage.ErrMuteNotFound) {
+			writeJSONError(w, http.StatusNotFound, codeMuteNotFound, "Mute not found")
+			return
+		}
+		writeJSONError(w, http.StatusInternalServerError, codeInternal, fmt.Sprintf("Failed to delete mute: %v", err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// rateLimitSweepInterval is how often idle client buckets are dropped
+const rateLimitSweepInterval = time.Minute
+
+// tokenBucket holds the tokens left for one client as of last
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter is a per-client token bucket limiter. Each client may make
+// burst requests at once, refilled at rate requests per second.
+type rateLimiter struct {
+	mu        sync.Mutex
+	rate      float64
+	burst     float64
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// newRateLimiter creates a limiter, or returns nil when rate is not positive
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &rateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+	}
+}
+
+// allow takes a token for key. When none is left it reports how long until
+// the next token is available.
+func (l *rateLimiter) allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = bucket
+	}
+	bucket.tokens = math.Min(l.burst, bucket.tokens+now.Sub(bucket.last).Seconds()*l.rate)
+	bucket.last = now
+
+	if bucket.tokens < 1 {
+		wait := time.Duration((1 - bucket.tokens) / l.rate * float64(time.Second))
+		return false, wait
+	}
+	bucket.tokens--
+	return true, 0
+}
+
+// sweep drops buckets that have refilled completely, since a new bucket
+// would be identical. Callers must hold l.mu.
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	refill := time.Duration(l.burst / l.rate * float64(time.Second))
+	for key, bucket := range l.buckets {
+		if now.Sub(bucket.last) >= refill {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// clientKey identifies the caller for rate limiting. The API authenticates
+// with a single shared token, so clients are told apart by remote address.
+func clientKey(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// rateLimited rejects requests beyond the client's allowance with 429 and a
+// Retry-After header. It is a no-op when rate limiting is disabled.
+func (s *Server) rateLimited(next http.HandlerFunc) http.HandlerFunc {
+	if s.limiter == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		ok, wait := s.limiter.allow(clientKey(r))
+		if !ok {
+			seconds := int(math.Ceil(wait.Seconds()))
+			if seconds < 1 {
+				seconds = 1
+			}
+			w.Header().Set("Retry-After", strconv.Itoa(seconds))
+			writeJSONError(w, http.StatusTooManyRequests, codeRateLimited, "Too many requests, retry later")
+			return
+		}
+		next(w, r)
+	}
+}
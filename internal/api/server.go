@@ -3,11 +3,18 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/handlers"
@@ -16,8 +23,11 @@ import (
 	"github.com/scaleway/audit-sentinel/internal/detection"
 	"github.com/scaleway/audit-sentinel/internal/ingestion"
 	"github.com/scaleway/audit-sentinel/internal/models"
+	"github.com/scaleway/audit-sentinel/internal/notification"
 	"github.com/scaleway/audit-sentinel/internal/remediation"
+	"github.com/scaleway/audit-sentinel/internal/retention"
 	"github.com/scaleway/audit-sentinel/internal/storage"
+	"github.com/scaleway/audit-sentinel/internal/threatintel"
 	"github.com/scaleway/audit-sentinel/pkg/scaleway"
 )
 
@@ -26,18 +36,50 @@ type Server struct {
 	config          *config.Config
 	router          *mux.Router
 	httpServer      *http.Server
+	redirectServer  *http.Server
 	storage         *storage.Storage
+	dbHealth        *storage.HealthChecker
 	eventRepo       *storage.EventRepository
 	alertRepo       *storage.AlertRepository
 	remediationRepo *storage.RemediationRepository
+	profileRepo     *storage.UserProfileRepository
+	idempotencyRepo *storage.IdempotencyRepository
+	muteRepo        *storage.MuteRepository
+	erasureRepo     *storage.ErasureRepository
+	windowRepo      *storage.MaintenanceRepository
+	limiter         *rateLimiter
+	eventTypes      *typesCache
+	alertTypes      *typesCache
 	ingestor        *ingestion.Ingestor
+	engine          *detection.Engine
+	janitor         *retention.Janitor
+	threatIntel     *threatintel.Feed
 	remediationSvc  *remediation.Service
+	recommender     *remediation.Recommender
+	notifications   *notification.Dispatcher
+	detectionQueue  *ingestion.AsyncProcessor
+	retrier         *ingestion.DetectionRetrier
+	alertHub        *notification.Hub
+
+	// background tracks long-running workers (ingestion loop, etc.) that must
+	// be drained on shutdown; done is closed once all of them have returned.
+	background   sync.WaitGroup
+	mu           sync.Mutex
+	ingestCancel context.CancelFunc
+	purgeCancel  context.CancelFunc
+	intelCancel  context.CancelFunc
+	healthCancel context.CancelFunc
+	retryCancel  context.CancelFunc
+	// closed is set once Shutdown begins; no worker may start after it
+	closed   bool
+	done     chan struct{}
+	doneOnce sync.Once
 }
 
 // NewServer creates a new API server instance
 func NewServer(cfg *config.Config) (*Server, error) {
 	// Initialize storage
-	store, err := storage.NewStorage(cfg.Database.URL)
+	store, err := storage.NewStorage(cfg.Database)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create storage: %w", err)
 	}
@@ -54,18 +96,64 @@ func NewServer(cfg *config.Config) (*Server, error) {
 		cfg.Scaleway.OrganizationID,
 		cfg.Scaleway.APIURL,
 	)
+	scalewayClient.SetMock(cfg.Scaleway.Mock)
+	scalewayClient.SetEndpoints(scaleway.Endpoints{
+		Audit:          scaleway.Endpoint{Path: cfg.Scaleway.AuditEventsPath, ListKey: cfg.Scaleway.AuditEventsListKey},
+		Authentication: scaleway.Endpoint{Path: cfg.Scaleway.LoginLogsPath, ListKey: cfg.Scaleway.LoginLogsListKey},
+	})
+	scalewayClient.SetMaxStalePages(cfg.Scaleway.MaxStalePages)
 
 	// Create detection engine
 	detectionStorage := detection.NewDetectionStorage(store.DB())
-	detectionEngine := detection.NewEngine(cfg, detectionStorage)
+	detectionEngine, err := detection.NewEngine(cfg, detectionStorage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create detection engine: %w", err)
+	}
+
+	// Create outbound notifiers
+	var notifiers []notification.Notifier
+	if cfg.Notification.GenericWebhookURL != "" {
+		notifiers = append(notifiers, notification.NewWebhookNotifier(
+			cfg.Notification.GenericWebhookURL,
+			cfg.Notification.GenericWebhookSecret,
+		))
+	}
+	var notifications *notification.Dispatcher
+	if len(notifiers) > 0 {
+		detectionStorage.EnableOutbox()
+		notifications = notification.NewDispatcher(alertRepo, notifiers...)
+		detectionEngine.AddPublisher(notifications)
+	}
 
-	// Create ingestion processor
-	processor := ingestion.NewProcessor(detectionEngine)
+	// Fan stored alerts out to live streams
+	alertHub := notification.NewHub()
+	detectionEngine.AddPublisher(alertHub)
+
+	// Create ingestion processor, queueing failed detections for retry and
+	// running detection off a queue if configured
+	detectionRetrier := ingestion.NewDetectionRetrier(detectionEngine, eventRepo,
+		cfg.Ingestion.DetectionRetryBackoff, cfg.Ingestion.DetectionRetryMaxAttempts)
+	detectionProcessor := ingestion.NewProcessor(detectionEngine)
+	detectionProcessor.SetRetrier(detectionRetrier)
+	var processor ingestion.EventProcessor = detectionProcessor
+	var detectionQueue *ingestion.AsyncProcessor
+	if cfg.Ingestion.DetectionWorkers > 0 {
+		detectionQueue = ingestion.NewAsyncProcessor(processor, cfg.Ingestion.DetectionWorkers,
+			cfg.Ingestion.DetectionQueueSize, cfg.Ingestion.DetectionQueueFullPolicy)
+		processor = detectionQueue
+	}
 
 	// Create ingestor
 	ingestor := ingestion.NewIngestor(cfg, scalewayClient, eventRepo)
 	ingestor.SetProcessor(processor)
 
+	// Check event IPs against the threat intel blocklist, if one is configured
+	var threatIntel *threatintel.Feed
+	if cfg.ThreatIntel.Source != "" {
+		threatIntel = threatintel.NewFeed(cfg.ThreatIntel.Source, cfg.ThreatIntel.RefreshInterval)
+		ingestor.SetIPReputation(threatIntel)
+	}
+
 	// Create remediation repository adapter
 	remediationRepoAdapter := &remediationRepositoryAdapter{
 		alertRepo:       alertRepo,
@@ -74,15 +162,12 @@ func NewServer(cfg *config.Config) (*Server, error) {
 
 	// Create remediation service
 	remediationSvc := remediation.NewService(cfg, scalewayClient, remediationRepoAdapter)
+	if cfg.Remediation.AutoRemediate {
+		detectionEngine.SetRemediator(remediation.NewAutoRemediator(cfg, remediationSvc))
+	}
 
 	router := mux.NewRouter()
 
-	corsMiddleware := handlers.CORS(
-		handlers.AllowedOrigins([]string{"*"}),
-		handlers.AllowedMethods([]string{http.MethodGet, http.MethodPost, http.MethodPatch, http.MethodPut, http.MethodDelete, http.MethodOptions}),
-		handlers.AllowedHeaders([]string{"Content-Type", "Authorization", "Accept"}),
-	)
-
 	server := &Server{
 		config:          cfg,
 		router:          router,
@@ -90,108 +175,463 @@ func NewServer(cfg *config.Config) (*Server, error) {
 		eventRepo:       eventRepo,
 		alertRepo:       alertRepo,
 		remediationRepo: remediationRepo,
+		profileRepo:     storage.NewUserProfileRepository(store.DB()),
+		idempotencyRepo: storage.NewIdempotencyRepository(store.DB()),
+		muteRepo:        storage.NewMuteRepository(store.DB()),
+		erasureRepo:     storage.NewErasureRepository(store.DB()),
+		windowRepo:      storage.NewMaintenanceRepository(store.DB()),
+		limiter:         newRateLimiter(cfg.Security.RateLimitRPS, cfg.Security.RateLimitBurst),
+		eventTypes:      newTypesCache(eventRepo.DistinctEventTypes),
+		alertTypes:      newTypesCache(alertRepo.DistinctAlertTypes),
 		ingestor:        ingestor,
+		engine:          detectionEngine,
+		janitor:         retention.NewJanitor(cfg, eventRepo),
+		dbHealth:        storage.NewHealthChecker(store.DB(), cfg.Database.HealthCheckInterval),
+		threatIntel:     threatIntel,
 		remediationSvc:  remediationSvc,
+		recommender:     remediation.NewRecommender(cfg.Remediation.RecommendedActions),
+		notifications:   notifications,
+		detectionQueue:  detectionQueue,
+		retrier:         detectionRetrier,
+		alertHub:        alertHub,
+		done:            make(chan struct{}),
 		httpServer: &http.Server{
 			Addr:         fmt.Sprintf("%s:%s", cfg.Server.Host, cfg.Server.Port),
-			Handler:      corsMiddleware(router),
+			Handler:      corsHandler(cfg.Server.CORSAllowedOrigins, compressHandler(cfg.Server, router)),
 			ReadTimeout:  15 * time.Second,
 			WriteTimeout: 15 * time.Second,
 			IdleTimeout:  60 * time.Second,
 		},
 	}
 
+	if cfg.Server.TLSEnabled() {
+		server.httpServer.TLSConfig = newTLSConfig(cfg.Server.TLSMinVersion)
+		if cfg.Server.HTTPRedirectPort != "" {
+			server.redirectServer = &http.Server{
+				Addr:         fmt.Sprintf("%s:%s", cfg.Server.Host, cfg.Server.HTTPRedirectPort),
+				Handler:      httpsRedirect(cfg.Server.Port),
+				ReadTimeout:  15 * time.Second,
+				WriteTimeout: 15 * time.Second,
+				IdleTimeout:  60 * time.Second,
+			}
+		}
+	}
+
+	// Streams never finish on their own; end them so Shutdown can drain
+	server.httpServer.RegisterOnShutdown(alertHub.Close)
+
 	server.setupRoutes()
 	return server, nil
 }
 
+// corsHandler wraps next with CORS headers for the allowed origins. With no
+// origins configured the API is same-origin only and no CORS headers are sent.
+func corsHandler(allowedOrigins []string, next http.Handler) http.Handler {
+	if len(allowedOrigins) == 0 {
+		return next
+	}
+	return handlers.CORS(
+		handlers.AllowedOrigins(allowedOrigins),
+		handlers.AllowedMethods([]string{http.MethodGet, http.MethodPost, http.MethodPatch, http.MethodPut, http.MethodDelete}),
+		handlers.AllowedHeaders([]string{"Content-Type", "Authorization", IdempotencyKeyHeader, ActorHeader, AdminTokenHeader}),
+		handlers.ExposedHeaders([]string{"Idempotent-Replayed"}),
+	)(next)
+}
+
+// compressHandler gzips (or deflates) responses from next per Accept-Encoding,
+// except the alert stream, whose events must reach the client as they are
+// flushed. It returns next unchanged if compression is disabled.
+func compressHandler(cfg config.ServerConfig, next http.Handler) http.Handler {
+	if !cfg.Compression {
+		return next
+	}
+	streamPath := cfg.APIPrefix + "/alerts/stream"
+	compressed := handlers.CompressHandler(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == streamPath || strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		compressed.ServeHTTP(w, r)
+	})
+}
+
 // setupRoutes configures all API routes
 func (s *Server) setupRoutes() {
 	api := s.router.PathPrefix(s.config.Server.APIPrefix).Subrouter()
 	api.Use(s.authMiddleware)
 
-	// Health check
+	// Health checks
 	s.router.HandleFunc("/health", s.healthCheck).Methods("GET")
+	s.router.HandleFunc("/ready", s.readinessCheck).Methods("GET")
 
 	// Alerts endpoints
 	api.HandleFunc("/alerts", s.listAlerts).Methods("GET")
+	api.HandleFunc("/alerts/report", s.alertsReport).Methods("GET")
+	api.HandleFunc("/alerts/stream", s.streamAlerts).Methods("GET")
+	api.HandleFunc("/alerts/types", s.listAlertTypes).Methods("GET")
 	api.HandleFunc("/alerts/{id}", s.getAlert).Methods("GET")
-	api.HandleFunc("/alerts/{id}/remediate", s.remediateAlert).Methods("POST")
+	api.HandleFunc("/alerts/{id}/remediate", s.rateLimited(s.idempotent(s.remediateAlert))).Methods("POST")
 	api.HandleFunc("/alerts/{id}/status", s.updateAlertStatus).Methods("PATCH")
+	api.HandleFunc("/alerts/{id}/ack", s.acknowledgeAlert).Methods("POST")
+	api.HandleFunc("/alerts/{id}/history", s.getAlertHistory).Methods("GET")
+
+	// Incidents group related alerts; the alerts stay available above
+	api.HandleFunc("/incidents", s.listIncidents).Methods("GET")
 
 	// Events endpoints
 	api.HandleFunc("/events", s.listEvents).Methods("GET")
+	api.HandleFunc("/events/export", s.exportEvents).Methods("GET")
+	api.HandleFunc("/events/types", s.listEventTypes).Methods("GET")
+	api.HandleFunc("/events/ingest", s.ingestEvents).Methods("POST")
+	api.HandleFunc("/events/by-event-id/{eventID}", s.getEventByEventID).Methods("GET")
 	api.HandleFunc("/events/{id}", s.getEvent).Methods("GET")
+	api.HandleFunc("/events/{id}/reprocess", s.reprocessEvent).Methods("POST")
 
 	// Ingestion endpoints
-	api.HandleFunc("/ingest/now", s.triggerIngestion).Methods("POST")
+	api.HandleFunc("/ingest/now", s.rateLimited(s.triggerIngestion)).Methods("POST")
+	api.HandleFunc("/ingest/status", s.ingestionStatus).Methods("GET")
 
 	// User endpoints
 	api.HandleFunc("/users/{id}/profile", s.getUserProfile).Methods("GET")
 	api.HandleFunc("/users/{id}/history", s.getUserHistory).Methods("GET")
+	api.HandleFunc("/users/{id}/contain", s.rateLimited(s.idempotent(s.containUser))).Methods("POST")
+	api.HandleFunc("/users/{id}/data", s.adminOnly(s.eraseUserData)).Methods("DELETE")
 
 	// Rules endpoints
 	api.HandleFunc("/rules", s.listRules).Methods("GET")
 	api.HandleFunc("/rules/{id}", s.updateRule).Methods("PUT")
+
+	// Alert mutes
+	api.HandleFunc("/mutes", s.listMutes).Methods("GET")
+	api.HandleFunc("/mutes", s.createMute).Methods("POST")
+	api.HandleFunc("/mutes/{id}", s.getMute).Methods("GET")
+	api.HandleFunc("/mutes/{id}", s.deleteMute).Methods("DELETE")
+
+	// Maintenance windows
+	api.HandleFunc("/maintenance-windows", s.listMaintenanceWindows).Methods("GET")
+	api.HandleFunc("/maintenance-windows", s.createMaintenanceWindow).Methods("POST")
+	api.HandleFunc("/maintenance-windows/{id}", s.deleteMaintenanceWindow).Methods("DELETE")
+
+	// Dashboard
+	api.HandleFunc("/stats", s.getStats).Methods("GET")
+
+	// Configuration
+	api.HandleFunc("/config/reload", s.reloadConfig).Methods("POST")
+	api.HandleFunc("/detection/config", s.getDetectionConfig).Methods("GET")
 }
 
-// Start starts the HTTP server
+// Start starts the HTTP server, serving HTTPS when TLS is configured
 func (s *Server) Start() error {
-	return s.httpServer.ListenAndServe()
+	if !s.config.Server.TLSEnabled() {
+		return s.httpServer.ListenAndServe()
+	}
+
+	if s.redirectServer != nil {
+		go func() {
+			log.Printf("Redirecting HTTP on %s to HTTPS", s.redirectServer.Addr)
+			if err := s.redirectServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Printf("HTTP redirect server failed: %v", err)
+			}
+		}()
+	}
+	return s.httpServer.ListenAndServeTLS(s.config.Server.TLSCertFile, s.config.Server.TLSKeyFile)
 }
 
-// StartIngestion starts the background ingestion loop.
+// StartIngestion starts the background ingestion loop. It blocks until the
+// loop stops, either because ctx is cancelled or because Shutdown was called.
 func (s *Server) StartIngestion(ctx context.Context) error {
 	if s.ingestor == nil {
 		return fmt.Errorf("ingestor is not configured")
 	}
+
+	ctx, stop, err := s.startWorker(ctx, &s.ingestCancel)
+	if err != nil {
+		return err
+	}
+	defer stop()
+
 	return s.ingestor.Start(ctx)
 }
 
-// Shutdown gracefully shuts down the server
+// StartRetention runs the retention janitor until ctx is cancelled or the
+// server shuts down. It returns immediately if no retention is configured.
+func (s *Server) StartRetention(ctx context.Context) error {
+	if !s.janitor.Enabled() {
+		return nil
+	}
+
+	ctx, stop, err := s.startWorker(ctx, &s.purgeCancel)
+	if err != nil {
+		return err
+	}
+	defer stop()
+
+	return s.janitor.Start(ctx)
+}
+
+// StartThreatIntel keeps the threat intel blocklist refreshed until ctx is
+// cancelled or the server shuts down. It returns immediately if no blocklist
+// is configured.
+func (s *Server) StartThreatIntel(ctx context.Context) error {
+	if s.threatIntel == nil {
+		return nil
+	}
+
+	ctx, stop, err := s.startWorker(ctx, &s.intelCancel)
+	if err != nil {
+		return err
+	}
+	defer stop()
+
+	return s.threatIntel.Start(ctx)
+}
+
+// StartDBHealth pings the database on the configured interval until ctx is
+// cancelled or the server shuts down, keeping /ready up to date
+func (s *Server) StartDBHealth(ctx context.Context) error {
+	ctx, stop, err := s.startWorker(ctx, &s.healthCancel)
+	if err != nil {
+		return err
+	}
+	defer stop()
+
+	return s.dbHealth.Start(ctx)
+}
+
+// StartDetectionRetry retries events whose detection failed on the configured
+// interval until ctx is cancelled or the server shuts down
+func (s *Server) StartDetectionRetry(ctx context.Context) error {
+	ctx, stop, err := s.startWorker(ctx, &s.retryCancel)
+	if err != nil {
+		return err
+	}
+	defer stop()
+
+	return s.retrier.Start(ctx, s.config.Ingestion.DetectionRetryInterval)
+}
+
+// ErrShuttingDown is returned by the Start methods once Shutdown has begun
+var ErrShuttingDown = errors.New("server is shutting down")
+
+// startWorker registers a background worker with the shutdown drain and
+// returns its context, cancelled through *cancelSlot on shutdown, and a stop
+// func the worker must call when it returns. Registering under mu orders it
+// before Shutdown's wait, or refuses the worker once Shutdown has begun.
+func (s *Server) startWorker(ctx context.Context, cancelSlot *context.CancelFunc) (context.Context, func(), error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, nil, ErrShuttingDown
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	*cancelSlot = cancel
+	s.background.Add(1)
+	return ctx, func() {
+		cancel()
+		s.background.Done()
+	}, nil
+}
+
+// Shutdown gracefully shuts down the server. It stops accepting HTTP
+// requests, cancels the ingestion loop and waits for in-flight background
+// work to drain, giving up when ctx expires.
 func (s *Server) Shutdown(ctx context.Context) error {
-	return s.httpServer.Shutdown(ctx)
+	log.Println("Draining HTTP connections...")
+	httpErr := s.httpServer.Shutdown(ctx)
+	if s.redirectServer != nil {
+		if err := s.redirectServer.Shutdown(ctx); err != nil {
+			log.Printf("HTTP redirect server shutdown failed: %v", err)
+		}
+	}
+
+	s.mu.Lock()
+	s.closed = true
+	if s.ingestCancel != nil {
+		log.Println("Stopping ingestion loop...")
+		s.ingestCancel()
+	}
+	if s.purgeCancel != nil {
+		s.purgeCancel()
+	}
+	if s.intelCancel != nil {
+		s.intelCancel()
+	}
+	if s.healthCancel != nil {
+		s.healthCancel()
+	}
+	if s.retryCancel != nil {
+		s.retryCancel()
+	}
+	s.mu.Unlock()
+
+	ingestionStopped := make(chan struct{})
+	go func() {
+		s.background.Wait()
+		close(ingestionStopped)
+	}()
+
+	log.Println("Waiting for in-flight ingestion cycle to finish...")
+	select {
+	case <-ingestionStopped:
+		log.Println("Background workers stopped")
+	case <-ctx.Done():
+		return fmt.Errorf("timed out waiting for background workers: %w", ctx.Err())
+	}
+
+	// Ingestion has stopped, so no new events can be queued for detection
+	if s.detectionQueue != nil {
+		if err := s.detectionQueue.Close(ctx); err != nil {
+			return fmt.Errorf("timed out draining detection queue: %w", err)
+		}
+		log.Println("Detection queue drained")
+	}
+
+	// Detection has stopped, so no new alerts can be queued
+	if s.notifications != nil {
+		if err := s.notifications.Close(ctx); err != nil {
+			return fmt.Errorf("timed out flushing notifications: %w", err)
+		}
+		log.Println("Notifications flushed")
+	}
+
+	s.doneOnce.Do(func() { close(s.done) })
+	return httpErr
+}
+
+// Done returns a channel that is closed once Shutdown has drained all
+// background workers.
+func (s *Server) Done() <-chan struct{} {
+	return s.done
+}
+
+// ReloadConfig re-reads the detection thresholds and applies them to rules
+// without a restart. The previous settings stay in effect if the new ones are
+// invalid.
+func (s *Server) ReloadConfig() (config.DetectionConfig, error) {
+	detection, err := s.config.ReloadDetection()
+	if err != nil {
+		return detection, err
+	}
+	log.Printf("Detection configuration reloaded: window=%dm threshold=%d travel_speed=%.0fkm/h",
+		detection.FailedLoginWindowMin, detection.FailedLoginThreshold, detection.ImpossibleTravelSpeed)
+	return detection, nil
+}
+
+// ActorHeader names the person acting through an authenticated client. The
+// shared API token cannot tell people apart, so the name is unverified and is
+// only recorded, in alert history and remediation logs, as claimed.
+const ActorHeader = "X-Actor"
+
+// defaultActor is the principal proven by authMiddleware: the API token
+// identifies the client, not the person using it
+const defaultActor = "api"
+
+// maxClaimedActorLen caps the length of a name taken from ActorHeader
+const maxClaimedActorLen = 128
+
+type actorContextKey struct{}
+
+// actorFromContext returns the actor set by authMiddleware
+func actorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorContextKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return defaultActor
+}
+
+// withActor stores the request's actor in its context: the authenticated
+// principal, followed by the name claimed in ActorHeader if there is one
+func withActor(r *http.Request) *http.Request {
+	actor := defaultActor
+	if claimed := claimedActor(r.Header.Get(ActorHeader)); claimed != "" {
+		actor = fmt.Sprintf("%s (claimed: %s)", defaultActor, claimed)
+	}
+	return r.WithContext(context.WithValue(r.Context(), actorContextKey{}, actor))
+}
+
+// claimedActor cleans a name from ActorHeader for recording, dropping
+// control characters and truncating it to maxClaimedActorLen runes
+func claimedActor(header string) string {
+	cleaned := strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(header))
+	if runes := []rune(cleaned); len(runes) > maxClaimedActorLen {
+		cleaned = string(runes[:maxClaimedActorLen])
+	}
+	return strings.TrimSpace(cleaned)
 }
 
 func (s *Server) authMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		secret := strings.TrimSpace(s.config.Security.JWTSecret)
 		if secret == "" {
-			next.ServeHTTP(w, r)
+			next.ServeHTTP(w, withActor(r))
 			return
 		}
 
 		const bearerPrefix = "Bearer "
 		authHeader := strings.TrimSpace(r.Header.Get("Authorization"))
 		if !strings.HasPrefix(authHeader, bearerPrefix) {
-			http.Error(w, "missing or invalid Authorization header", http.StatusUnauthorized)
+			writeJSONError(w, http.StatusUnauthorized, codeUnauthorized, "missing or invalid Authorization header")
 			return
 		}
 
 		token := strings.TrimSpace(strings.TrimPrefix(authHeader, bearerPrefix))
 		if token == "" || token != secret {
-			http.Error(w, "invalid token", http.StatusUnauthorized)
+			writeJSONError(w, http.StatusUnauthorized, codeUnauthorized, "invalid token")
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, withActor(r))
 	})
 }
 
-// Health check handler
+// readinessTimeout bounds the database ping performed by the readiness check
+const readinessTimeout = 2 * time.Second
+
+// healthCheck is the liveness probe; it only reports that the process is serving
 func (s *Server) healthCheck(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte(`{"status":"ok"}`))
 }
 
+// readinessCheck is the readiness probe; it reports the latest background
+// database health check, pinging the database itself until one has run
+func (s *Server) readinessCheck(w http.ResponseWriter, r *http.Request) {
+	status := s.dbHealth.Status()
+	if status.CheckedAt.IsZero() {
+		ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+		defer cancel()
+		status = s.dbHealth.Check(ctx)
+	}
+
+	response := map[string]interface{}{
+		"status":        "ok",
+		"db_latency_ms": status.Latency.Milliseconds(),
+		"checked_at":    status.CheckedAt,
+	}
+	statusCode := http.StatusOK
+	if !status.Healthy {
+		response["status"] = "unavailable"
+		response["error"] = fmt.Sprintf("database unreachable: %v", status.Err)
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(response)
+}
+
 // listAlerts lists alerts with optional filters
 func (s *Server) listAlerts(w http.ResponseWriter, r *http.Request) {
 	// Parse query parameters
 	limitStr := r.URL.Query().Get("limit")
 	offsetStr := r.URL.Query().Get("offset")
-	severity := r.URL.Query().Get("severity")
-	status := r.URL.Query().Get("status")
-	userID := r.URL.Query().Get("user_id")
 
 	limit := 50 // default
 	if limitStr != "" {
@@ -207,10 +647,34 @@ func (s *Server) listAlerts(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	query := r.URL.Query()
+	filter := storage.AlertFilter{
+		Severity:  query.Get("severity"),
+		Status:    query.Get("status"),
+		UserID:    query.Get("user_id"),
+		AlertType: query.Get("alert_type"),
+		ProjectID: query.Get("project_id"),
+	}
+	var err error
+	if filter.From, err = parseTimeParam(query.Get("from")); err != nil {
+		writeJSONError(w, http.StatusBadRequest, codeInvalidParameter, fmt.Sprintf("invalid from: %v", err))
+		return
+	}
+	if filter.To, err = parseTimeParam(query.Get("to")); err != nil {
+		writeJSONError(w, http.StatusBadRequest, codeInvalidParameter, fmt.Sprintf("invalid to: %v", err))
+		return
+	}
+
+	order, err := storage.ParseAlertOrder(query.Get("sort"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, codeInvalidParameter, err.Error())
+		return
+	}
+
 	ctx := r.Context()
-	alerts, err := s.alertRepo.ListAlerts(ctx, limit, offset, severity, status, userID)
+	alerts, err := s.alertRepo.ListAlerts(ctx, limit, offset, filter, order)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to list alerts: %v", err), http.StatusInternalServerError)
+		writeJSONError(w, http.StatusInternalServerError, codeInternal, fmt.Sprintf("Failed to list alerts: %v", err))
 		return
 	}
 
@@ -221,30 +685,81 @@ func (s *Server) listAlerts(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// getAlert retrieves a single alert by ID
+// alertsReport returns alert counts by severity, type, status and top users
+func (s *Server) alertsReport(w http.ResponseWriter, r *http.Request) {
+	from, err := parseTimeParam(r.URL.Query().Get("from"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, codeInvalidParameter, fmt.Sprintf("invalid from: %v", err))
+		return
+	}
+	to, err := parseTimeParam(r.URL.Query().Get("to"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, codeInvalidParameter, fmt.Sprintf("invalid to: %v", err))
+		return
+	}
+
+	ctx := r.Context()
+	summary, err := s.alertRepo.Summarize(ctx, from, to)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, codeInternal, fmt.Sprintf("Failed to summarize alerts: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
+
+// getAlert retrieves a single alert by ID. With expand=events the events it
+// references are embedded in the response.
 func (s *Server) getAlert(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	idStr := vars["id"]
 
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		http.Error(w, "Invalid alert ID", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, codeInvalidAlertID, "Invalid alert ID")
+		return
+	}
+
+	expand := r.URL.Query().Get("expand")
+	if expand != "" && expand != "events" {
+		writeJSONError(w, http.StatusBadRequest, codeInvalidParameter, fmt.Sprintf("invalid expand: %q", expand))
 		return
 	}
 
 	ctx := r.Context()
 	alert, err := s.alertRepo.GetAlert(ctx, id)
 	if err != nil {
-		if err.Error() == "alert not found" {
-			http.Error(w, "Alert not found", http.StatusNotFound)
+		if errors.Is(err, storage.ErrAlertNotFound) {
+			writeJSONError(w, http.StatusNotFound, codeAlertNotFound, "Alert not found")
 			return
 		}
-		http.Error(w, fmt.Sprintf("Failed to get alert: %v", err), http.StatusInternalServerError)
+		writeJSONError(w, http.StatusInternalServerError, codeInternal, fmt.Sprintf("Failed to get alert: %v", err))
+		return
+	}
+
+	recommended := s.recommender.Recommend(alert.AlertType)
+	if expand != "events" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(struct {
+			*models.Alert
+			RecommendedAction models.ActionType `json:"recommended_action,omitempty"`
+		}{Alert: alert, RecommendedAction: recommended})
+		return
+	}
+
+	events, err := s.eventRepo.GetEventsByIDs(ctx, alert.EventRefs)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, codeInternal, fmt.Sprintf("Failed to get alert events: %v", err))
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(alert)
+	json.NewEncoder(w).Encode(struct {
+		*models.Alert
+		RecommendedAction models.ActionType `json:"recommended_action,omitempty"`
+		Events            []*models.Event   `json:"events"`
+	}{Alert: alert, RecommendedAction: recommended, Events: events})
 }
 
 // RemediateRequest represents a remediation action request
@@ -260,7 +775,7 @@ func (s *Server) remediateAlert(w http.ResponseWriter, r *http.Request) {
 
 	alertID, err := uuid.Parse(alertIDStr)
 	if err != nil {
-		http.Error(w, "Invalid alert ID", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, codeInvalidAlertID, "Invalid alert ID")
 		return
 	}
 
@@ -268,61 +783,67 @@ func (s *Server) remediateAlert(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	alert, err := s.alertRepo.GetAlert(ctx, alertID)
 	if err != nil {
-		if err.Error() == "alert not found" {
-			http.Error(w, "Alert not found", http.StatusNotFound)
+		if errors.Is(err, storage.ErrAlertNotFound) {
+			writeJSONError(w, http.StatusNotFound, codeAlertNotFound, "Alert not found")
 			return
 		}
-		http.Error(w, fmt.Sprintf("Failed to get alert: %v", err), http.StatusInternalServerError)
+		writeJSONError(w, http.StatusInternalServerError, codeInternal, fmt.Sprintf("Failed to get alert: %v", err))
 		return
 	}
 
 	// Parse request body
 	var req RemediateRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, codeInvalidRequestBody, "Invalid request body")
 		return
 	}
 
-	// Determine actor (for now, use a default system user)
-	actor := "system"
+	actor := actorFromContext(ctx)
 
 	// Perform remediation based on alert type and action
 	var remediationErr error
 	switch req.Action {
 	case "lock_user":
 		if alert.UserID == "" {
-			http.Error(w, "Alert has no user ID to lock", http.StatusBadRequest)
+			writeJSONError(w, http.StatusBadRequest, codeMissingTarget, "Alert has no user ID to lock")
 			return
 		}
 		remediationErr = s.remediationSvc.LockUserWithAlert(ctx, alertID, alert.UserID, actor, req.Reason)
 	case "unlock_user":
 		if alert.UserID == "" {
-			http.Error(w, "Alert has no user ID to unlock", http.StatusBadRequest)
+			writeJSONError(w, http.StatusBadRequest, codeMissingTarget, "Alert has no user ID to unlock")
 			return
 		}
 		remediationErr = s.remediationSvc.UnlockUserWithAlert(ctx, alertID, alert.UserID, actor, req.Reason)
 	case "revoke_key":
 		// Extract key ID from alert evidence
-		keyID, ok := alert.Evidence["key_id"].(string)
-		if !ok || keyID == "" {
-			http.Error(w, "Alert has no key ID to revoke", http.StatusBadRequest)
+		keyID, err := alert.Evidence.GetString("key_id")
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, codeMissingTarget, fmt.Sprintf("Alert has no key ID to revoke: %v", err))
 			return
 		}
 		remediationErr = s.remediationSvc.RevokeAPIKeyWithAlert(ctx, alertID, keyID, actor, req.Reason)
 	default:
-		http.Error(w, fmt.Sprintf("Unknown action: %s", req.Action), http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, codeUnknownAction, fmt.Sprintf("Unknown action: %s", req.Action))
 		return
 	}
 
+	if errors.Is(remediationErr, remediation.ErrProtectedAccount) {
+		writeJSONError(w, http.StatusUnprocessableEntity, codeRemediationBlocked, fmt.Sprintf("Remediation blocked: %v", remediationErr))
+		return
+	}
 	if remediationErr != nil {
-		http.Error(w, fmt.Sprintf("Remediation failed: %v", remediationErr), http.StatusInternalServerError)
+		writeJSONError(w, http.StatusInternalServerError, codeRemediationFailed, fmt.Sprintf("Remediation failed: %v", remediationErr))
 		return
 	}
 
-	// Update alert status to resolved
-	if err := s.alertRepo.UpdateAlertStatus(ctx, alertID, models.AlertStatusResolved); err != nil {
-		// Log but don't fail the request
-		fmt.Printf("Failed to update alert status: %v\n", err)
+	// Resolve the alert unless it was already closed, which the status
+	// transitions would reject
+	if !alert.Status.IsTerminal() {
+		if err := s.alertRepo.UpdateAlertStatus(ctx, alertID, models.AlertStatusResolved, actor, req.Reason); err != nil {
+			// Log but don't fail the request
+			log.Printf("Failed to update status of alert %s: %v", alertID, err)
+		}
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -357,28 +878,37 @@ func (s *Server) updateAlertStatus(w http.ResponseWriter, r *http.Request) {
 
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		http.Error(w, "Invalid alert ID", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, codeInvalidAlertID, "Invalid alert ID")
 		return
 	}
 
 	var req struct {
 		Status string `json:"status"`
+		Reason string `json:"reason"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, codeInvalidRequestBody, "Invalid request body")
 		return
 	}
 
 	status := models.AlertStatus(req.Status)
 	if status != models.AlertStatusOpen && status != models.AlertStatusInvestigating &&
 		status != models.AlertStatusResolved && status != models.AlertStatusFalsePositive {
-		http.Error(w, "Invalid status", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, codeInvalidStatus, "Invalid status")
 		return
 	}
 
 	ctx := r.Context()
-	if err := s.alertRepo.UpdateAlertStatus(ctx, id, status); err != nil {
-		http.Error(w, fmt.Sprintf("Failed to update alert status: %v", err), http.StatusInternalServerError)
+	if err := s.alertRepo.UpdateAlertStatus(ctx, id, status, actorFromContext(ctx), req.Reason); err != nil {
+		if errors.Is(err, storage.ErrAlertNotFound) {
+			writeJSONError(w, http.StatusNotFound, codeAlertNotFound, "Alert not found")
+			return
+		}
+		if errors.Is(err, storage.ErrInvalidStatusTransition) {
+			writeJSONError(w, http.StatusConflict, codeInvalidStatusChange, err.Error())
+			return
+		}
+		writeJSONError(w, http.StatusInternalServerError, codeInternal, fmt.Sprintf("Failed to update alert status: %v", err))
 		return
 	}
 
@@ -388,6 +918,61 @@ func (s *Server) updateAlertStatus(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// acknowledgeAlert claims an alert for the calling actor without changing its
+// status. Acknowledging an acknowledged alert returns it unchanged.
+func (s *Server) acknowledgeAlert(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, codeInvalidAlertID, "Invalid alert ID")
+		return
+	}
+
+	ctx := r.Context()
+	alert, err := s.alertRepo.AcknowledgeAlert(ctx, id, actorFromContext(ctx))
+	if err != nil {
+		if errors.Is(err, storage.ErrAlertNotFound) {
+			writeJSONError(w, http.StatusNotFound, codeAlertNotFound, "Alert not found")
+			return
+		}
+		writeJSONError(w, http.StatusInternalServerError, codeInternal, fmt.Sprintf("Failed to acknowledge alert: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(alert)
+}
+
+// getAlertHistory returns the status transitions of an alert
+func (s *Server) getAlertHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, codeInvalidAlertID, "Invalid alert ID")
+		return
+	}
+
+	ctx := r.Context()
+	if _, err := s.alertRepo.GetAlert(ctx, id); err != nil {
+		if errors.Is(err, storage.ErrAlertNotFound) {
+			writeJSONError(w, http.StatusNotFound, codeAlertNotFound, "Alert not found")
+			return
+		}
+		writeJSONError(w, http.StatusInternalServerError, codeInternal, fmt.Sprintf("Failed to get alert: %v", err))
+		return
+	}
+
+	history, err := s.alertRepo.GetStatusHistory(ctx, id)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, codeInternal, fmt.Sprintf("Failed to get alert history: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"history": history,
+		"count":   len(history),
+	})
+}
+
 // listEvents lists events with optional filters
 func (s *Server) listEvents(w http.ResponseWriter, r *http.Request) {
 	// Parse query parameters
@@ -408,23 +993,146 @@ func (s *Server) listEvents(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	eventType := r.URL.Query().Get("event_type")
-	actor := r.URL.Query().Get("actor")
+	filter, err := parseEventFilter(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, codeInvalidParameter, err.Error())
+		return
+	}
+
+	order, err := storage.ParseEventOrder(r.URL.Query().Get("order"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, codeInvalidParameter, err.Error())
+		return
+	}
+
+	// Raw payloads are included unless ?include_raw=false
+	filter.OmitRaw = r.URL.Query().Get("include_raw") == "false"
 
 	ctx := r.Context()
-	events, err := s.eventRepo.ListEvents(ctx, limit, offset, eventType, actor)
+	events, err := s.eventRepo.ListEvents(ctx, limit, offset, filter, order)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to list events: %v", err), http.StatusInternalServerError)
+		writeJSONError(w, http.StatusInternalServerError, codeInternal, fmt.Sprintf("Failed to list events: %v", err))
 		return
 	}
 
+	var body interface{} = events
+	if filter.OmitRaw {
+		summaries := make([]eventSummary, len(events))
+		for idx, event := range events {
+			summaries[idx] = eventSummary{Event: event}
+		}
+		body = summaries
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"events": events,
+		"events": body,
 		"count":  len(events),
 	})
 }
 
+// eventSummary encodes an event without its raw field
+type eventSummary struct {
+	*models.Event
+	// Raw shadows the embedded field and is always nil, so it is omitted
+	Raw *struct{} `json:"raw,omitempty"`
+}
+
+// parseEventFilter reads the event filters shared by listEvents and exportEvents
+func parseEventFilter(r *http.Request) (storage.EventFilter, error) {
+	query := r.URL.Query()
+	filter := storage.EventFilter{
+		EventType: query.Get("event_type"),
+		Actor:     query.Get("actor"),
+		IPScope:   query.Get("ip_scope"),
+		ProjectID: query.Get("project_id"),
+	}
+	switch filter.IPScope {
+	case "", models.IPScopeInternal, models.IPScopePublic:
+	default:
+		return filter, fmt.Errorf("invalid ip_scope %q: must be internal or public", filter.IPScope)
+	}
+
+	var err error
+	if filter.From, err = parseTimeParam(query.Get("from")); err != nil {
+		return filter, fmt.Errorf("invalid from: %w", err)
+	}
+	if filter.To, err = parseTimeParam(query.Get("to")); err != nil {
+		return filter, fmt.Errorf("invalid to: %w", err)
+	}
+	if filter.Raw, err = parseRawFilters(query); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+// maxRawFilters caps the number of raw.* filters accepted on one request
+const maxRawFilters = 5
+
+// rawPathSegment restricts raw filter keys to plain identifiers
+var rawPathSegment = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// parseRawFilters parses filters on the raw event payload. A parameter
+// raw.<path>=<value> matches a field exactly and raw.<path>[contains]=<value>
+// matches a substring, where <path> is a dot-separated list of nested keys.
+func parseRawFilters(query url.Values) ([]storage.RawFilter, error) {
+	var filters []storage.RawFilter
+	for key, values := range query {
+		if !strings.HasPrefix(key, "raw.") {
+			continue
+		}
+
+		path := strings.TrimPrefix(key, "raw.")
+		operator := storage.RawEquals
+		if strings.HasSuffix(path, "]") {
+			open := strings.LastIndex(path, "[")
+			if open < 0 {
+				return nil, fmt.Errorf("invalid raw filter %q", key)
+			}
+			operator = storage.RawOperator(path[open+1 : len(path)-1])
+			path = path[:open]
+			if operator != storage.RawEquals && operator != storage.RawContains {
+				return nil, fmt.Errorf("invalid raw filter %q: unsupported operator %q", key, operator)
+			}
+		}
+
+		segments := strings.Split(path, ".")
+		for _, segment := range segments {
+			if !rawPathSegment.MatchString(segment) {
+				return nil, fmt.Errorf("invalid raw filter %q: bad field name %q", key, segment)
+			}
+		}
+
+		for _, value := range values {
+			filters = append(filters, storage.RawFilter{Path: segments, Operator: operator, Value: value})
+		}
+	}
+
+	if len(filters) > maxRawFilters {
+		return nil, fmt.Errorf("too many raw filters: at most %d allowed", maxRawFilters)
+	}
+
+	// Map iteration order is random; keep the generated SQL stable
+	sort.Slice(filters, func(a, b int) bool {
+		return strings.Join(filters[a].Path, ".") < strings.Join(filters[b].Path, ".")
+	})
+
+	return filters, nil
+}
+
+// parseTimeParam parses an optional RFC3339 query parameter
+func parseTimeParam(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
 // getEvent retrieves a single event by ID
 func (s *Server) getEvent(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -432,18 +1140,18 @@ func (s *Server) getEvent(w http.ResponseWriter, r *http.Request) {
 
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, codeInvalidEventID, "Invalid event ID")
 		return
 	}
 
 	ctx := r.Context()
 	event, err := s.eventRepo.GetEventByID(ctx, id)
 	if err != nil {
-		if err.Error() == "event not found" {
-			http.Error(w, "Event not found", http.StatusNotFound)
+		if errors.Is(err, storage.ErrEventNotFound) {
+			writeJSONError(w, http.StatusNotFound, codeEventNotFound, "Event not found")
 			return
 		}
-		http.Error(w, fmt.Sprintf("Failed to get event: %v", err), http.StatusInternalServerError)
+		writeJSONError(w, http.StatusInternalServerError, codeInternal, fmt.Sprintf("Failed to get event: %v", err))
 		return
 	}
 
@@ -451,33 +1159,324 @@ func (s *Server) getEvent(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(event)
 }
 
-// triggerIngestion manually triggers event ingestion
-func (s *Server) triggerIngestion(w http.ResponseWriter, r *http.Request) {
+// reprocessEvent runs a stored event through detection again and returns the
+// alerts raised. The alerts are stored like newly detected ones unless
+// ?store=false, which only reports what the rules would raise.
+func (s *Server) reprocessEvent(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, codeInvalidEventID, "Invalid event ID")
+		return
+	}
+	store := r.URL.Query().Get("store") != "false"
+
 	ctx := r.Context()
-	if err := s.ingestor.Ingest(ctx); err != nil {
-		http.Error(w, fmt.Sprintf("Ingestion failed: %v", err), http.StatusInternalServerError)
+	event, err := s.eventRepo.GetEventByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, storage.ErrEventNotFound) {
+			writeJSONError(w, http.StatusNotFound, codeEventNotFound, "Event not found")
+			return
+		}
+		writeJSONError(w, http.StatusInternalServerError, codeInternal, fmt.Sprintf("Failed to get event: %v", err))
 		return
 	}
 
+	alerts, ruleErr := s.engine.Evaluate(ctx, event)
+	if store {
+		alerts = s.engine.StoreAlerts(ctx, event, alerts)
+	}
+	if alerts == nil {
+		alerts = []*models.Alert{}
+	}
+
+	response := map[string]interface{}{
+		"event_id": event.ID,
+		"stored":   store,
+		"alerts":   alerts,
+		"count":    len(alerts),
+	}
+	// Rules that failed raised nothing; report why alongside the others' alerts
+	if ruleErr != nil {
+		response["rule_errors"] = strings.Split(ruleErr.Error(), "\n")
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	json.NewEncoder(w).Encode(response)
+}
+
+// getEventByEventID retrieves a single event by the ID assigned by its source
+func (s *Server) getEventByEventID(w http.ResponseWriter, r *http.Request) {
+	eventID := mux.Vars(r)["eventID"]
+
+	ctx := r.Context()
+	event, err := s.eventRepo.GetEventByEventID(ctx, eventID)
+	if err != nil {
+		if errors.Is(err, storage.ErrEventNotFound) {
+			writeJSONError(w, http.StatusNotFound, codeEventNotFound, "Event not found")
+			return
+		}
+		writeJSONError(w, http.StatusInternalServerError, codeInternal, fmt.Sprintf("Failed to get event: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(event)
+}
+
+// triggerIngestion manually triggers event ingestion. With from and to it
+// fetches that window instead of resuming from the cursor.
+func (s *Server) triggerIngestion(w http.ResponseWriter, r *http.Request) {
+	from, err := parseTimeParam(r.URL.Query().Get("from"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, codeInvalidParameter, fmt.Sprintf("invalid from: %v", err))
+		return
+	}
+	to, err := parseTimeParam(r.URL.Query().Get("to"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, codeInvalidParameter, fmt.Sprintf("invalid to: %v", err))
+		return
+	}
+	if (from == nil) != (to == nil) {
+		writeJSONError(w, http.StatusBadRequest, codeInvalidParameter, "from and to must be given together")
+		return
+	}
+
+	ctx := r.Context()
+	response := map[string]interface{}{
 		"status":  "success",
 		"message": "Ingestion triggered successfully",
+	}
+	if from != nil {
+		var fetched int
+		fetched, err = s.ingestor.IngestRange(ctx, *from, *to)
+		response["fetched"] = fetched
+	} else {
+		err = s.ingestor.Ingest(ctx)
+	}
+	if err != nil {
+		if errors.Is(err, ingestion.ErrIngestionRunning) {
+			writeJSONError(w, http.StatusConflict, codeIngestionRunning, "Ingestion already in progress")
+			return
+		}
+		if errors.Is(err, ingestion.ErrInvalidRange) {
+			writeJSONError(w, http.StatusBadRequest, codeInvalidParameter, err.Error())
+			return
+		}
+		writeJSONError(w, http.StatusInternalServerError, codeIngestionFailed, fmt.Sprintf("Ingestion failed: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// ingestItemError reports why a single pushed event was rejected
+type ingestItemError struct {
+	Index  int          `json:"index"`
+	Error  string       `json:"error"`
+	Fields []fieldError `json:"fields,omitempty"`
+}
+
+// ingestionStatus reports when ingestion last ran and whether it is keeping up
+func (s *Server) ingestionStatus(w http.ResponseWriter, r *http.Request) {
+	response := struct {
+		ingestion.Stats
+		LagSeconds *float64 `json:"lag_seconds"`
+		// Detection failures are only counted when the database answers
+		DetectionRetryPending *int `json:"detection_retry_pending"`
+		DetectionDeadLettered *int `json:"detection_dead_lettered"`
+	}{Stats: s.ingestor.Stats()}
+	if response.LastSuccessEnd != nil {
+		lag := time.Since(*response.LastSuccessEnd).Seconds()
+		response.LagSeconds = &lag
+	}
+	pending, dead, err := s.eventRepo.CountDetectionFailures(r.Context())
+	if err != nil {
+		log.Printf("Failed to count detection failures: %v", err)
+	} else {
+		response.DetectionRetryPending = &pending
+		response.DetectionDeadLettered = &dead
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// ingestEvents accepts events pushed by external systems, either as a single
+// JSON object or an array, and runs them through the normal ingestion path.
+// Events failing validateInboundEvent are reported per field and skipped.
+// Bodies over INGEST_MAX_BODY_BYTES or INGEST_MAX_EVENTS are rejected whole.
+func (s *Server) ingestEvents(w http.ResponseWriter, r *http.Request) {
+	maxBytes := s.config.Server.IngestMaxBodyBytes
+	var body json.RawMessage
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, int64(maxBytes))).Decode(&body); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeJSONError(w, http.StatusRequestEntityTooLarge, codeRequestTooLarge,
+				fmt.Sprintf("Request body exceeds %d bytes", maxBytes))
+			return
+		}
+		writeJSONError(w, http.StatusBadRequest, codeInvalidRequestBody, "Invalid request body")
+		return
+	}
+
+	var items []map[string]any
+	trimmed := strings.TrimSpace(string(body))
+	if strings.HasPrefix(trimmed, "[") {
+		if err := json.Unmarshal(body, &items); err != nil {
+			writeJSONError(w, http.StatusBadRequest, codeInvalidRequestBody, "Request body must be an event object or an array of event objects")
+			return
+		}
+	} else {
+		var item map[string]any
+		if err := json.Unmarshal(body, &item); err != nil || item == nil {
+			writeJSONError(w, http.StatusBadRequest, codeInvalidRequestBody, "Request body must be an event object or an array of event objects")
+			return
+		}
+		items = []map[string]any{item}
+	}
+	if maxEvents := s.config.Server.IngestMaxEvents; len(items) > maxEvents {
+		writeJSONError(w, http.StatusRequestEntityTooLarge, codeRequestTooLarge,
+			fmt.Sprintf("Request holds %d events, at most %d are accepted per request", len(items), maxEvents))
+		return
+	}
+
+	ctx := r.Context()
+	accepted, duplicates := 0, 0
+	itemErrors := []ingestItemError{}
+	for idx, raw := range items {
+		if fields := validateInboundEvent(raw); len(fields) > 0 {
+			itemErrors = append(itemErrors, ingestItemError{Index: idx, Error: "event does not match the inbound schema", Fields: fields})
+			continue
+		}
+		event, err := scaleway.MapToAuditEvent(raw)
+		if err != nil {
+			itemErrors = append(itemErrors, ingestItemError{Index: idx, Error: err.Error()})
+			continue
+		}
+		event.Source = "webhook"
+
+		stored, err := s.ingestor.IngestEvent(ctx, event)
+		if err != nil {
+			itemErrors = append(itemErrors, ingestItemError{Index: idx, Error: err.Error()})
+			continue
+		}
+		if stored {
+			accepted++
+		} else {
+			duplicates++
+		}
+	}
+
+	statusCode := http.StatusOK
+	if len(items) > 0 && len(itemErrors) == len(items) {
+		statusCode = http.StatusUnprocessableEntity
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"accepted":   accepted,
+		"duplicates": duplicates,
+		"errors":     itemErrors,
 	})
 }
 
+// reloadConfig applies updated detection thresholds and returns the settings
+// now in effect
+func (s *Server) reloadConfig(w http.ResponseWriter, r *http.Request) {
+	detection, err := s.ReloadConfig()
+	if err != nil {
+		writeJSONError(w, http.StatusUnprocessableEntity, codeInvalidConfig, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(detection)
+}
+
+// getDetectionConfig returns the effective detection configuration: the
+// rules loaded in the engine, the registered rules that are disabled, and the
+// live settings including any reload
+func (s *Server) getDetectionConfig(w http.ResponseWriter, r *http.Request) {
+	rules := s.engine.Rules()
+	loaded := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		loaded[rule.Name] = true
+	}
+	disabled := []string{}
+	for _, name := range detection.RuleNames() {
+		if !loaded[name] {
+			disabled = append(disabled, name)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"rules":          rules,
+		"disabled_rules": disabled,
+		"settings":       s.config.CurrentDetection(),
+	})
+}
+
+// getUserProfile returns a user's risk profile with the risk score decayed
+// to the current time
 func (s *Server) getUserProfile(w http.ResponseWriter, r *http.Request) {
-	http.Error(w, "Not implemented", http.StatusNotImplemented)
+	userID := mux.Vars(r)["id"]
+
+	ctx := r.Context()
+	profile, err := s.profileRepo.GetUserProfile(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserProfileNotFound) {
+			writeJSONError(w, http.StatusNotFound, codeUserProfileNotFound, "User profile not found")
+			return
+		}
+		writeJSONError(w, http.StatusInternalServerError, codeInternal, fmt.Sprintf("Failed to get user profile: %v", err))
+		return
+	}
+
+	profile.RiskScore = detection.DecayedRiskScore(profile, time.Now())
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(profile)
+}
+
+// containUser locks a user and revokes all of their API keys, returning what
+// succeeded and what failed
+func (s *Server) containUser(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["id"]
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, codeInvalidRequestBody, "Invalid request body")
+		return
+	}
+
+	ctx := r.Context()
+	result, err := s.remediationSvc.ContainUser(ctx, userID, actorFromContext(ctx), req.Reason)
+	if errors.Is(err, remediation.ErrProtectedAccount) {
+		writeJSONError(w, http.StatusUnprocessableEntity, codeRemediationBlocked, fmt.Sprintf("Remediation blocked: %v", err))
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, codeRemediationFailed, fmt.Sprintf("Containment failed: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
 }
 
 func (s *Server) getUserHistory(w http.ResponseWriter, r *http.Request) {
-	http.Error(w, "Not implemented", http.StatusNotImplemented)
+	writeJSONError(w, http.StatusNotImplemented, codeNotImplemented, "Not implemented")
 }
 
 func (s *Server) listRules(w http.ResponseWriter, r *http.Request) {
-	http.Error(w, "Not implemented", http.StatusNotImplemented)
+	writeJSONError(w, http.StatusNotImplemented, codeNotImplemented, "Not implemented")
 }
 
 func (s *Server) updateRule(w http.ResponseWriter, r *http.Request) {
-	http.Error(w, "Not implemented", http.StatusNotImplemented)
+	writeJSONError(w, http.StatusNotImplemented, codeNotImplemented, "Not implemented")
 }
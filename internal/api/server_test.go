@@ -0,0 +1,84 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scaleway/audit-sentinel/internal/storage"
+	"github.com/scaleway/audit-sentinel/internal/storage/storagetest"
+)
+
+func TestWithActor(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{name: "no header", want: "api"},
+		{name: "blank header", header: "   ", want: "api"},
+		{name: "claimed name", header: "alice@example.com", want: "api (claimed: alice@example.com)"},
+		{name: "control characters dropped", header: "alice\r\nINFO forged", want: "api (claimed: aliceINFO forged)"},
+		{name: "long name truncated", header: strings.Repeat("a", 200), want: "api (claimed: " + strings.Repeat("a", maxClaimedActorLen) + ")"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/alerts", nil)
+			if tt.header != "" {
+				r.Header.Set(ActorHeader, tt.header)
+			}
+			if got := actorFromContext(withActor(r).Context()); got != tt.want {
+				t.Errorf("actor = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShutdownStopsWorkersAndRefusesNewOnes(t *testing.T) {
+	s := &Server{
+		httpServer: &http.Server{},
+		dbHealth:   storage.NewHealthChecker(storagetest.New(t, nil).DB, time.Hour),
+		done:       make(chan struct{}),
+	}
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- s.StartDBHealth(context.Background()) }()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		s.mu.Lock()
+		started := s.healthCancel != nil
+		s.mu.Unlock()
+		if started {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("health checker did not start")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	select {
+	case <-stopped:
+	default:
+		t.Fatal("Shutdown returned before the running worker stopped")
+	}
+
+	for name, start := range map[string]func(context.Context) error{
+		"StartDBHealth":       s.StartDBHealth,
+		"StartDetectionRetry": s.StartDetectionRetry,
+	} {
+		if err := start(context.Background()); !errors.Is(err, ErrShuttingDown) {
+			t.Errorf("%s after Shutdown = %v, want ErrShuttingDown", name, err)
+		}
+	}
+}
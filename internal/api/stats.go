@@ -0,0 +1,61 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// statsEventWindow is the look-back window for the event count KPI
+const statsEventWindow = 24 * time.Hour
+
+// DashboardStats holds the headline numbers shown on the dashboard
+type DashboardStats struct {
+	EventsLast24h        int            `json:"events_last_24h"`
+	OpenAlerts           int            `json:"open_alerts"`
+	OpenAlertsBySeverity map[string]int `json:"open_alerts_by_severity"`
+	RemediationsToday    int            `json:"remediations_today"`
+	LastEventAt          *time.Time     `json:"last_event_at"`
+	IngestionLagSeconds  *float64       `json:"ingestion_lag_seconds"`
+	GeneratedAt          time.Time      `json:"generated_at"`
+}
+
+// getStats returns the dashboard KPIs in a single response
+func (s *Server) getStats(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	now := time.Now()
+	stats := DashboardStats{GeneratedAt: now}
+
+	var err error
+	if stats.EventsLast24h, err = s.eventRepo.CountEventsSince(ctx, now.Add(-statsEventWindow)); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, codeInternal, fmt.Sprintf("Failed to count events: %v", err))
+		return
+	}
+
+	if stats.OpenAlertsBySeverity, err = s.alertRepo.CountOpenBySeverity(ctx); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, codeInternal, fmt.Sprintf("Failed to count open alerts: %v", err))
+		return
+	}
+	for _, count := range stats.OpenAlertsBySeverity {
+		stats.OpenAlerts += count
+	}
+
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if stats.RemediationsToday, err = s.remediationRepo.CountRemediationsSince(ctx, startOfDay); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, codeInternal, fmt.Sprintf("Failed to count remediations: %v", err))
+		return
+	}
+
+	if stats.LastEventAt, err = s.eventRepo.GetLastEventTimestamp(ctx); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, codeInternal, fmt.Sprintf("Failed to get last event: %v", err))
+		return
+	}
+	if stats.LastEventAt != nil {
+		lag := now.Sub(*stats.LastEventAt).Seconds()
+		stats.IngestionLagSeconds = &lag
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
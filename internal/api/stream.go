@@ -0,0 +1,64 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// streamHeartbeatInterval is how often an idle alert stream sends a comment
+// so proxies do not close the connection
+const streamHeartbeatInterval = 15 * time.Second
+
+// streamAlerts pushes newly stored alerts to the client as Server-Sent Events
+func (s *Server) streamAlerts(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+	// The server's write timeout would otherwise end the stream
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, codeInternal, "Streaming is not supported")
+		return
+	}
+
+	ctx := r.Context()
+	alerts := s.alertHub.Subscribe(ctx)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case alert, ok := <-alerts:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(alert)
+			if err != nil {
+				log.Printf("Failed to encode alert %s for stream: %v", alert.ID, err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "id: %s\nevent: alert\ndata: %s\n\n", alert.ID, data); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
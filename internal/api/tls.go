@@ -0,0 +1,38 @@
+package api
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+)
+
+// tlsVersions maps TLS_MIN_VERSION values to crypto/tls versions
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// newTLSConfig returns the server TLS settings for the configured minimum
+// version, defaulting to TLS 1.2
+func newTLSConfig(minVersion string) *tls.Config {
+	version, ok := tlsVersions[minVersion]
+	if !ok {
+		version = tls.VersionTLS12
+	}
+	return &tls.Config{MinVersion: version}
+}
+
+// httpsRedirect redirects every request to the same host and path over
+// HTTPS on httpsPort
+func httpsRedirect(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
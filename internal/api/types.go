@@ -0,0 +1,71 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/scaleway/audit-sentinel/internal/storage"
+)
+
+// typesCacheTTL is how long type discovery results are served from memory;
+// the underlying GROUP BY scans the whole table
+const typesCacheTTL = time.Minute
+
+// typesCache holds the last result of a type count query for typesCacheTTL
+type typesCache struct {
+	mu      sync.Mutex
+	load    func(ctx context.Context) ([]storage.TypeCount, error)
+	types   []storage.TypeCount
+	expires time.Time
+}
+
+func newTypesCache(load func(ctx context.Context) ([]storage.TypeCount, error)) *typesCache {
+	return &typesCache{load: load}
+}
+
+// get returns the cached counts, reloading them once they have expired.
+// Concurrent callers wait for a single reload.
+func (c *typesCache) get(ctx context.Context) ([]storage.TypeCount, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.types != nil && time.Now().Before(c.expires) {
+		return c.types, nil
+	}
+
+	types, err := c.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.types = types
+	c.expires = time.Now().Add(typesCacheTTL)
+	return types, nil
+}
+
+// listEventTypes returns the stored event types with their counts
+func (s *Server) listEventTypes(w http.ResponseWriter, r *http.Request) {
+	s.writeTypes(w, r, s.eventTypes, "event")
+}
+
+// listAlertTypes returns the stored alert types with their counts
+func (s *Server) listAlertTypes(w http.ResponseWriter, r *http.Request) {
+	s.writeTypes(w, r, s.alertTypes, "alert")
+}
+
+func (s *Server) writeTypes(w http.ResponseWriter, r *http.Request, cache *typesCache, kind string) {
+	types, err := cache.get(r.Context())
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, codeInternal, fmt.Sprintf("Failed to list %s types: %v", kind, err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"types": types,
+		"count": len(types),
+	})
+}
@@ -0,0 +1,338 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Validate checks the configuration for invalid values and inconsistent
+// combinations, returning every problem found joined into a single error
+func (c *Config) Validate() error {
+	var errs []error
+	add := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	// Database
+	if c.Database.URL == "" {
+		add("DB_URL is required")
+	}
+	if c.Database.MaxOpenConns < 0 {
+		add("DB_MAX_OPEN_CONNS must be >= 0 (0 means unlimited), got %d", c.Database.MaxOpenConns)
+	}
+	if c.Database.StatementTimeout < 0 {
+		add("DB_STATEMENT_TIMEOUT must be >= 0 (0 disables it), got %s", c.Database.StatementTimeout)
+	}
+	if c.Database.ConnectRetries < 0 {
+		add("DB_CONNECT_RETRIES must be >= 0, got %d", c.Database.ConnectRetries)
+	}
+	if c.Database.ConnectRetries > 0 && c.Database.ConnectRetryBackoff <= 0 {
+		add("DB_CONNECT_RETRY_BACKOFF must be > 0 when DB_CONNECT_RETRIES is set, got %s", c.Database.ConnectRetryBackoff)
+	}
+	if c.Database.HealthCheckInterval <= 0 {
+		add("DB_HEALTH_CHECK_INTERVAL must be > 0, got %s", c.Database.HealthCheckInterval)
+	}
+	if c.Database.MaxIdleConns < 0 {
+		add("DB_MAX_IDLE_CONNS must be >= 0, got %d", c.Database.MaxIdleConns)
+	}
+	if c.Database.MaxOpenConns > 0 && c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		add("DB_MAX_IDLE_CONNS (%d) must not exceed DB_MAX_OPEN_CONNS (%d)", c.Database.MaxIdleConns, c.Database.MaxOpenConns)
+	}
+
+	// Scaleway
+	seenTenants := make(map[string]bool)
+	for _, entry := range c.Scaleway.Tenants {
+		projectID, organizationID, ok := strings.Cut(entry, ":")
+		if !ok || (strings.TrimSpace(projectID) == "" && strings.TrimSpace(organizationID) == "") {
+			add("SCALEWAY_TENANTS entries must look like project_id:organization_id, got %q", entry)
+			continue
+		}
+		if seenTenants[entry] {
+			add("SCALEWAY_TENANTS lists %q more than once", entry)
+		}
+		seenTenants[entry] = true
+	}
+	if !strings.HasPrefix(c.Scaleway.AuditEventsPath, "/") {
+		add("SCALEWAY_AUDIT_EVENTS_PATH must be a path starting with /, got %q", c.Scaleway.AuditEventsPath)
+	}
+	if !strings.HasPrefix(c.Scaleway.LoginLogsPath, "/") {
+		add("SCALEWAY_LOGIN_LOGS_PATH must be a path starting with /, got %q", c.Scaleway.LoginLogsPath)
+	}
+	if strings.TrimSpace(c.Scaleway.AuditEventsListKey) == "" {
+		add("SCALEWAY_AUDIT_EVENTS_LIST_KEY must not be empty")
+	}
+	if strings.TrimSpace(c.Scaleway.LoginLogsListKey) == "" {
+		add("SCALEWAY_LOGIN_LOGS_LIST_KEY must not be empty")
+	}
+	if c.Scaleway.MaxStalePages <= 0 {
+		add("SCALEWAY_MAX_STALE_PAGES must be > 0, got %d", c.Scaleway.MaxStalePages)
+	}
+
+	// Server
+	if c.Server.IdempotencyTTL <= 0 {
+		add("IDEMPOTENCY_TTL must be > 0, got %s", c.Server.IdempotencyTTL)
+	}
+	if c.Server.IngestMaxBodyBytes <= 0 {
+		add("INGEST_MAX_BODY_BYTES must be > 0, got %d", c.Server.IngestMaxBodyBytes)
+	}
+	if c.Server.IngestMaxEvents <= 0 {
+		add("INGEST_MAX_EVENTS must be > 0, got %d", c.Server.IngestMaxEvents)
+	}
+	for _, origin := range c.Server.CORSAllowedOrigins {
+		if origin == "*" {
+			if len(c.Server.CORSAllowedOrigins) > 1 {
+				add("CORS_ALLOWED_ORIGINS must not combine \"*\" with other origins")
+			}
+			continue
+		}
+		if !strings.HasPrefix(origin, "http://") && !strings.HasPrefix(origin, "https://") {
+			add("CORS_ALLOWED_ORIGINS entries must be \"*\" or start with http:// or https://, got %q", origin)
+		}
+	}
+
+	// TLS
+	if (c.Server.TLSCertFile == "") != (c.Server.TLSKeyFile == "") {
+		add("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	switch c.Server.TLSMinVersion {
+	case "1.2", "1.3":
+	default:
+		add("TLS_MIN_VERSION must be 1.2 or 1.3, got %q", c.Server.TLSMinVersion)
+	}
+	if c.Server.HTTPRedirectPort != "" {
+		if !c.Server.TLSEnabled() {
+			add("TLS_HTTP_REDIRECT_PORT requires TLS_CERT_FILE and TLS_KEY_FILE")
+		}
+		if port, err := strconv.Atoi(c.Server.HTTPRedirectPort); err != nil || !validPort(port) {
+			add("TLS_HTTP_REDIRECT_PORT must be a port number between 1 and 65535, got %q", c.Server.HTTPRedirectPort)
+		} else if c.Server.HTTPRedirectPort == c.Server.Port {
+			add("TLS_HTTP_REDIRECT_PORT (%s) must differ from SERVER_PORT", c.Server.HTTPRedirectPort)
+		}
+	}
+
+	// Ports
+	serverPort, err := strconv.Atoi(c.Server.Port)
+	if err != nil || !validPort(serverPort) {
+		add("SERVER_PORT must be a port number between 1 and 65535, got %q", c.Server.Port)
+	}
+	if c.Observability.PrometheusEnabled {
+		if !validPort(c.Observability.PrometheusPort) {
+			add("PROMETHEUS_PORT must be between 1 and 65535, got %d", c.Observability.PrometheusPort)
+		} else if err == nil && serverPort == c.Observability.PrometheusPort {
+			add("PROMETHEUS_PORT (%d) must differ from SERVER_PORT", c.Observability.PrometheusPort)
+		}
+	}
+
+	// Tracing
+	if c.Observability.TracingEnabled {
+		if u, err := url.Parse(c.Observability.TracingEndpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add("TRACING_ENDPOINT must be an http(s) URL, got %q", c.Observability.TracingEndpoint)
+		}
+		if c.Observability.TracingSampleRatio < 0 || c.Observability.TracingSampleRatio > 1 {
+			add("TRACING_SAMPLE_RATIO must be between 0 and 1, got %g", c.Observability.TracingSampleRatio)
+		}
+	}
+
+	// Rate limiting
+	if c.Security.RateLimitRPS < 0 {
+		add("RATE_LIMIT_RPS must be >= 0, got %g", c.Security.RateLimitRPS)
+	}
+	if c.Security.RateLimitRPS > 0 && c.Security.RateLimitBurst < 1 {
+		add("RATE_LIMIT_BURST must be >= 1 when rate limiting is enabled, got %d", c.Security.RateLimitBurst)
+	}
+
+	// Ingestion
+	if c.Ingestion.PollIntervalSeconds <= 0 {
+		add("POLL_INTERVAL_SECONDS must be > 0, got %d", c.Ingestion.PollIntervalSeconds)
+	}
+	if c.Ingestion.BatchSize <= 0 {
+		add("INGEST_BATCH_SIZE must be > 0, got %d", c.Ingestion.BatchSize)
+	}
+	if c.Ingestion.MaxRetries < 0 {
+		add("INGEST_MAX_RETRIES must be >= 0, got %d", c.Ingestion.MaxRetries)
+	}
+	if c.Ingestion.SinceOverlap < 0 {
+		add("INGEST_SINCE_OVERLAP must be >= 0, got %s", c.Ingestion.SinceOverlap)
+	}
+	if c.Ingestion.LogSampleFirst < 0 {
+		add("INGEST_LOG_SAMPLE_FIRST must be >= 0, got %d", c.Ingestion.LogSampleFirst)
+	}
+	if c.Ingestion.LogSampleEvery < 0 {
+		add("INGEST_LOG_SAMPLE_EVERY must be >= 0, got %d", c.Ingestion.LogSampleEvery)
+	}
+	if c.Ingestion.MaxRangeSpan <= 0 {
+		add("INGEST_MAX_RANGE_SPAN must be > 0, got %s", c.Ingestion.MaxRangeSpan)
+	}
+	for _, mapping := range c.Ingestion.EventTypeMappings {
+		raw, canonical, ok := strings.Cut(mapping, "=")
+		if !ok || strings.TrimSpace(raw) == "" || strings.TrimSpace(canonical) == "" {
+			add("EVENT_TYPE_MAP entries must look like raw=canonical, got %q", mapping)
+		}
+	}
+	if c.Ingestion.DetectionWorkers < 0 {
+		add("DETECTION_WORKERS must be >= 0, got %d", c.Ingestion.DetectionWorkers)
+	}
+	if c.Ingestion.DetectionWorkers > 0 && c.Ingestion.DetectionQueueSize <= 0 {
+		add("DETECTION_QUEUE_SIZE must be > 0, got %d", c.Ingestion.DetectionQueueSize)
+	}
+	switch c.Ingestion.DetectionQueueFullPolicy {
+	case "block", "drop":
+	default:
+		add("DETECTION_QUEUE_FULL_POLICY must be block or drop, got %q", c.Ingestion.DetectionQueueFullPolicy)
+	}
+	if c.Ingestion.DetectionRetryInterval <= 0 {
+		add("DETECTION_RETRY_INTERVAL must be > 0, got %s", c.Ingestion.DetectionRetryInterval)
+	}
+	if c.Ingestion.DetectionRetryBackoff <= 0 {
+		add("DETECTION_RETRY_BACKOFF must be > 0, got %s", c.Ingestion.DetectionRetryBackoff)
+	}
+	if c.Ingestion.DetectionRetryMaxAttempts < 1 {
+		add("DETECTION_RETRY_MAX_ATTEMPTS must be >= 1, got %d", c.Ingestion.DetectionRetryMaxAttempts)
+	}
+
+	// Retention
+	if c.Retention.EventRetentionDays < 0 {
+		add("EVENT_RETENTION_DAYS must be >= 0, got %d", c.Retention.EventRetentionDays)
+	}
+	if c.Retention.JanitorInterval <= 0 {
+		add("RETENTION_JANITOR_INTERVAL must be > 0, got %s", c.Retention.JanitorInterval)
+	}
+
+	// Threat intel
+	if c.ThreatIntel.Source != "" && c.ThreatIntel.RefreshInterval <= 0 {
+		add("THREAT_INTEL_REFRESH_INTERVAL must be > 0, got %s", c.ThreatIntel.RefreshInterval)
+	}
+
+	// Detection
+	if err := c.Detection.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	// Security
+	if c.Security.JWTExpiryHours <= 0 {
+		add("JWT_EXPIRY_HOURS must be > 0, got %d", c.Security.JWTExpiryHours)
+	}
+	if c.Security.BCryptCost < 4 || c.Security.BCryptCost > 31 {
+		add("BCRYPT_COST must be between 4 and 31, got %d", c.Security.BCryptCost)
+	}
+
+	// Remediation
+	if c.Remediation.AutoRemediate && len(c.Remediation.AutoRemediateAlertTypes) == 0 {
+		add("AUTO_REMEDIATE_ALERT_TYPES must list at least one alert type when AUTO_REMEDIATE is set")
+	}
+	for _, mapping := range c.Remediation.RecommendedActions {
+		alertType, action, ok := strings.Cut(mapping, "=")
+		if !ok || strings.TrimSpace(alertType) == "" {
+			add("REMEDIATION_RECOMMENDED_ACTIONS entries must look like alert_type=action, got %q", mapping)
+			continue
+		}
+		switch strings.TrimSpace(action) {
+		case "lock_user", "unlock_user", "revoke_key", "none":
+		default:
+			add("REMEDIATION_RECOMMENDED_ACTIONS action must be lock_user, unlock_user, revoke_key or none, got %q", mapping)
+		}
+	}
+
+	// Notifiers
+	if c.Notification.EmailSMTPHost != "" {
+		if c.Notification.EmailFrom == "" {
+			add("EMAIL_FROM is required when EMAIL_SMTP_HOST is set")
+		}
+		if c.Notification.EmailTo == "" {
+			add("EMAIL_TO is required when EMAIL_SMTP_HOST is set")
+		}
+		if !validPort(c.Notification.EmailSMTPPort) {
+			add("EMAIL_SMTP_PORT must be between 1 and 65535, got %d", c.Notification.EmailSMTPPort)
+		}
+	}
+	if c.Notification.GenericWebhookURL != "" {
+		if u, err := url.Parse(c.Notification.GenericWebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add("GENERIC_WEBHOOK_URL must be an absolute http(s) URL, got %q", c.Notification.GenericWebhookURL)
+		}
+	}
+	if c.Notification.GenericWebhookSecret != "" && c.Notification.GenericWebhookURL == "" {
+		add("GENERIC_WEBHOOK_SECRET is set but GENERIC_WEBHOOK_URL is empty")
+	}
+
+	return errors.Join(errs...)
+}
+
+// Validate checks the detection settings, returning every problem found
+func (d DetectionConfig) Validate() error {
+	var errs []error
+	add := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if d.FailedLoginWindowMin <= 0 {
+		add("FAILED_LOGIN_WINDOW_MIN must be > 0, got %d", d.FailedLoginWindowMin)
+	}
+	if d.FailedLoginThreshold <= 0 {
+		add("FAILED_LOGIN_THRESHOLD must be > 0, got %d", d.FailedLoginThreshold)
+	}
+	if d.ImpossibleTravelSpeed <= 0 {
+		add("IMPOSSIBLE_TRAVEL_SPEED_KMH must be > 0, got %g", d.ImpossibleTravelSpeed)
+	}
+	if d.RuleTimeout <= 0 {
+		add("DETECTION_RULE_TIMEOUT must be > 0, got %s", d.RuleTimeout)
+	}
+	if d.RuleConcurrency <= 0 {
+		add("DETECTION_RULE_CONCURRENCY must be > 0, got %d", d.RuleConcurrency)
+	}
+	if d.NewCountryMinHistory < 0 {
+		add("NEW_COUNTRY_MIN_HISTORY must be >= 0, got %d", d.NewCountryMinHistory)
+	}
+	if d.APIKeyBurstWindowMin <= 0 {
+		add("API_KEY_BURST_WINDOW_MIN must be > 0, got %d", d.APIKeyBurstWindowMin)
+	}
+	if d.APIKeyBurstThreshold <= 0 {
+		add("API_KEY_BURST_THRESHOLD must be > 0, got %d", d.APIKeyBurstThreshold)
+	}
+	switch d.APIKeyServiceAccountAction {
+	case ServiceAccountDowngrade, ServiceAccountSuppress:
+	default:
+		add("API_KEY_SERVICE_ACCOUNT_ACTION must be downgrade or suppress, got %q", d.APIKeyServiceAccountAction)
+	}
+	for _, pattern := range d.APIKeyServiceAccounts {
+		if _, err := path.Match(pattern, ""); err != nil {
+			add("API_KEY_SERVICE_ACCOUNTS contains invalid pattern %q", pattern)
+		}
+	}
+	if d.NewKeyRapidUseWindowMin <= 0 {
+		add("NEW_KEY_RAPID_USE_WINDOW_MIN must be > 0, got %d", d.NewKeyRapidUseWindowMin)
+	}
+	if len(d.SensitiveResources) == 0 {
+		add("SENSITIVE_RESOURCES must list at least one pattern")
+	}
+	if len(d.PrivilegedPolicies) == 0 {
+		add("PRIVILEGED_POLICIES must list at least one pattern")
+	}
+	for _, entry := range d.SeverityOverrides {
+		rule, severity, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(rule) == "" {
+			add("DETECTION_SEVERITY_OVERRIDES entries must look like rule=SEVERITY, got %q", entry)
+			continue
+		}
+		switch strings.ToUpper(strings.TrimSpace(severity)) {
+		case "LOW", "MEDIUM", "HIGH", "CRITICAL":
+		default:
+			add("DETECTION_SEVERITY_OVERRIDES severity must be LOW, MEDIUM, HIGH or CRITICAL, got %q", entry)
+		}
+	}
+	for _, cidr := range d.AllowedIPRanges {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			add("ALLOWED_IP_RANGES contains invalid CIDR %q", cidr)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func validPort(port int) bool {
+	return port > 0 && port <= 65535
+}
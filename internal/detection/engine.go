@@ -2,23 +2,100 @@ package detection
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"sync"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/scaleway/audit-sentinel/internal/config"
+	"github.com/scaleway/audit-sentinel/internal/metrics"
 	"github.com/scaleway/audit-sentinel/internal/models"
+	"github.com/scaleway/audit-sentinel/internal/tracing"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// tracer records detection spans
+var tracer = tracing.Tracer("detection")
+
+// defaultRuleTimeout bounds a single rule evaluation when none is configured
+const defaultRuleTimeout = 5 * time.Second
+
 // Engine handles anomaly detection
 type Engine struct {
-	config  *config.Config
-	rules   []Rule
-	storage DetectionStorage
+	config     *config.Config
+	rules      []Rule
+	storage    DetectionStorage
+	publishers []AlertPublisher
+	remediator Remediator
 }
 
+// Remediator responds automatically to alerts after they have been stored
+type Remediator interface {
+	Remediate(ctx context.Context, alert *models.Alert) error
+}
+
+// AlertPublisher receives alerts after they have been stored
+type AlertPublisher interface {
+	Publish(alert *models.Alert)
+}
 
 type DetectionStorage interface {
 	StoreAlert(ctx context.Context, alert *models.Alert) error
+	// GetRecentEventsByActor returns the actor's events in the project since
+	// the given time, oldest first. Like every project-scoped lookup below,
+	// an empty projectID matches all projects.
+	GetRecentEventsByActor(ctx context.Context, projectID, actor string, since time.Time) ([]*models.Event, error)
+	// CountRecentEventsByActor counts the actor's events of eventType in the
+	// project with a timestamp between since and until, both inclusive
+	CountRecentEventsByActor(ctx context.Context, projectID, actor, eventType string, since, until time.Time) (int, error)
+	// GetActorCountries counts the actor's events in the project per
+	// country, excluding the given event, ordered by country
+	GetActorCountries(ctx context.Context, projectID, actor string, excludeEventID uuid.UUID) ([]CountryCount, error)
+	// HasRecentAlert reports whether an alert of the given type was raised
+	// for the user in the project since the given time
+	HasRecentAlert(ctx context.Context, projectID, userID, alertType string, since time.Time) (bool, error)
+	// HasRecentKeyAlert reports whether an alert of the given type was
+	// raised for the API key keyID in the project since the given time
+	HasRecentKeyAlert(ctx context.Context, projectID, keyID, alertType string, since time.Time) (bool, error)
+	// FindOpenAlert returns the newest alert of alertType for userID in the
+	// project that is still OPEN or INVESTIGATING, or nil if there is none
+	FindOpenAlert(ctx context.Context, projectID, alertType, userID string) (*models.Alert, error)
+	// MergeEvidence folds a duplicate into an open alert's evidence
+	MergeEvidence(ctx context.Context, alertID uuid.UUID, delta models.EvidenceDelta) error
 	GetUserProfile(ctx context.Context, userID string) (*models.UserProfile, error)
 	UpdateUserProfile(ctx context.Context, profile *models.UserProfile) error
+	// IsMuted reports whether an active mute suppresses alerts of alertType
+	// for userID on resource
+	IsMuted(ctx context.Context, alertType, userID, resource string) (bool, error)
+	// ActiveMaintenanceWindow returns the maintenance window covering alerts
+	// of alertType at the given time, or nil if there is none
+	ActiveMaintenanceWindow(ctx context.Context, alertType string, at time.Time) (*models.MaintenanceWindow, error)
+	// GetActorContext summarizes the actor's activity in the project between
+	// since and until, for attaching to alerts as triage context
+	GetActorContext(ctx context.Context, projectID, actor string, since, until time.Time) (*ActorContext, error)
+	// GetKeyCreationEvent returns the apiKey.create event in the project for
+	// keyID at or after since, or nil if there is none
+	GetKeyCreationEvent(ctx context.Context, projectID, keyID string, since time.Time) (*models.Event, error)
+}
+
+// ActorContext is a snapshot of an actor's recent activity
+type ActorContext struct {
+	RecentEventCount int
+	DistinctIPs      []string
+	LastSuccessAt    *time.Time
+}
+
+// contextWindow is how far back the activity snapshot attached to alerts looks
+const contextWindow = time.Hour
+
+// CountryCount is the number of events an actor has from one country
+type CountryCount struct {
+	Country string
+	Count   int
 }
 
 // Rule defines a detection rule interface
@@ -28,50 +105,404 @@ type Rule interface {
 	IsActive() bool
 }
 
+// NewEngine creates a detection engine running the rules enabled in
+// cfg.Detection.EnabledRules, or every registered rule if none are listed
+func NewEngine(cfg *config.Config, storage DetectionStorage) (*Engine, error) {
+	rules, err := buildRules(cfg, storage, cfg.Detection.EnabledRules)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkRuleNames("severity overrides", cfg.Detection.SeverityOverrideMap()); err != nil {
+		return nil, err
+	}
 
-func NewEngine(cfg *config.Config, storage DetectionStorage) *Engine {
-	engine := &Engine{
+	return &Engine{
 		config:  cfg,
 		storage: storage,
-		rules:   []Rule{},
+		rules:   rules,
+	}, nil
+}
+
+// AddPublisher registers a publisher notified of every stored alert
+func (e *Engine) AddPublisher(publisher AlertPublisher) {
+	e.publishers = append(e.publishers, publisher)
+}
+
+// RuleStatus describes a rule loaded in the engine
+type RuleStatus struct {
+	Name   string `json:"name"`
+	Active bool   `json:"active"`
+	// Severity is the configured override of the rule's severity, if any
+	Severity string `json:"severity_override,omitempty"`
+}
+
+// Rules returns the rules the engine runs, in evaluation order, with their
+// current status
+func (e *Engine) Rules() []RuleStatus {
+	overrides := e.config.CurrentDetection().SeverityOverrideMap()
+	statuses := make([]RuleStatus, 0, len(e.rules))
+	for _, rule := range e.rules {
+		statuses = append(statuses, RuleStatus{
+			Name:     rule.Name(),
+			Active:   rule.IsActive(),
+			Severity: overrides[rule.Name()],
+		})
 	}
+	return statuses
+}
+
+// SetRemediator sets the automatic remediator run on every stored alert
+func (e *Engine) SetRemediator(remediator Remediator) {
+	e.remediator = remediator
+}
+
+// DetectionError reports the rules whose detection failed on an event, either
+// because the rule errored or because one of its alerts could not be stored.
+// Running just those rules again with RetryEvent completes the detection.
+type DetectionError struct {
+	Rules []string
+	Err   error
+}
+
+func (e *DetectionError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DetectionError) Unwrap() error {
+	return e.Err
+}
 
-	// Register default rules
-	engine.registerDefaultRules()
+// ProcessEvent evaluates all active rules against event and stores the
+// resulting alerts. A failing rule is logged and skipped, so the alerts of
+// the other rules are still stored; the failed rules are reported in a
+// *DetectionError.
+func (e *Engine) ProcessEvent(ctx context.Context, event *models.Event) error {
+	ctx, span := tracer.Start(ctx, "detection.process_event", trace.WithAttributes(tracing.AttrEventID.String(event.EventID)))
+	defer span.End()
 
-	return engine
+	return e.process(ctx, event, nil)
 }
 
+// RetryEvent is ProcessEvent restricted to the named rules, for completing
+// a detection that failed for them. Rules no longer active are skipped.
+func (e *Engine) RetryEvent(ctx context.Context, event *models.Event, rules []string) error {
+	ctx, span := tracer.Start(ctx, "detection.retry_event", trace.WithAttributes(tracing.AttrEventID.String(event.EventID)))
+	defer span.End()
 
-func (e *Engine) registerDefaultRules() {
-	e.rules = []Rule{
-		NewFailedLoginRule(e.config, e.storage),
-		NewForbiddenResourceRule(e.config, e.storage),
-		NewAPIKeyCreationRule(e.config, e.storage),
+	only := make(map[string]bool, len(rules))
+	for _, name := range rules {
+		only[name] = true
+	}
+	return e.process(ctx, event, only)
+}
 
+// process evaluates the active rules in only, or all of them if only is nil,
+// and stores their alerts
+func (e *Engine) process(ctx context.Context, event *models.Event, only map[string]bool) error {
+	result := e.evaluateRules(ctx, event, only)
+	e.assignProject(event, result.alerts)
+	_, storeFailures := e.storeAlerts(ctx, event, result.alerts)
+
+	failed := result.failed
+	failures := []error{result.err}
+	for alert, err := range storeFailures {
+		rule := result.rules[alert]
+		if !slices.Contains(failed, rule) {
+			failed = append(failed, rule)
+		}
+		failures = append(failures, fmt.Errorf("rule %s: %w", rule, err))
+	}
+	if len(failed) == 0 {
+		return nil
 	}
+	sort.Strings(failed)
+	return &DetectionError{Rules: failed, Err: errors.Join(failures...)}
 }
 
+// Evaluate runs all active rules against event and returns the alerts they
+// raise, without storing them or checking mutes and maintenance windows.
+// The error joins the failures of individual rules; the alerts of the rules
+// that succeeded are returned alongside it.
+func (e *Engine) Evaluate(ctx context.Context, event *models.Event) ([]*models.Alert, error) {
+	result := e.evaluateRules(ctx, event, nil)
+	e.assignProject(event, result.alerts)
+	return result.alerts, result.err
+}
 
-func (e *Engine) ProcessEvent(ctx context.Context, event *models.Event) error {
-	for _, rule := range e.rules {
-		if !rule.IsActive() {
+// assignProject sets the project of alerts that have none to the project of
+// the event that raised them
+func (e *Engine) assignProject(event *models.Event, alerts []*models.Alert) {
+	for _, alert := range alerts {
+		if alert.ProjectID == "" {
+			alert.ProjectID = event.ProjectID
+		}
+	}
+}
+
+// StoreAlerts stores the alerts raised by event that no mute or maintenance
+// window suppresses, then scores, remediates and publishes them. It returns
+// the alerts stored; failures are logged and skip only the affected alert.
+func (e *Engine) StoreAlerts(ctx context.Context, event *models.Event, alerts []*models.Alert) []*models.Alert {
+	stored, _ := e.storeAlerts(ctx, event, alerts)
+	return stored
+}
+
+// storeAlerts is StoreAlerts, also returning why each alert that could not be
+// stored failed
+func (e *Engine) storeAlerts(ctx context.Context, event *models.Event, alerts []*models.Alert) ([]*models.Alert, map[*models.Alert]error) {
+	stored := []*models.Alert{}
+	failed := map[*models.Alert]error{}
+	for _, alert := range alerts {
+		if e.muted(ctx, alert) {
+			metrics.SuppressedAlerts.WithLabelValues(alert.AlertType, "mute").Inc()
+			continue
+		}
+		window := e.maintenanceWindow(ctx, alert, event.Timestamp)
+		if window != nil && window.Mode == models.MaintenanceSuppress {
+			metrics.SuppressedAlerts.WithLabelValues(alert.AlertType, "maintenance").Inc()
 			continue
 		}
-		alerts, err := rule.Evaluate(ctx, event)
+		if window != nil {
+			if alert.Evidence == nil {
+				alert.Evidence = map[string]any{}
+			}
+			alert.Evidence[models.EvidenceDuringMaintenance] = true
+			alert.Evidence["maintenance_window_id"] = window.ID.String()
+		}
+		merged, err := e.mergeDuplicate(ctx, alert, event)
 		if err != nil {
-			// Log error but continue with other rules
+			log.Printf("Failed to merge %s alert for event %s: %v", alert.AlertType, event.EventID, err)
+			failed[alert] = err
 			continue
 		}
+		if merged {
+			metrics.SuppressedAlerts.WithLabelValues(alert.AlertType, "duplicate").Inc()
+			continue
+		}
+		e.attachContext(ctx, alert, event)
+		if err := e.storeAlert(ctx, alert); err != nil {
+			// Log error but continue
+			log.Printf("Failed to store %s alert for event %s: %v", alert.AlertType, event.EventID, err)
+			failed[alert] = err
+			continue
+		}
+		stored = append(stored, alert)
+		if err := e.updateRiskScore(ctx, alert); err != nil {
+			log.Printf("Failed to update risk score of %s for alert %s: %v", alert.UserID, alert.ID, err)
+		}
+		// Activity during maintenance is expected, so it is never remediated
+		if e.remediator != nil && !alert.DuringMaintenance() {
+			if err := e.remediator.Remediate(ctx, alert); err != nil {
+				log.Printf("Auto-remediation failed for alert %s: %v", alert.ID, err)
+			}
+		}
+		for _, publisher := range e.publishers {
+			publisher.Publish(alert)
+		}
+	}
+
+	return stored, failed
+}
+
+// storeAlert stores alert under its own span
+func (e *Engine) storeAlert(ctx context.Context, alert *models.Alert) (err error) {
+	ctx, span := tracer.Start(ctx, "detection.store_alert", trace.WithAttributes(tracing.AttrAlertType.String(alert.AlertType)))
+	defer func() { tracing.End(span, err) }()
+
+	return e.storage.StoreAlert(ctx, alert)
+}
 
-		// Store alerts
-		for _, alert := range alerts {
-			if err := e.storage.StoreAlert(ctx, alert); err != nil {
-				// Log error but continue
-				continue
+// mergeDuplicate folds alert into the open alert of the same type for the
+// same user, if there is one, and reports whether it did. Alerts without a
+// user have nothing to match on and are never merged. If the lookup fails
+// the alert is stored as new rather than lost.
+func (e *Engine) mergeDuplicate(ctx context.Context, alert *models.Alert, event *models.Event) (bool, error) {
+	if alert.UserID == "" || !e.config.CurrentDetection().MergeDuplicateAlerts {
+		return false, nil
+	}
+	existing, err := e.storage.FindOpenAlert(ctx, alert.ProjectID, alert.AlertType, alert.UserID)
+	if err != nil {
+		log.Printf("Failed to look up open %s alert for %s: %v", alert.AlertType, alert.UserID, err)
+		return false, nil
+	}
+	if existing == nil {
+		return false, nil
+	}
+
+	ips, _ := alert.Evidence.GetStrings(models.EvidenceIPAddresses)
+	if event.IP != "" {
+		ips = append(slices.Clip(ips), event.IP)
+	}
+	refs := alert.EventRefs
+	if !slices.Contains(refs, event.ID) {
+		refs = append(slices.Clip(refs), event.ID)
+	}
+	delta := models.EvidenceDelta{
+		Occurrences: 1,
+		LastSeen:    event.Timestamp,
+		IPAddresses: ips,
+		EventRefs:   refs,
+	}
+	if err := e.storage.MergeEvidence(ctx, existing.ID, delta); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// attachContext freezes a snapshot of the user's activity leading up to the
+// event into the alert's evidence. A failed lookup leaves the alert as is.
+func (e *Engine) attachContext(ctx context.Context, alert *models.Alert, event *models.Event) {
+	if alert.UserID == "" {
+		return
+	}
+	snapshot, err := e.storage.GetActorContext(ctx, alert.ProjectID, alert.UserID, event.Timestamp.Add(-contextWindow), event.Timestamp)
+	if err != nil {
+		log.Printf("Failed to load activity context for %s alert: %v", alert.AlertType, err)
+		return
+	}
+
+	var lastSuccessAt any
+	if snapshot.LastSuccessAt != nil {
+		lastSuccessAt = snapshot.LastSuccessAt.UTC().Format(time.RFC3339)
+	}
+	if alert.Evidence == nil {
+		alert.Evidence = map[string]any{}
+	}
+	alert.Evidence[models.EvidenceContext] = map[string]any{
+		"window":             contextWindow.String(),
+		"recent_event_count": snapshot.RecentEventCount,
+		"distinct_ips":       snapshot.DistinctIPs,
+		"last_success_at":    lastSuccessAt,
+	}
+}
+
+// muted reports whether an active mute covers alert. If mutes cannot be
+// checked the alert is kept, so an outage never hides detections.
+func (e *Engine) muted(ctx context.Context, alert *models.Alert) bool {
+	// Alerts without a resource only match mutes that name none
+	resource, _ := alert.Evidence.GetString("resource")
+	muted, err := e.storage.IsMuted(ctx, alert.AlertType, alert.UserID, resource)
+	if err != nil {
+		log.Printf("Failed to check mutes for %s alert: %v", alert.AlertType, err)
+		return false
+	}
+	return muted
+}
+
+// maintenanceWindow returns the maintenance window covering alert at the
+// time of its event. Like mutes, windows fail open.
+func (e *Engine) maintenanceWindow(ctx context.Context, alert *models.Alert, at time.Time) *models.MaintenanceWindow {
+	window, err := e.storage.ActiveMaintenanceWindow(ctx, alert.AlertType, at)
+	if err != nil {
+		log.Printf("Failed to check maintenance windows for %s alert: %v", alert.AlertType, err)
+		return nil
+	}
+	return window
+}
+
+// evaluation is the outcome of running the rules against one event
+type evaluation struct {
+	// alerts are sorted by alert type
+	alerts []*models.Alert
+	// rules maps each alert to the name of the rule that raised it
+	rules map[*models.Alert]string
+	// failed names the rules that errored; err joins their errors
+	failed []string
+	err    error
+}
+
+// evaluateRules runs the active rules in only, or all of them if only is nil,
+// concurrently, bounded by the configured concurrency. A failing rule is
+// skipped without affecting the others.
+func (e *Engine) evaluateRules(ctx context.Context, event *models.Event, only map[string]bool) evaluation {
+	active := make([]Rule, 0, len(e.rules))
+	for _, rule := range e.rules {
+		if rule.IsActive() && (only == nil || only[rule.Name()]) {
+			active = append(active, rule)
+		}
+	}
+
+	detection := e.config.CurrentDetection()
+	workers := detection.RuleConcurrency
+	if workers <= 0 {
+		workers = 1
+	}
+
+	// Each rule writes only to its own slot, so results needs no locking
+	results := make([][]*models.Alert, len(active))
+	failures := make([]error, len(active))
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for idx, rule := range active {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(idx int, rule Rule) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			alerts, err := e.evaluateRule(ctx, rule, event)
+			if err != nil {
+				// Already logged; continue with other rules
+				failures[idx] = fmt.Errorf("rule %s: %w", rule.Name(), err)
+				return
 			}
+			results[idx] = alerts
+		}(idx, rule)
+	}
+	wg.Wait()
+
+	overrides := detection.SeverityOverrideMap()
+	result := evaluation{rules: map[*models.Alert]string{}}
+	for idx, ruleAlerts := range results {
+		name := active[idx].Name()
+		if failures[idx] != nil {
+			result.failed = append(result.failed, name)
+		}
+		severity, override := overrides[name]
+		for _, alert := range ruleAlerts {
+			if override {
+				alert.Severity = models.Severity(severity)
+			}
+			result.rules[alert] = name
+		}
+		result.alerts = append(result.alerts, ruleAlerts...)
+	}
+	sort.SliceStable(result.alerts, func(a, b int) bool {
+		return result.alerts[a].AlertType < result.alerts[b].AlertType
+	})
+	result.err = errors.Join(failures...)
+
+	return result
+}
+
+// evaluateRule runs a single rule under the configured timeout so a slow rule
+// cannot stall the pipeline. A timed-out rule yields no alerts.
+func (e *Engine) evaluateRule(ctx context.Context, rule Rule, event *models.Event) (alerts []*models.Alert, err error) {
+	ctx, span := tracer.Start(ctx, "detection.evaluate_rule", trace.WithAttributes(
+		tracing.AttrRuleName.String(rule.Name()),
+		tracing.AttrEventID.String(event.EventID),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	timeout := e.config.CurrentDetection().RuleTimeout
+	if timeout <= 0 {
+		timeout = defaultRuleTimeout
+	}
+
+	ruleCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	alerts, err = rule.Evaluate(ruleCtx, event)
+	if err != nil {
+		if errors.Is(ruleCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+			metrics.RuleTimeouts.WithLabelValues(rule.Name()).Inc()
+			log.Printf("Rule %s timed out after %s on event %s", rule.Name(), timeout, event.EventID)
+			return nil, nil
 		}
+		metrics.RuleErrors.WithLabelValues(rule.Name()).Inc()
+		log.Printf("Rule %s failed on event %s: %v", rule.Name(), event.EventID, err)
+		return nil, err
 	}
 
-	return nil
+	return alerts, nil
 }
@@ -0,0 +1,178 @@
+package detection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/scaleway/audit-sentinel/internal/config"
+	"github.com/scaleway/audit-sentinel/internal/models"
+	"github.com/scaleway/audit-sentinel/pkg/scaleway"
+)
+
+// stubRule raises one alert of its own type after delay, or fails with err
+type stubRule struct {
+	name  string
+	delay time.Duration
+	err   error
+}
+
+func (r *stubRule) Name() string   { return r.name }
+func (r *stubRule) IsActive() bool { return true }
+
+func (r *stubRule) Evaluate(ctx context.Context, event *models.Event) ([]*models.Alert, error) {
+	if r.delay > 0 {
+		select {
+		case <-time.After(r.delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return []*models.Alert{{ID: uuid.New(), AlertType: r.name, UserID: event.Actor, Status: models.AlertStatusOpen}}, nil
+}
+
+// newTestEngine returns an engine running rules against storage, bypassing
+// the rule registry
+func newTestEngine(cfg *config.Config, storage DetectionStorage, rules ...Rule) *Engine {
+	return &Engine{config: cfg, storage: storage, rules: rules}
+}
+
+func TestEvaluateIsolatesFailingRules(t *testing.T) {
+	ruleErr := errors.New("lookup failed")
+	engine := newTestEngine(testConfig(), newFakeStorage(),
+		&stubRule{name: "zeta"},
+		&stubRule{name: "broken", err: ruleErr},
+		&stubRule{name: "alpha", delay: time.Millisecond},
+		&stubRule{name: "mid"},
+	)
+
+	alerts, err := engine.Evaluate(context.Background(), newEvent("auth.failed", "alice", "203.0.113.7", time.Now()))
+	if !errors.Is(err, ruleErr) {
+		t.Errorf("error = %v, want the failing rule's error", err)
+	}
+
+	var types []string
+	for _, alert := range alerts {
+		types = append(types, alert.AlertType)
+		if alert.ProjectID != "project-a" {
+			t.Errorf("alert %s has project %q, want the event's project", alert.AlertType, alert.ProjectID)
+		}
+	}
+	if fmt.Sprint(types) != "[alpha mid zeta]" {
+		t.Errorf("alert types = %v, want the other rules' alerts sorted by type", types)
+	}
+}
+
+func TestProcessEventReportsFailedRules(t *testing.T) {
+	storage := newFakeStorage()
+	engine := newTestEngine(testConfig(), storage,
+		&stubRule{name: "ok"},
+		&stubRule{name: "broken", err: errors.New("lookup failed")},
+	)
+
+	err := engine.ProcessEvent(context.Background(), newEvent("auth.failed", "alice", "203.0.113.7", time.Now()))
+	var detectionErr *DetectionError
+	if !errors.As(err, &detectionErr) {
+		t.Fatalf("error = %v, want a *DetectionError", err)
+	}
+	if fmt.Sprint(detectionErr.Rules) != "[broken]" {
+		t.Errorf("failed rules = %v, want [broken]", detectionErr.Rules)
+	}
+	if alerts := storage.storedAlerts(); len(alerts) != 1 || alerts[0].AlertType != "ok" {
+		t.Errorf("stored %d alerts, want the one from the working rule", len(alerts))
+	}
+}
+
+// BenchmarkEvaluateRules compares evaluating rules one at a time with the
+// default worker pool. Each rule waits as long as a storage round trip.
+func BenchmarkEvaluateRules(b *testing.B) {
+	rules := make([]Rule, 12)
+	for idx := range rules {
+		rules[idx] = &stubRule{name: fmt.Sprintf("rule_%02d", idx), delay: 200 * time.Microsecond}
+	}
+	event := newEvent("auth.failed", "alice", "203.0.113.7", time.Now())
+
+	for _, workers := range []int{1, 4, len(rules)} {
+		name := fmt.Sprintf("workers=%d", workers)
+		if workers == 1 {
+			name = "sequential"
+		}
+		b.Run(name, func(b *testing.B) {
+			cfg := testConfig(func(detection *config.DetectionConfig) {
+				detection.RuleConcurrency = workers
+			})
+			engine := newTestEngine(cfg, newFakeStorage(), rules...)
+			ctx := context.Background()
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				if _, err := engine.Evaluate(ctx, event); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// mockStreamEvent converts a mock Scaleway event into a stored event in
+// project-a, as ingestion would
+func mockStreamEvent(event *scaleway.AuditEvent) *models.Event {
+	return &models.Event{
+		ID:        uuid.New(),
+		EventID:   event.ID,
+		EventType: event.Type,
+		Actor:     event.Actor,
+		Resource:  event.Resource,
+		IP:        event.IP,
+		ProjectID: "project-a",
+		Timestamp: event.Timestamp,
+		Raw:       event.Raw,
+		CreatedAt: event.Timestamp,
+	}
+}
+
+func TestEvaluateMockStream(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	generator := &scaleway.MockGenerator{Now: func() time.Time { return now }, Seed: 42}
+	cfg := testConfig(func(detection *config.DetectionConfig) {
+		detection.EnabledRules = []string{"failed_login_spike", "api_key_creation", "forbidden_sensitive_resource"}
+	})
+	storage := newFakeStorage()
+	engine, err := NewEngine(cfg, storage)
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+
+	raised := map[string][]string{}
+	for _, mock := range generator.Events(nil, "audit") {
+		event := mockStreamEvent(mock)
+		storage.addEvents(event)
+		alerts, err := engine.Evaluate(context.Background(), event)
+		if err != nil {
+			t.Fatalf("Evaluate %s: %v", event.EventID, err)
+		}
+		for _, alert := range alerts {
+			raised[alert.AlertType] = append(raised[alert.AlertType], event.EventID)
+			if alert.UserID != event.Actor {
+				t.Errorf("%s alert for %s, want the event's actor %s", alert.AlertType, alert.UserID, event.Actor)
+			}
+		}
+	}
+
+	want := map[string][]string{
+		"failed_login_spike":           {"evt_mock_42_007"},
+		"api_key_creation":             {"evt_mock_42_004"},
+		"forbidden_sensitive_resource": {"evt_mock_42_005"},
+	}
+	if fmt.Sprint(raised) != fmt.Sprint(want) {
+		t.Errorf("alerts raised by event = %v, want %v", raised, want)
+	}
+	if alerts := storage.storedAlerts(); len(alerts) != 0 {
+		t.Errorf("Evaluate stored %d alerts, want none", len(alerts))
+	}
+}
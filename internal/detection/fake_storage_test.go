@@ -0,0 +1,298 @@
+package detection
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/scaleway/audit-sentinel/internal/config"
+	"github.com/scaleway/audit-sentinel/internal/models"
+	"github.com/scaleway/audit-sentinel/internal/storage"
+)
+
+// fakeStorage is an in-memory DetectionStorage for rule and engine tests.
+// Lookups mirror the SQL of DetectionStorageImpl closely enough for the
+// rules: an empty projectID matches every project.
+type fakeStorage struct {
+	mu       sync.Mutex
+	events   []*models.Event
+	alerts   []*models.Alert
+	profiles map[string]*models.UserProfile
+	// muted holds "alertType/userID" pairs suppressed by a mute
+	muted map[string]bool
+	// err, when set, is returned by every lookup
+	err error
+	// storeErr, when set, is returned by StoreAlert
+	storeErr error
+}
+
+func newFakeStorage(events ...*models.Event) *fakeStorage {
+	return &fakeStorage{
+		events:   events,
+		profiles: map[string]*models.UserProfile{},
+		muted:    map[string]bool{},
+	}
+}
+
+// addEvents appends events to the fake event table
+func (s *fakeStorage) addEvents(events ...*models.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, events...)
+}
+
+// storedAlerts returns the alerts stored so far
+func (s *fakeStorage) storedAlerts() []*models.Alert {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*models.Alert(nil), s.alerts...)
+}
+
+func inProject(projectID, eventProject string) bool {
+	return projectID == "" || projectID == eventProject
+}
+
+func (s *fakeStorage) StoreAlert(ctx context.Context, alert *models.Alert) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.storeErr != nil {
+		return s.storeErr
+	}
+	s.alerts = append(s.alerts, alert)
+	return nil
+}
+
+func (s *fakeStorage) GetRecentEventsByActor(ctx context.Context, projectID, actor string, since time.Time) ([]*models.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, s.err
+	}
+	var events []*models.Event
+	for _, event := range s.events {
+		if event.Actor == actor && inProject(projectID, event.ProjectID) && !event.Timestamp.Before(since) {
+			events = append(events, event)
+		}
+	}
+	sort.SliceStable(events, func(a, b int) bool { return events[a].Timestamp.Before(events[b].Timestamp) })
+	return events, nil
+}
+
+func (s *fakeStorage) CountRecentEventsByActor(ctx context.Context, projectID, actor, eventType string, since, until time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return 0, s.err
+	}
+	count := 0
+	for _, event := range s.events {
+		if event.Actor == actor && event.EventType == eventType && inProject(projectID, event.ProjectID) &&
+			!event.Timestamp.Before(since) && !event.Timestamp.After(until) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *fakeStorage) GetActorCountries(ctx context.Context, projectID, actor string, excludeEventID uuid.UUID) ([]CountryCount, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, s.err
+	}
+	counts := map[string]int{}
+	for _, event := range s.events {
+		if event.Actor != actor || event.ID == excludeEventID || !inProject(projectID, event.ProjectID) {
+			continue
+		}
+		country := event.Region
+		if country == "" {
+			country, _ = event.Raw["country"].(string)
+		}
+		if country != "" {
+			counts[country]++
+		}
+	}
+	countries := []CountryCount{}
+	for country, count := range counts {
+		countries = append(countries, CountryCount{Country: country, Count: count})
+	}
+	sort.Slice(countries, func(a, b int) bool { return countries[a].Country < countries[b].Country })
+	return countries, nil
+}
+
+func (s *fakeStorage) HasRecentAlert(ctx context.Context, projectID, userID, alertType string, since time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return false, s.err
+	}
+	for _, alert := range s.alerts {
+		if alert.UserID == userID && alert.AlertType == alertType && inProject(projectID, alert.ProjectID) && alert.CreatedAt.After(since) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *fakeStorage) HasRecentKeyAlert(ctx context.Context, projectID, keyID, alertType string, since time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return false, s.err
+	}
+	for _, alert := range s.alerts {
+		if alert.Evidence["key_id"] == keyID && alert.AlertType == alertType && inProject(projectID, alert.ProjectID) && alert.CreatedAt.After(since) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *fakeStorage) FindOpenAlert(ctx context.Context, projectID, alertType, userID string) (*models.Alert, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, s.err
+	}
+	for idx := len(s.alerts) - 1; idx >= 0; idx-- {
+		alert := s.alerts[idx]
+		open := alert.Status == models.AlertStatusOpen || alert.Status == models.AlertStatusInvestigating
+		if open && alert.AlertType == alertType && alert.UserID == userID && inProject(projectID, alert.ProjectID) {
+			return alert, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *fakeStorage) MergeEvidence(ctx context.Context, alertID uuid.UUID, delta models.EvidenceDelta) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, alert := range s.alerts {
+		if alert.ID != alertID {
+			continue
+		}
+		occurrences, err := alert.Evidence.GetInt(models.EvidenceOccurrences)
+		if err != nil {
+			occurrences = 1
+		}
+		alert.Evidence[models.EvidenceOccurrences] = occurrences + delta.Occurrences
+		alert.Evidence[models.EvidenceLastSeen] = delta.LastSeen.UTC().Format(time.RFC3339)
+		alert.EventRefs = append(alert.EventRefs, delta.EventRefs...)
+		return nil
+	}
+	return storage.ErrAlertNotFound
+}
+
+func (s *fakeStorage) GetUserProfile(ctx context.Context, userID string) (*models.UserProfile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if profile, ok := s.profiles[userID]; ok {
+		copied := *profile
+		return &copied, nil
+	}
+	return nil, storage.ErrUserProfileNotFound
+}
+
+func (s *fakeStorage) UpdateUserProfile(ctx context.Context, profile *models.UserProfile) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	copied := *profile
+	s.profiles[profile.ScalewayUserID] = &copied
+	return nil
+}
+
+func (s *fakeStorage) IsMuted(ctx context.Context, alertType, userID, resource string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.muted[alertType+"/"+userID], nil
+}
+
+func (s *fakeStorage) ActiveMaintenanceWindow(ctx context.Context, alertType string, at time.Time) (*models.MaintenanceWindow, error) {
+	return nil, nil
+}
+
+func (s *fakeStorage) GetActorContext(ctx context.Context, projectID, actor string, since, until time.Time) (*ActorContext, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshot := &ActorContext{DistinctIPs: []string{}}
+	seen := map[string]bool{}
+	for _, event := range s.events {
+		if event.Actor != actor || !inProject(projectID, event.ProjectID) ||
+			event.Timestamp.Before(since) || event.Timestamp.After(until) {
+			continue
+		}
+		snapshot.RecentEventCount++
+		if event.IP != "" && !seen[event.IP] {
+			seen[event.IP] = true
+			snapshot.DistinctIPs = append(snapshot.DistinctIPs, event.IP)
+		}
+	}
+	return snapshot, nil
+}
+
+func (s *fakeStorage) GetKeyCreationEvent(ctx context.Context, projectID, keyID string, since time.Time) (*models.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, s.err
+	}
+	for _, event := range s.events {
+		if event.EventType == "apiKey.create" && inProject(projectID, event.ProjectID) &&
+			event.Raw["key_id"] == keyID && !event.Timestamp.Before(since) {
+			return event, nil
+		}
+	}
+	return nil, nil
+}
+
+// testConfig returns a config with the default detection settings, adjusted
+// by the given options
+func testConfig(options ...func(*config.DetectionConfig)) *config.Config {
+	detection := config.DetectionConfig{
+		FailedLoginWindowMin:       15,
+		FailedLoginThreshold:       5,
+		ImpossibleTravelSpeed:      1000,
+		RuleTimeout:                5 * time.Second,
+		RuleConcurrency:            4,
+		NewCountryMinHistory:       5,
+		APIKeyBurstWindowMin:       10,
+		APIKeyBurstThreshold:       3,
+		APIKeyServiceAccountAction: config.ServiceAccountDowngrade,
+		NewKeyRapidUseWindowMin:    10,
+		APIKeyUsageKeys:            []string{"access_key", "api_key_id"},
+		SensitiveResources:         []string{"iam", "secrets", "kms", "secret"},
+		MergeDuplicateAlerts:       true,
+	}
+	for _, option := range options {
+		option(&detection)
+	}
+	return &config.Config{Detection: detection}
+}
+
+// newEvent builds a stored event of eventType by actor at ts in project-a
+func newEvent(eventType, actor, ip string, ts time.Time) *models.Event {
+	return &models.Event{
+		ID:        uuid.New(),
+		EventID:   "evt-" + strings.ReplaceAll(uuid.NewString(), "-", "")[:12],
+		EventType: eventType,
+		Actor:     actor,
+		IP:        ip,
+		ProjectID: "project-a",
+		Timestamp: ts,
+		Raw:       map[string]any{},
+		CreatedAt: ts,
+	}
+}
+
+// failures builds n auth.failed events by actor, a minute apart, ending at end
+func failures(actor string, n int, end time.Time) []*models.Event {
+	events := make([]*models.Event, 0, n)
+	for idx := n - 1; idx >= 0; idx-- {
+		events = append(events, newEvent("auth.failed", actor, "203.0.113.7", end.Add(-time.Duration(idx)*time.Minute)))
+	}
+	return events
+}
@@ -0,0 +1,111 @@
+package detection
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/scaleway/audit-sentinel/internal/config"
+)
+
+// RuleFactory constructs a rule from configuration and storage
+type RuleFactory func(cfg *config.Config, storage DetectionStorage) Rule
+
+// ruleRegistry maps rule names to their constructors. Names must match the
+// value returned by the rule's Name method.
+var ruleRegistry = map[string]RuleFactory{
+	"failed_login_spike": func(cfg *config.Config, s DetectionStorage) Rule {
+		return NewFailedLoginRule(cfg, s)
+	},
+	"forbidden_sensitive_resource": func(cfg *config.Config, s DetectionStorage) Rule {
+		return NewForbiddenResourceRule(cfg, s)
+	},
+	"api_key_creation": func(cfg *config.Config, s DetectionStorage) Rule {
+		return NewAPIKeyCreationRule(cfg, s)
+	},
+	"new_key_rapid_use": func(cfg *config.Config, s DetectionStorage) Rule {
+		return NewNewKeyRapidUseRule(cfg, s)
+	},
+	"successful_login_after_brute_force": func(cfg *config.Config, s DetectionStorage) Rule {
+		return NewLoginAfterBruteForceRule(cfg, s)
+	},
+	"new_country_login": func(cfg *config.Config, s DetectionStorage) Rule {
+		return NewNewCountryRule(cfg, s)
+	},
+	"unusual_ip_region": func(cfg *config.Config, s DetectionStorage) Rule {
+		return NewUnusualIPRule(cfg, s)
+	},
+	"impossible_travel": func(cfg *config.Config, s DetectionStorage) Rule {
+		return NewImpossibleTravelRule(cfg, s)
+	},
+	"known_malicious_ip": func(cfg *config.Config, s DetectionStorage) Rule {
+		return NewKnownMaliciousIPRule(cfg, s)
+	},
+	"privilege_escalation": func(cfg *config.Config, s DetectionStorage) Rule {
+		return NewPrivilegeEscalationRule(cfg, s)
+	},
+	"iam_policy_change": func(cfg *config.Config, s DetectionStorage) Rule {
+		return NewIAMPolicyChangeRule(cfg, s)
+	},
+	"high_privilege_unknown_ip": func(cfg *config.Config, s DetectionStorage) Rule {
+		return NewHighPrivilegeUnknownIPRule(cfg, s)
+	},
+}
+
+// RuleNames returns the names of all registered rules in sorted order
+func RuleNames() []string {
+	names := make([]string, 0, len(ruleRegistry))
+	for name := range ruleRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// buildRules instantiates the named rules, or every registered rule when
+// names is empty. Unknown names are reported together in a single error.
+func buildRules(cfg *config.Config, storage DetectionStorage, names []string) ([]Rule, error) {
+	if len(names) == 0 {
+		names = RuleNames()
+	}
+
+	var unknown []string
+	rules := make([]Rule, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		factory, ok := ruleRegistry[name]
+		if !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		rules = append(rules, factory(cfg, storage))
+	}
+
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown detection rules: %s (valid rules: %s)",
+			strings.Join(unknown, ", "), strings.Join(RuleNames(), ", "))
+	}
+
+	return rules, nil
+}
+
+// checkRuleNames reports the keys of byRule that name no registered rule
+func checkRuleNames(setting string, byRule map[string]string) error {
+	var unknown []string
+	for name := range byRule {
+		if _, ok := ruleRegistry[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	return fmt.Errorf("unknown detection rules in %s: %s (valid rules: %s)",
+		setting, strings.Join(unknown, ", "), strings.Join(RuleNames(), ", "))
+}
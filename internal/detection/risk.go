@@ -0,0 +1,65 @@
+package detection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/scaleway/audit-sentinel/internal/models"
+	"github.com/scaleway/audit-sentinel/internal/storage"
+)
+
+// riskHalfLife is the time for an untouched risk score to halve
+const riskHalfLife = 7 * 24 * time.Hour
+
+// maxRiskScore is the ceiling enforced by the user_profiles table
+const maxRiskScore = 100
+
+// severityRiskWeights is how much each alert adds to its user's risk score
+var severityRiskWeights = map[models.Severity]float64{
+	models.SeverityLow:      5,
+	models.SeverityMedium:   10,
+	models.SeverityHigh:     25,
+	models.SeverityCritical: 40,
+}
+
+// DecayedRiskScore returns the profile's risk score decayed from its last
+// update to now, halving every riskHalfLife
+func DecayedRiskScore(profile *models.UserProfile, now time.Time) int {
+	return int(math.Round(decay(float64(profile.RiskScore), profile.UpdatedAt, now)))
+}
+
+func decay(score float64, since, now time.Time) float64 {
+	elapsed := now.Sub(since)
+	if elapsed <= 0 {
+		return score
+	}
+	return score * math.Pow(0.5, float64(elapsed)/float64(riskHalfLife))
+}
+
+// updateRiskScore decays the alerted user's risk score and adds the weight of
+// the alert's severity, creating the profile on the user's first alert
+func (e *Engine) updateRiskScore(ctx context.Context, alert *models.Alert) error {
+	if alert.UserID == "" {
+		return nil
+	}
+
+	now := time.Now()
+	profile, err := e.storage.GetUserProfile(ctx, alert.UserID)
+	if errors.Is(err, storage.ErrUserProfileNotFound) {
+		profile = &models.UserProfile{ScalewayUserID: alert.UserID, UpdatedAt: now}
+	} else if err != nil {
+		return fmt.Errorf("failed to load user profile: %w", err)
+	}
+
+	score := decay(float64(profile.RiskScore), profile.UpdatedAt, now) + severityRiskWeights[alert.Severity]
+	profile.RiskScore = int(math.Min(math.Round(score), maxRiskScore))
+	profile.UpdatedAt = now
+
+	if err := e.storage.UpdateUserProfile(ctx, profile); err != nil {
+		return fmt.Errorf("failed to update user profile: %w", err)
+	}
+	return nil
+}
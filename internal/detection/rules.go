@@ -2,8 +2,8 @@ package detection
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
+	"path"
 	"strings"
 	"time"
 
@@ -16,16 +16,21 @@ import (
 type FailedLoginRule struct {
 	config  *config.Config
 	storage DetectionStorage
-	db      *sql.DB
+	// counter tracks recent failures per actor in memory so the database is
+	// only queried once the threshold may have been crossed; nil when disabled
+	counter *slidingWindowCounter
 }
 
 func NewFailedLoginRule(cfg *config.Config, storage DetectionStorage) *FailedLoginRule {
-	impl := storage.(*DetectionStorageImpl)
-	return &FailedLoginRule{
+	rule := &FailedLoginRule{
 		config:  cfg,
 		storage: storage,
-		db:      impl.db,
 	}
+	if cfg.Detection.FailedLoginInMemory {
+		window := time.Duration(cfg.Detection.FailedLoginWindowMin) * time.Minute
+		rule.counter = newSlidingWindowCounter(window)
+	}
+	return rule
 }
 
 func (r *FailedLoginRule) Name() string {
@@ -48,45 +53,49 @@ func (r *FailedLoginRule) Evaluate(ctx context.Context, event *models.Event) ([]
 	}
 
 	// Count failed login attempts in the time window
-	windowMinutes := r.config.Detection.FailedLoginWindowMin
-	threshold := r.config.Detection.FailedLoginThreshold
-
-	query := fmt.Sprintf(`
-		SELECT COUNT(*) as failed_count, 
-		       array_agg(id ORDER BY timestamp)::text as event_ids,
-		       array_agg(ip ORDER BY timestamp)::text as ip_addresses
-		FROM events
-		WHERE actor = $1
-		  AND event_type = 'auth.failed'
-		  AND timestamp > NOW() - INTERVAL '%d minutes'
-	`, windowMinutes)
-
-	var failedCount int
-	var eventIDsStr sql.NullString
-	var ipAddressesStr sql.NullString
-
-	err := r.db.QueryRowContext(ctx, query, event.Actor).Scan(&failedCount, &eventIDsStr, &ipAddressesStr)
-	if err != nil && err != sql.ErrNoRows {
-		return nil, fmt.Errorf("failed to query failed logins: %w", err)
+	detection := r.config.CurrentDetection()
+	windowMinutes := detection.FailedLoginWindowMin
+	threshold := detection.FailedLoginThreshold
+
+	// Skip the query while the in-memory count is below threshold, unless
+	// the counter cannot have seen the whole window ending at this event,
+	// e.g. a backfilled or retried event older than the counter itself
+	window := time.Duration(windowMinutes) * time.Minute
+	if r.counter != nil {
+		// Follow hot reloads of the window length
+		r.counter.SetWindow(window)
+		// Count per project so tenants sharing an actor name stay apart
+		recent, complete := r.counter.Add(event.ProjectID+"/"+event.Actor, event.Timestamp)
+		if recent < threshold && complete {
+			return nil, nil
+		}
 	}
 
-	eventIDsStrVal := ""
-	if eventIDsStr.Valid {
-		eventIDsStrVal = eventIDsStr.String
-	}
-	ipAddressesStrVal := ""
-	if ipAddressesStr.Valid {
-		ipAddressesStrVal = ipAddressesStr.String
+	// Count in the database over the same event-time window as the counter;
+	// rows are only loaded for evidence once the threshold is crossed
+	until := event.Timestamp
+	since := until.Add(-window)
+	failedCount, err := r.storage.CountRecentEventsByActor(ctx, event.ProjectID, event.Actor, "auth.failed", since, until)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count failed logins: %w", err)
 	}
 
 	if failedCount >= threshold {
-
-		eventIDs := parseUUIDArray(eventIDsStrVal)
-		if len(eventIDs) == 0 {
-			eventIDs = []uuid.UUID{event.ID}
+		history, err := r.storage.GetRecentEventsByActor(ctx, event.ProjectID, event.Actor, since)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query failed logins: %w", err)
+		}
+		var eventIDs []uuid.UUID
+		var ipAddresses []string
+		for _, recentEvent := range history {
+			if recentEvent.EventType != "auth.failed" || recentEvent.Timestamp.After(until) {
+				continue
+			}
+			eventIDs = append(eventIDs, recentEvent.ID)
+			if recentEvent.IP != "" {
+				ipAddresses = append(ipAddresses, recentEvent.IP)
+			}
 		}
-
-		ipAddresses := parseStringArray(ipAddressesStrVal)
 		if len(ipAddresses) == 0 {
 			ipAddresses = []string{event.IP}
 		}
@@ -116,18 +125,189 @@ func (r *FailedLoginRule) Evaluate(ctx context.Context, event *models.Event) ([]
 	return nil, nil
 }
 
+// successEventTypes are the event types treated as a successful login
+var successEventTypes = map[string]bool{
+	"auth.success":  true,
+	"login.success": true,
+}
+
+// LoginAfterBruteForceRule detects a successful login preceded by a burst of
+// failures for the same actor, a strong account takeover signal
+type LoginAfterBruteForceRule struct {
+	config  *config.Config
+	storage DetectionStorage
+}
+
+func NewLoginAfterBruteForceRule(cfg *config.Config, storage DetectionStorage) *LoginAfterBruteForceRule {
+	return &LoginAfterBruteForceRule{
+		config:  cfg,
+		storage: storage,
+	}
+}
+
+func (r *LoginAfterBruteForceRule) Name() string {
+	return "successful_login_after_brute_force"
+}
+
+func (r *LoginAfterBruteForceRule) IsActive() bool {
+	return true
+}
+
+func (r *LoginAfterBruteForceRule) Evaluate(ctx context.Context, event *models.Event) ([]*models.Alert, error) {
+	if !successEventTypes[event.EventType] || event.Actor == "" {
+		return nil, nil
+	}
+
+	detection := r.config.CurrentDetection()
+	windowMinutes := detection.FailedLoginWindowMin
+	threshold := detection.FailedLoginThreshold
+
+	// Count failures in the window leading up to this success
+	windowStart := event.Timestamp.Add(-time.Duration(windowMinutes) * time.Minute)
+	history, err := r.storage.GetRecentEventsByActor(ctx, event.ProjectID, event.Actor, windowStart)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query failed logins: %w", err)
+	}
+
+	var eventIDs []uuid.UUID
+	failedIPs := []string{}
+	for _, recentEvent := range history {
+		if recentEvent.EventType != "auth.failed" || !recentEvent.Timestamp.Before(event.Timestamp) {
+			continue
+		}
+		eventIDs = append(eventIDs, recentEvent.ID)
+		if recentEvent.IP != "" {
+			failedIPs = append(failedIPs, recentEvent.IP)
+		}
+	}
+	failedCount := len(eventIDs)
+
+	if failedCount < threshold {
+		return nil, nil
+	}
+
+	eventIDs = append(eventIDs, event.ID)
+
+	alert := &models.Alert{
+		ID:          uuid.New(),
+		EventRefs:   eventIDs,
+		AlertType:   r.Name(),
+		Severity:    models.SeverityCritical,
+		UserID:      event.Actor,
+		Description: fmt.Sprintf("Successful login for user %s after %d failed attempts within %d minutes", event.Actor, failedCount, windowMinutes),
+		Status:      models.AlertStatusOpen,
+		Evidence: map[string]any{
+			"failed_attempts":     failedCount,
+			"window_minutes":      windowMinutes,
+			"threshold":           threshold,
+			"failed_ip_addresses": failedIPs,
+			"success_ip_address":  event.IP,
+			"success_event_id":    event.EventID,
+			"success_timestamp":   event.Timestamp.Format(time.RFC3339),
+		},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	return []*models.Alert{alert}, nil
+}
+
+// eventCountry returns the country an event originated from, if known
+func eventCountry(event *models.Event) string {
+	if event.Region != "" {
+		return event.Region
+	}
+	for _, key := range []string{"country", "country_code"} {
+		if country, ok := event.Raw[key].(string); ok && country != "" {
+			return country
+		}
+	}
+	return ""
+}
+
+// NewCountryRule detects a login from a country never seen for the user
+type NewCountryRule struct {
+	config  *config.Config
+	storage DetectionStorage
+}
+
+func NewNewCountryRule(cfg *config.Config, storage DetectionStorage) *NewCountryRule {
+	return &NewCountryRule{
+		config:  cfg,
+		storage: storage,
+	}
+}
+
+func (r *NewCountryRule) Name() string {
+	return "new_country_login"
+}
+
+func (r *NewCountryRule) IsActive() bool {
+	return true
+}
+
+func (r *NewCountryRule) Evaluate(ctx context.Context, event *models.Event) ([]*models.Alert, error) {
+	if !successEventTypes[event.EventType] || event.Actor == "" {
+		return nil, nil
+	}
+
+	country := eventCountry(event)
+	if country == "" {
+		return nil, nil
+	}
+
+	countries, err := r.storage.GetActorCountries(ctx, event.ProjectID, event.Actor, event.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	knownCountries := make([]string, 0, len(countries))
+	history := 0
+	seen := false
+	for _, known := range countries {
+		knownCountries = append(knownCountries, known.Country)
+		history += known.Count
+		if strings.EqualFold(known.Country, country) {
+			seen = true
+		}
+	}
+
+	// Brand-new users have no baseline yet, so everything would look new
+	if seen || history < r.config.CurrentDetection().NewCountryMinHistory {
+		return nil, nil
+	}
+
+	alert := &models.Alert{
+		ID:          uuid.New(),
+		EventRefs:   []uuid.UUID{event.ID},
+		AlertType:   r.Name(),
+		Severity:    models.SeverityMedium,
+		UserID:      event.Actor,
+		Description: fmt.Sprintf("User %s logged in from new country %s", event.Actor, country),
+		Status:      models.AlertStatusOpen,
+		Evidence: map[string]any{
+			"new_country":     country,
+			"known_countries": knownCountries,
+			"history_events":  history,
+			"ip_address":      event.IP,
+			"timestamp":       event.Timestamp.Format(time.RFC3339),
+		},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	return []*models.Alert{alert}, nil
+}
+
 type ForbiddenResourceRule struct {
 	config  *config.Config
 	storage DetectionStorage
-	db      *sql.DB
 }
 
 func NewForbiddenResourceRule(cfg *config.Config, storage DetectionStorage) *ForbiddenResourceRule {
-	impl := storage.(*DetectionStorageImpl)
 	return &ForbiddenResourceRule{
 		config:  cfg,
 		storage: storage,
-		db:      impl.db,
 	}
 }
 
@@ -145,13 +325,11 @@ func (r *ForbiddenResourceRule) Evaluate(ctx context.Context, event *models.Even
 		return nil, nil
 	}
 
-	// Define sensitive resource types
-	sensitiveResources := []string{"iam", "secrets", "kms", "secret"}
 	isSensitive := false
 	resourceType := ""
 
 	// Check if resource is sensitive
-	for _, sensitive := range sensitiveResources {
+	for _, sensitive := range r.config.CurrentDetection().SensitiveResources {
 		if event.Resource != "" && contains(event.Resource, sensitive) {
 			isSensitive = true
 			resourceType = sensitive
@@ -179,11 +357,12 @@ func (r *ForbiddenResourceRule) Evaluate(ctx context.Context, event *models.Even
 		Description: fmt.Sprintf("Forbidden access attempt to sensitive resource (%s) by %s", resourceType, event.Actor),
 		Status:      models.AlertStatusOpen,
 		Evidence: map[string]any{
-			"resource_type": resourceType,
-			"resource":      event.Resource,
-			"ip_address":    event.IP,
-			"timestamp":     event.Timestamp.Format(time.RFC3339),
-			"raw_event":     event.Raw,
+			"resource_type":   resourceType,
+			"matched_pattern": resourceType,
+			"resource":        event.Resource,
+			"ip_address":      event.IP,
+			"timestamp":       event.Timestamp.Format(time.RFC3339),
+			"raw_event":       event.Raw,
 		},
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
@@ -196,15 +375,12 @@ func (r *ForbiddenResourceRule) Evaluate(ctx context.Context, event *models.Even
 type APIKeyCreationRule struct {
 	config  *config.Config
 	storage DetectionStorage
-	db      *sql.DB
 }
 
 func NewAPIKeyCreationRule(cfg *config.Config, storage DetectionStorage) *APIKeyCreationRule {
-	impl := storage.(*DetectionStorageImpl)
 	return &APIKeyCreationRule{
 		config:  cfg,
 		storage: storage,
-		db:      impl.db,
 	}
 }
 
@@ -232,21 +408,48 @@ func (r *APIKeyCreationRule) Evaluate(ctx context.Context, event *models.Event)
 		keyName = rawKeyName
 	}
 
-	// Create HIGH severity alert for API key creation
+	// A burst of creations by one actor escalates to a single CRITICAL alert
+	// that replaces the per-key HIGH alerts for the rest of the burst
+	detection := r.config.CurrentDetection()
+	burstCount, burstFired, err := r.burstState(ctx, event, detection)
+	if err != nil {
+		return nil, err
+	}
+	if burstFired {
+		return nil, nil
+	}
+	if burstCount > detection.APIKeyBurstThreshold {
+		return []*models.Alert{r.burstAlert(event, burstCount, detection)}, nil
+	}
+
+	// Service accounts create keys routinely, so theirs are downgraded or
+	// suppressed; everyone else's stay HIGH. Bursts are escalated above
+	// regardless.
+	allowlisted := matchesAny(detection.APIKeyServiceAccounts, event.Actor)
+	severity := models.SeverityHigh
+	if allowlisted {
+		if detection.APIKeyServiceAccountAction == config.ServiceAccountSuppress {
+			return nil, nil
+		}
+		severity = models.SeverityLow
+	}
+
+	// Create alert for API key creation
 	alert := &models.Alert{
 		ID:          uuid.New(),
 		EventRefs:   []uuid.UUID{event.ID},
 		AlertType:   r.Name(),
-		Severity:    models.SeverityHigh,
+		Severity:    severity,
 		UserID:      event.Actor,
 		Description: fmt.Sprintf("New API key created by %s", event.Actor),
 		Status:      models.AlertStatusOpen,
 		Evidence: map[string]any{
-			"key_id":     keyID,
-			"key_name":   keyName,
-			"ip_address": event.IP,
-			"timestamp":  event.Timestamp.Format(time.RFC3339),
-			"raw_event":  event.Raw,
+			"key_id":      keyID,
+			"key_name":    keyName,
+			"ip_address":  event.IP,
+			"timestamp":   event.Timestamp.Format(time.RFC3339),
+			"allowlisted": allowlisted,
+			"raw_event":   event.Raw,
 		},
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
@@ -255,6 +458,56 @@ func (r *APIKeyCreationRule) Evaluate(ctx context.Context, event *models.Event)
 	return []*models.Alert{alert}, nil
 }
 
+// apiKeyBurstAlertType is the alert type raised for a burst of key creations
+const apiKeyBurstAlertType = "api_key_creation_burst"
+
+// burstState counts the actor's key creations in the burst window and reports
+// whether a burst alert was already raised for them within it
+func (r *APIKeyCreationRule) burstState(ctx context.Context, event *models.Event, detection config.DetectionConfig) (int, bool, error) {
+	if event.Actor == "" {
+		return 0, false, nil
+	}
+
+	// Anchor the window on the event so replayed or delayed creations are
+	// judged against the keys created around them
+	windowEnd := event.Timestamp
+	windowStart := windowEnd.Add(-time.Duration(detection.APIKeyBurstWindowMin) * time.Minute)
+
+	count, err := r.storage.CountRecentEventsByActor(ctx, event.ProjectID, event.Actor, "apiKey.create", windowStart, windowEnd)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to count API key creations: %w", err)
+	}
+
+	fired, err := r.storage.HasRecentAlert(ctx, event.ProjectID, event.Actor, apiKeyBurstAlertType, windowStart)
+	if err != nil {
+		return 0, false, err
+	}
+
+	return count, fired, nil
+}
+
+func (r *APIKeyCreationRule) burstAlert(event *models.Event, count int, detection config.DetectionConfig) *models.Alert {
+	windowMinutes := detection.APIKeyBurstWindowMin
+	return &models.Alert{
+		ID:          uuid.New(),
+		EventRefs:   []uuid.UUID{event.ID},
+		AlertType:   apiKeyBurstAlertType,
+		Severity:    models.SeverityCritical,
+		UserID:      event.Actor,
+		Description: fmt.Sprintf("%d API keys created by %s within %d minutes", count, event.Actor, windowMinutes),
+		Status:      models.AlertStatusOpen,
+		Evidence: map[string]any{
+			"key_count":      count,
+			"window_minutes": windowMinutes,
+			"threshold":      detection.APIKeyBurstThreshold,
+			"ip_address":     event.IP,
+			"timestamp":      event.Timestamp.Format(time.RFC3339),
+		},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+}
+
 // UnusualIPRule detects access from unusual IP/region
 type UnusualIPRule struct {
 	config  *config.Config
@@ -274,6 +527,10 @@ func (r *UnusualIPRule) IsActive() bool {
 }
 
 func (r *UnusualIPRule) Evaluate(ctx context.Context, event *models.Event) ([]*models.Alert, error) {
+	// Internal traffic is never unusual for a region
+	if event.IPScope == models.IPScopeInternal {
+		return nil, nil
+	}
 
 	return nil, nil
 }
@@ -346,92 +603,246 @@ func (r *HighPrivilegeUnknownIPRule) Evaluate(ctx context.Context, event *models
 	return nil, nil
 }
 
-// Helper functions
+// KnownMaliciousIPRule raises an alert for events from an IP on the threat
+// intel blocklist, as tagged during ingestion
+type KnownMaliciousIPRule struct {
+	config  *config.Config
+	storage DetectionStorage
+}
+
+func NewKnownMaliciousIPRule(cfg *config.Config, storage DetectionStorage) *KnownMaliciousIPRule {
+	return &KnownMaliciousIPRule{config: cfg, storage: storage}
+}
+
+func (r *KnownMaliciousIPRule) Name() string {
+	return "known_malicious_ip"
+}
 
-// parseUUIDArray parses PostgreSQL array string into UUID slice
-func parseUUIDArray(str string) []uuid.UUID {
-	if str == "" || str == "{}" {
-		return []uuid.UUID{}
+func (r *KnownMaliciousIPRule) IsActive() bool {
+	return true
+}
+
+func (r *KnownMaliciousIPRule) Evaluate(ctx context.Context, event *models.Event) ([]*models.Alert, error) {
+	match, ok := event.Raw[models.RawThreatIntel].(map[string]any)
+	if !ok {
+		return nil, nil
+	}
+
+	alert := &models.Alert{
+		ID:          uuid.New(),
+		EventRefs:   []uuid.UUID{event.ID},
+		AlertType:   r.Name(),
+		Severity:    models.SeverityHigh,
+		UserID:      event.Actor,
+		Description: fmt.Sprintf("Activity by %s from known malicious IP %s", event.Actor, event.IP),
+		Status:      models.AlertStatusOpen,
+		Evidence: map[string]any{
+			"ip_address":    event.IP,
+			"event_type":    event.EventType,
+			"resource":      event.Resource,
+			"source":        match["source"],
+			"matched_entry": match["matched_entry"],
+		},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
 	}
 
-	// Remove curly braces
-	str = str[1 : len(str)-1]
-	if str == "" {
-		return []uuid.UUID{}
+	return []*models.Alert{alert}, nil
+}
+
+// PrivilegeEscalationRule detects a user being granted an admin-level policy,
+// group or role, such as an attacker adding themselves to a privileged group
+type PrivilegeEscalationRule struct {
+	config  *config.Config
+	storage DetectionStorage
+}
+
+func NewPrivilegeEscalationRule(cfg *config.Config, storage DetectionStorage) *PrivilegeEscalationRule {
+	return &PrivilegeEscalationRule{config: cfg, storage: storage}
+}
+
+func (r *PrivilegeEscalationRule) Name() string {
+	return "privilege_escalation"
+}
+
+func (r *PrivilegeEscalationRule) IsActive() bool {
+	return true
+}
+
+func (r *PrivilegeEscalationRule) Evaluate(ctx context.Context, event *models.Event) ([]*models.Alert, error) {
+	detection := r.config.CurrentDetection()
+	if !containsFold(detection.PrivilegeGrantEventTypes, event.EventType) {
+		return nil, nil
 	}
 
-	parts := splitArrayString(str)
-	uuids := make([]uuid.UUID, 0, len(parts))
-	for _, part := range parts {
-		part = trimQuotes(part)
-		if part == "" {
-			continue
-		}
-		u, err := uuid.Parse(part)
-		if err != nil {
-			continue
+	// Fall back to the event's resource when the payload does not name it
+	policy := rawString(event.Raw, detection.PrivilegeGrantPolicyKeys...)
+	if policy == "" {
+		policy = event.Resource
+	}
+	privileged := false
+	for _, pattern := range detection.PrivilegedPolicies {
+		if contains(policy, pattern) {
+			privileged = true
+			break
 		}
-		uuids = append(uuids, u)
 	}
-	return uuids
+	if !privileged {
+		return nil, nil
+	}
+
+	grantee := rawString(event.Raw, detection.PrivilegeGrantGranteeKeys...)
+	target := grantee
+	if target == "" {
+		target = "an unknown principal"
+	}
+
+	alert := &models.Alert{
+		ID:          uuid.New(),
+		EventRefs:   []uuid.UUID{event.ID},
+		AlertType:   r.Name(),
+		Severity:    models.SeverityHigh,
+		UserID:      event.Actor,
+		Description: fmt.Sprintf("%s granted privileged %s to %s", event.Actor, policy, target),
+		Status:      models.AlertStatusOpen,
+		Evidence: map[string]any{
+			"grantor":        event.Actor,
+			"grantee":        grantee,
+			"granted_policy": policy,
+			"event_type":     event.EventType,
+			"resource":       event.Resource,
+			"ip_address":     event.IP,
+		},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	return []*models.Alert{alert}, nil
+}
+
+// NewKeyRapidUseRule detects an API key used from a different IP than the
+// one it was created from shortly after its creation, a strong sign the key
+// leaked or was created by an attacker
+type NewKeyRapidUseRule struct {
+	config  *config.Config
+	storage DetectionStorage
+}
+
+func NewNewKeyRapidUseRule(cfg *config.Config, storage DetectionStorage) *NewKeyRapidUseRule {
+	return &NewKeyRapidUseRule{config: cfg, storage: storage}
+}
+
+func (r *NewKeyRapidUseRule) Name() string {
+	return "new_key_rapid_use"
+}
+
+func (r *NewKeyRapidUseRule) IsActive() bool {
+	return true
 }
 
-// parseStringArray parses PostgreSQL array string into string slice
-func parseStringArray(str string) []string {
-	if str == "" || str == "{}" {
-		return []string{}
+func (r *NewKeyRapidUseRule) Evaluate(ctx context.Context, event *models.Event) ([]*models.Alert, error) {
+	if event.EventType == "apiKey.create" || event.IP == "" {
+		return nil, nil
+	}
+	detection := r.config.CurrentDetection()
+	keyID := rawString(event.Raw, detection.APIKeyUsageKeys...)
+	if keyID == "" {
+		return nil, nil
 	}
 
-	// Remove curly braces
-	str = str[1 : len(str)-1]
-	if str == "" {
-		return []string{}
+	window := time.Duration(detection.NewKeyRapidUseWindowMin) * time.Minute
+	created, err := r.storage.GetKeyCreationEvent(ctx, event.ProjectID, keyID, event.Timestamp.Add(-window))
+	if err != nil {
+		return nil, err
+	}
+	// Keys created before the window, or outside what we ingested, are skipped
+	if created == nil || created.IP == "" || created.IP == event.IP || created.Timestamp.After(event.Timestamp) {
+		return nil, nil
 	}
 
-	parts := splitArrayString(str)
-	result := make([]string, 0, len(parts))
-	for _, part := range parts {
-		part = trimQuotes(part)
-		if part != "" {
-			result = append(result, part)
-		}
+	// One alert per key: later uses within the window add nothing new, but
+	// another key created by the same actor still alerts
+	fired, err := r.storage.HasRecentKeyAlert(ctx, event.ProjectID, keyID, r.Name(), created.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+	if fired {
+		return nil, nil
 	}
-	return result
+
+	delay := event.Timestamp.Sub(created.Timestamp)
+	alert := &models.Alert{
+		ID:          uuid.New(),
+		EventRefs:   []uuid.UUID{created.ID, event.ID},
+		AlertType:   r.Name(),
+		Severity:    models.SeverityCritical,
+		UserID:      created.Actor,
+		Description: fmt.Sprintf("API key %s created by %s from %s was used from %s %s later", keyID, created.Actor, created.IP, event.IP, delay.Round(time.Second)),
+		Status:      models.AlertStatusOpen,
+		Evidence: map[string]any{
+			"key_id":           keyID,
+			"created_by":       created.Actor,
+			"create_ip":        created.IP,
+			"create_timestamp": created.Timestamp.Format(time.RFC3339),
+			"use_ip":           event.IP,
+			"use_timestamp":    event.Timestamp.Format(time.RFC3339),
+			"use_event_type":   event.EventType,
+			"used_by":          event.Actor,
+			"delay_seconds":    int(delay.Seconds()),
+		},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	return []*models.Alert{alert}, nil
 }
 
-// splitArrayString splits PostgreSQL array string handling quoted values
-func splitArrayString(s string) []string {
-	var result []string
-	var current strings.Builder
-	inQuotes := false
+// Helper functions
 
-	for i := 0; i < len(s); i++ {
-		char := s[i]
-		if char == '"' {
-			inQuotes = !inQuotes
-			current.WriteByte(char)
-		} else if char == ',' && !inQuotes {
-			if current.Len() > 0 {
-				result = append(result, current.String())
-				current.Reset()
-			}
-		} else {
-			current.WriteByte(char)
+// containsFold reports whether values holds value, ignoring case
+func containsFold(values []string, value string) bool {
+	for _, candidate := range values {
+		if strings.EqualFold(candidate, value) {
+			return true
 		}
 	}
-	if current.Len() > 0 {
-		result = append(result, current.String())
-	}
-	return result
+	return false
 }
 
-// trimQuotes removes surrounding quotes from string
-func trimQuotes(s string) string {
-	s = strings.TrimSpace(s)
-	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
-		return s[1 : len(s)-1]
+// matchesAny reports whether value equals one of patterns or matches it as a
+// glob (e.g. "svc-*@example.com"), ignoring case
+func matchesAny(patterns []string, value string) bool {
+	value = strings.ToLower(value)
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if pattern == value {
+			return true
+		}
+		if matched, err := path.Match(pattern, value); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// rawString returns the first non-empty string found in raw under keys.
+// Dotted keys such as "member.user_id" address nested objects.
+func rawString(raw map[string]any, keys ...string) string {
+	for _, key := range keys {
+		var value any = raw
+		for _, part := range strings.Split(key, ".") {
+			object, ok := value.(map[string]any)
+			if !ok {
+				value = nil
+				break
+			}
+			value = object[part]
+		}
+		if str, ok := value.(string); ok && str != "" {
+			return str
+		}
 	}
-	return s
+	return ""
 }
 
 // contains checks if string contains substring (case-insensitive)
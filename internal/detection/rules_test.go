@@ -0,0 +1,498 @@
+package detection
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/scaleway/audit-sentinel/internal/config"
+	"github.com/scaleway/audit-sentinel/internal/models"
+)
+
+func TestFailedLoginRule(t *testing.T) {
+	now := time.Now().Add(-time.Minute)
+	tests := []struct {
+		name     string
+		history  []*models.Event
+		event    *models.Event
+		wantHit  bool
+		wantSeen int
+	}{
+		{
+			name:    "below threshold",
+			history: failures("alice", 3, now.Add(-time.Minute)),
+			event:   newEvent("auth.failed", "alice", "203.0.113.7", now),
+		},
+		{
+			name:     "at threshold",
+			history:  failures("alice", 4, now.Add(-time.Minute)),
+			event:    newEvent("auth.failed", "alice", "203.0.113.7", now),
+			wantHit:  true,
+			wantSeen: 5,
+		},
+		{
+			name:    "failures outside the window",
+			history: failures("alice", 4, now.Add(-20*time.Minute)),
+			event:   newEvent("auth.failed", "alice", "203.0.113.7", now),
+		},
+		{
+			name:    "other actors and successes not counted",
+			history: append(failures("bob", 4, now.Add(-time.Minute)), newEvent("auth.success", "alice", "203.0.113.7", now.Add(-time.Minute))),
+			event:   newEvent("auth.failed", "alice", "203.0.113.7", now),
+		},
+		{
+			name:    "not a failed login",
+			history: failures("alice", 6, now.Add(-time.Minute)),
+			event:   newEvent("auth.success", "alice", "203.0.113.7", now),
+		},
+		{
+			name:    "no actor",
+			history: failures("", 6, now.Add(-time.Minute)),
+			event:   newEvent("auth.failed", "", "203.0.113.7", now),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := newFakeStorage(tt.history...)
+			storage.addEvents(tt.event)
+			rule := NewFailedLoginRule(testConfig(), storage)
+
+			alerts, err := rule.Evaluate(context.Background(), tt.event)
+			if err != nil {
+				t.Fatalf("Evaluate: %v", err)
+			}
+			if !tt.wantHit {
+				if len(alerts) != 0 {
+					t.Fatalf("got %d alerts, want none", len(alerts))
+				}
+				return
+			}
+			if len(alerts) != 1 {
+				t.Fatalf("got %d alerts, want 1", len(alerts))
+			}
+			alert := alerts[0]
+			if alert.AlertType != "failed_login_spike" || alert.Severity != models.SeverityHigh || alert.UserID != "alice" {
+				t.Errorf("alert = %s/%s for %s, want failed_login_spike/HIGH for alice", alert.AlertType, alert.Severity, alert.UserID)
+			}
+			if got := alert.Evidence["failed_attempts"]; got != tt.wantSeen {
+				t.Errorf("failed_attempts = %v, want %d", got, tt.wantSeen)
+			}
+			if len(alert.EventRefs) != tt.wantSeen {
+				t.Errorf("got %d event refs, want %d", len(alert.EventRefs), tt.wantSeen)
+			}
+			if ips, _ := alert.Evidence["ip_addresses"].([]string); len(ips) != tt.wantSeen {
+				t.Errorf("ip_addresses = %v, want one per failure", alert.Evidence["ip_addresses"])
+			}
+		})
+	}
+}
+
+func TestFailedLoginRuleStorageError(t *testing.T) {
+	storage := newFakeStorage()
+	storage.err = errors.New("connection refused")
+	rule := NewFailedLoginRule(testConfig(), storage)
+
+	_, err := rule.Evaluate(context.Background(), newEvent("auth.failed", "alice", "203.0.113.7", time.Now()))
+	if !errors.Is(err, storage.err) {
+		t.Errorf("error = %v, want the storage error wrapped", err)
+	}
+}
+
+func TestForbiddenResourceRule(t *testing.T) {
+	tests := []struct {
+		name        string
+		eventType   string
+		resource    string
+		rawResource string
+		wantPattern string
+	}{
+		{name: "sensitive resource", eventType: "forbidden", resource: "secrets/db-password", wantPattern: "secrets"},
+		{name: "sensitive raw resource", eventType: "forbidden", rawResource: "kms/key-1", wantPattern: "kms"},
+		{name: "not sensitive", eventType: "forbidden", resource: "instance/web-1"},
+		{name: "not forbidden", eventType: "iam.policy.update", resource: "iam/policy-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := newEvent(tt.eventType, "alice", "203.0.113.7", time.Now())
+			event.Resource = tt.resource
+			if tt.rawResource != "" {
+				event.Raw["resource"] = tt.rawResource
+			}
+			rule := NewForbiddenResourceRule(testConfig(), newFakeStorage())
+
+			alerts, err := rule.Evaluate(context.Background(), event)
+			if err != nil {
+				t.Fatalf("Evaluate: %v", err)
+			}
+			if tt.wantPattern == "" {
+				if len(alerts) != 0 {
+					t.Fatalf("got %d alerts, want none", len(alerts))
+				}
+				return
+			}
+			if len(alerts) != 1 {
+				t.Fatalf("got %d alerts, want 1", len(alerts))
+			}
+			if alerts[0].Severity != models.SeverityCritical {
+				t.Errorf("severity = %s, want CRITICAL", alerts[0].Severity)
+			}
+			if got := alerts[0].Evidence["matched_pattern"]; got != tt.wantPattern {
+				t.Errorf("matched_pattern = %v, want %s", got, tt.wantPattern)
+			}
+		})
+	}
+}
+
+func TestForbiddenResourceRuleCustomPatterns(t *testing.T) {
+	t.Setenv("SENSITIVE_RESOURCES", "billing, object-storage-bucket/prod")
+	cfg := testConfig()
+	if _, err := cfg.ReloadDetection(); err != nil {
+		t.Fatalf("ReloadDetection: %v", err)
+	}
+	rule := NewForbiddenResourceRule(cfg, newFakeStorage())
+
+	tests := []struct {
+		resource    string
+		wantPattern string
+	}{
+		{resource: "Billing/invoices", wantPattern: "billing"},
+		{resource: "object-storage-bucket/prod-backups", wantPattern: "object-storage-bucket/prod"},
+		{resource: "object-storage-bucket/staging"},
+		// The custom list replaces the defaults
+		{resource: "secrets/db-password"},
+	}
+	for _, tt := range tests {
+		event := newEvent("forbidden", "alice", "203.0.113.7", time.Now())
+		event.Resource = tt.resource
+
+		alerts, err := rule.Evaluate(context.Background(), event)
+		if err != nil {
+			t.Fatalf("Evaluate %s: %v", tt.resource, err)
+		}
+		if tt.wantPattern == "" {
+			if len(alerts) != 0 {
+				t.Errorf("%s raised %d alerts, want none", tt.resource, len(alerts))
+			}
+			continue
+		}
+		if len(alerts) != 1 {
+			t.Fatalf("%s raised %d alerts, want 1", tt.resource, len(alerts))
+		}
+		if got := alerts[0].Evidence["matched_pattern"]; got != tt.wantPattern {
+			t.Errorf("%s matched_pattern = %v, want %s", tt.resource, got, tt.wantPattern)
+		}
+	}
+}
+
+func TestAPIKeyCreationRule(t *testing.T) {
+	now := time.Now().Add(-time.Minute)
+	keyCreations := func(actor string, n int) []*models.Event {
+		events := make([]*models.Event, 0, n)
+		for idx := 0; idx < n; idx++ {
+			events = append(events, newEvent("apiKey.create", actor, "203.0.113.7", now.Add(-time.Duration(n-idx)*time.Minute)))
+		}
+		return events
+	}
+
+	tests := []struct {
+		name         string
+		history      []*models.Event
+		actor        string
+		options      []func(*config.DetectionConfig)
+		burstFired   bool
+		wantType     string
+		wantSeverity models.Severity
+	}{
+		{
+			name:         "single key",
+			actor:        "alice",
+			wantType:     "api_key_creation",
+			wantSeverity: models.SeverityHigh,
+		},
+		{
+			name:         "at burst threshold stays per key",
+			history:      keyCreations("alice", 2),
+			actor:        "alice",
+			wantType:     "api_key_creation",
+			wantSeverity: models.SeverityHigh,
+		},
+		{
+			name:         "burst",
+			history:      keyCreations("alice", 3),
+			actor:        "alice",
+			wantType:     apiKeyBurstAlertType,
+			wantSeverity: models.SeverityCritical,
+		},
+		{
+			name:       "burst already raised",
+			history:    keyCreations("alice", 3),
+			actor:      "alice",
+			burstFired: true,
+		},
+		{
+			name:         "service account downgraded",
+			actor:        "ci-bot",
+			options:      []func(*config.DetectionConfig){serviceAccounts(config.ServiceAccountDowngrade)},
+			wantType:     "api_key_creation",
+			wantSeverity: models.SeverityLow,
+		},
+		{
+			name:    "service account suppressed",
+			actor:   "ci-bot",
+			options: []func(*config.DetectionConfig){serviceAccounts(config.ServiceAccountSuppress)},
+		},
+		{
+			name:         "service account burst still escalated",
+			history:      keyCreations("ci-bot", 3),
+			actor:        "ci-bot",
+			options:      []func(*config.DetectionConfig){serviceAccounts(config.ServiceAccountSuppress)},
+			wantType:     apiKeyBurstAlertType,
+			wantSeverity: models.SeverityCritical,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := newEvent("apiKey.create", tt.actor, "203.0.113.7", now)
+			event.Raw["key_id"] = "SCW1234567890"
+			storage := newFakeStorage(tt.history...)
+			storage.addEvents(event)
+			if tt.burstFired {
+				storage.alerts = append(storage.alerts, &models.Alert{
+					AlertType: apiKeyBurstAlertType,
+					UserID:    tt.actor,
+					ProjectID: event.ProjectID,
+					Status:    models.AlertStatusOpen,
+					CreatedAt: now.Add(-time.Minute),
+				})
+			}
+			rule := NewAPIKeyCreationRule(testConfig(tt.options...), storage)
+
+			alerts, err := rule.Evaluate(context.Background(), event)
+			if err != nil {
+				t.Fatalf("Evaluate: %v", err)
+			}
+			if tt.wantType == "" {
+				if len(alerts) != 0 {
+					t.Fatalf("got %d alerts, want none", len(alerts))
+				}
+				return
+			}
+			if len(alerts) != 1 {
+				t.Fatalf("got %d alerts, want 1", len(alerts))
+			}
+			if alerts[0].AlertType != tt.wantType || alerts[0].Severity != tt.wantSeverity {
+				t.Errorf("alert = %s/%s, want %s/%s", alerts[0].AlertType, alerts[0].Severity, tt.wantType, tt.wantSeverity)
+			}
+		})
+	}
+}
+
+// serviceAccounts allowlists ci-bot as a service account handled with action
+func serviceAccounts(action string) func(*config.DetectionConfig) {
+	return func(detection *config.DetectionConfig) {
+		detection.APIKeyServiceAccounts = []string{"ci-bot"}
+		detection.APIKeyServiceAccountAction = action
+	}
+}
+
+func TestFailedLoginRuleFollowsWindowReload(t *testing.T) {
+	t.Setenv("FAILED_LOGIN_WINDOW_MIN", "15")
+	cfg := testConfig(func(detection *config.DetectionConfig) {
+		detection.FailedLoginInMemory = true
+	})
+	storage := newFakeStorage()
+	rule := NewFailedLoginRule(cfg, storage)
+	rule.counter.startedAt = time.Now().Add(-time.Hour)
+
+	// Failures spread over ten minutes all fall in the 15 minute window
+	end := time.Now().Add(-time.Minute)
+	events := failures("alice", 5, end.Add(-5*time.Minute))
+	events = append(events[:4], newEvent("auth.failed", "alice", "203.0.113.7", end))
+	storage.addEvents(events...)
+
+	// Shrink the window to five minutes; only the last failure remains inside it
+	t.Setenv("FAILED_LOGIN_WINDOW_MIN", "5")
+	if _, err := cfg.ReloadDetection(); err != nil {
+		t.Fatalf("ReloadDetection: %v", err)
+	}
+
+	var alerts []*models.Alert
+	for _, event := range events {
+		got, err := rule.Evaluate(context.Background(), event)
+		if err != nil {
+			t.Fatalf("Evaluate: %v", err)
+		}
+		alerts = append(alerts, got...)
+	}
+	if len(alerts) != 0 {
+		t.Errorf("got %d alerts, want none with the reloaded 5 minute window", len(alerts))
+	}
+	if rule.counter.window != 5*time.Minute {
+		t.Errorf("counter window = %s, want 5m", rule.counter.window)
+	}
+}
+
+func TestFailedLoginRuleCountsInStorageBeforeCounterStart(t *testing.T) {
+	cfg := testConfig(func(detection *config.DetectionConfig) {
+		detection.FailedLoginInMemory = true
+	})
+	// Four failures stored before the process started, then a fifth that
+	// arrives late, e.g. through a backfill or a detection retry
+	end := time.Now().Add(-30 * time.Minute)
+	events := failures("alice", 5, end)
+	storage := newFakeStorage(events...)
+	rule := NewFailedLoginRule(cfg, storage)
+	rule.counter.startedAt = end.Add(-time.Minute)
+
+	alerts, err := rule.Evaluate(context.Background(), events[4])
+	if err != nil {
+		t.Fatalf("Evaluate: %v", err)
+	}
+	if len(alerts) != 1 || alerts[0].Evidence["failed_attempts"] != 5 {
+		t.Fatalf("got %d alerts, want one counting all 5 stored failures", len(alerts))
+	}
+
+	// Once the counter has seen a whole window, it answers without storage
+	storage.err = errors.New("storage unavailable")
+	later := newEvent("auth.failed", "bob", "203.0.113.7", end.Add(20*time.Minute))
+	if alerts, err := rule.Evaluate(context.Background(), later); err != nil || len(alerts) != 0 {
+		t.Errorf("Evaluate with a complete count = %d alerts, %v, want none without querying storage", len(alerts), err)
+	}
+}
+
+func TestFailedLoginRuleAnchorsOnEventTime(t *testing.T) {
+	// A burst replayed from yesterday is judged against its own window
+	end := time.Now().Add(-24 * time.Hour)
+	events := failures("alice", 5, end)
+	storage := newFakeStorage(events...)
+	rule := NewFailedLoginRule(testConfig(), storage)
+
+	alerts, err := rule.Evaluate(context.Background(), events[len(events)-1])
+	if err != nil {
+		t.Fatalf("Evaluate: %v", err)
+	}
+	if len(alerts) != 1 {
+		t.Fatalf("got %d alerts, want 1 for the replayed burst", len(alerts))
+	}
+	if len(alerts[0].EventRefs) != 5 {
+		t.Errorf("got %d event refs, want 5", len(alerts[0].EventRefs))
+	}
+}
+
+func TestNewKeyRapidUseRuleDedupsPerKey(t *testing.T) {
+	now := time.Now().Add(-time.Hour)
+	keyCreation := func(keyID string) *models.Event {
+		event := newEvent("apiKey.create", "alice", "198.51.100.1", now)
+		event.Raw["key_id"] = keyID
+		return event
+	}
+	keyUse := func(keyID string, after time.Duration) *models.Event {
+		event := newEvent("instance.list", "alice", "203.0.113.9", now.Add(after))
+		event.Raw["access_key"] = keyID
+		return event
+	}
+	storage := newFakeStorage(keyCreation("SCWKEY1"), keyCreation("SCWKEY2"))
+	rule := NewNewKeyRapidUseRule(testConfig(), storage)
+
+	uses := []struct {
+		event     *models.Event
+		wantAlert bool
+	}{
+		{event: keyUse("SCWKEY1", time.Minute), wantAlert: true},
+		{event: keyUse("SCWKEY1", 2*time.Minute)},
+		{event: keyUse("SCWKEY2", 3*time.Minute), wantAlert: true},
+	}
+	for idx, use := range uses {
+		alerts, err := rule.Evaluate(context.Background(), use.event)
+		if err != nil {
+			t.Fatalf("Evaluate use %d: %v", idx, err)
+		}
+		if got := len(alerts) == 1; got != use.wantAlert {
+			t.Fatalf("use %d of %s raised %d alerts, want alert %v", idx, use.event.Raw["access_key"], len(alerts), use.wantAlert)
+		}
+		for _, alert := range alerts {
+			alert.ProjectID = use.event.ProjectID
+			if err := storage.StoreAlert(context.Background(), alert); err != nil {
+				t.Fatalf("StoreAlert: %v", err)
+			}
+		}
+	}
+}
+
+func TestAPIKeyCreationRuleAnchorsBurstOnEventTime(t *testing.T) {
+	// A burst replayed from yesterday escalates, and today's keys do not
+	// count towards it
+	end := time.Now().Add(-24 * time.Hour)
+	var events []*models.Event
+	for idx := 3; idx >= 0; idx-- {
+		events = append(events, newEvent("apiKey.create", "alice", "203.0.113.7", end.Add(-time.Duration(idx)*time.Minute)))
+	}
+	storage := newFakeStorage(events...)
+	storage.addEvents(newEvent("apiKey.create", "alice", "203.0.113.7", time.Now().Add(-time.Minute)))
+	rule := NewAPIKeyCreationRule(testConfig(), storage)
+
+	alerts, err := rule.Evaluate(context.Background(), events[len(events)-1])
+	if err != nil {
+		t.Fatalf("Evaluate: %v", err)
+	}
+	if len(alerts) != 1 || alerts[0].AlertType != apiKeyBurstAlertType {
+		t.Fatalf("got %v, want one burst alert", alerts)
+	}
+	if got := alerts[0].Evidence["key_count"]; got != 4 {
+		t.Errorf("key_count = %v, want 4", got)
+	}
+}
+
+func TestFailedLoginRuleAggregatesPerTenant(t *testing.T) {
+	end := time.Now().Add(-time.Minute)
+	inProject := func(projectID string, events []*models.Event) []*models.Event {
+		for _, event := range events {
+			event.ProjectID = projectID
+		}
+		return events
+	}
+
+	for _, inMemory := range []bool{false, true} {
+		// The same actor fails three times in each of two projects: five
+		// would alert, but neither tenant reaches it on its own
+		storage := newFakeStorage()
+		storage.addEvents(inProject("project-a", failures("alice", 3, end))...)
+		storage.addEvents(inProject("project-b", failures("alice", 3, end))...)
+		rule := NewFailedLoginRule(testConfig(func(detection *config.DetectionConfig) {
+			detection.FailedLoginInMemory = inMemory
+		}), storage)
+
+		var alerts []*models.Alert
+		for _, event := range storage.events {
+			got, err := rule.Evaluate(context.Background(), event)
+			if err != nil {
+				t.Fatalf("inMemory=%v: Evaluate: %v", inMemory, err)
+			}
+			alerts = append(alerts, got...)
+		}
+		if len(alerts) != 0 {
+			t.Errorf("inMemory=%v: got %d alerts, want none across tenants", inMemory, len(alerts))
+		}
+
+		// Two more failures in project-a cross its threshold alone
+		more := inProject("project-a", failures("alice", 2, end.Add(30*time.Second)))
+		storage.addEvents(more...)
+		for _, event := range more {
+			got, err := rule.Evaluate(context.Background(), event)
+			if err != nil {
+				t.Fatalf("inMemory=%v: Evaluate: %v", inMemory, err)
+			}
+			alerts = append(alerts, got...)
+		}
+		if len(alerts) != 1 {
+			t.Fatalf("inMemory=%v: got %d alerts, want 1 for project-a", inMemory, len(alerts))
+		}
+		if got := alerts[0].Evidence["failed_attempts"]; got != 5 {
+			t.Errorf("inMemory=%v: failed_attempts = %v, want project-a's 5", inMemory, got)
+		}
+	}
+}
@@ -3,38 +3,226 @@ package detection
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/scaleway/audit-sentinel/internal/models"
 	"github.com/scaleway/audit-sentinel/internal/storage"
 )
 
 // DetectionStorageImpl implements DetectionStorage interface
 type DetectionStorageImpl struct {
-	db        *sql.DB
-	alertRepo *storage.AlertRepository
+	db          *sql.DB
+	alertRepo   *storage.AlertRepository
+	eventRepo   *storage.EventRepository
+	profileRepo *storage.UserProfileRepository
+	muteRepo    *storage.MuteRepository
+	windowRepo  *storage.MaintenanceRepository
+	outbox      bool
 }
 
+// recentEventsLimit caps the history returned to rules for a single actor
+const recentEventsLimit = 1000
+
 // NewDetectionStorage creates a new detection storage implementation
 func NewDetectionStorage(db *sql.DB) *DetectionStorageImpl {
 	return &DetectionStorageImpl{
-		db:        db,
-		alertRepo: storage.NewAlertRepository(db),
+		db:          db,
+		alertRepo:   storage.NewAlertRepository(db),
+		eventRepo:   storage.NewEventRepository(db),
+		profileRepo: storage.NewUserProfileRepository(db),
+		muteRepo:    storage.NewMuteRepository(db),
+		windowRepo:  storage.NewMaintenanceRepository(db),
 	}
 }
 
-// StoreAlert stores an alert in the database
+// EnableOutbox makes StoreAlert also queue a notification for each alert
+func (s *DetectionStorageImpl) EnableOutbox() {
+	s.outbox = true
+}
+
+// StoreAlert stores an alert in the database. Alerts raised during
+// maintenance are never queued for notification.
 func (s *DetectionStorageImpl) StoreAlert(ctx context.Context, alert *models.Alert) error {
+	if s.outbox && !alert.DuringMaintenance() {
+		return s.alertRepo.StoreAlertWithOutbox(ctx, alert)
+	}
 	return s.alertRepo.StoreAlert(ctx, alert)
 }
 
-// GetUserProfile gets a user profile (not needed for MVP, but required by interface)
+// GetRecentEventsByActor returns up to recentEventsLimit of the actor's events
+// in the project since the given time, oldest first
+func (s *DetectionStorageImpl) GetRecentEventsByActor(ctx context.Context, projectID, actor string, since time.Time) ([]*models.Event, error) {
+	filter := storage.EventFilter{Actor: actor, ProjectID: projectID, From: &since}
+	return s.eventRepo.ListEvents(ctx, recentEventsLimit, 0, filter, storage.EventOrderTimestampAsc)
+}
+
+// CountRecentEventsByActor counts the actor's events of eventType in the
+// project between since and until, without loading them
+func (s *DetectionStorageImpl) CountRecentEventsByActor(ctx context.Context, projectID, actor, eventType string, since, until time.Time) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM events
+		WHERE actor = $1 AND event_type = $2
+		  AND timestamp >= $3 AND timestamp <= $4
+		  AND ($5::text = '' OR project_id = $5)
+	`, actor, eventType, since, until, projectID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count recent events: %w", err)
+	}
+	return count, nil
+}
+
+// countrySQL resolves an event's country from the region column or the raw payload
+const countrySQL = `COALESCE(NULLIF(region, ''), raw->>'country', raw->>'country_code')`
+
+// GetActorCountries counts the actor's events in the project per country,
+// excluding the given event, ordered by country
+func (s *DetectionStorageImpl) GetActorCountries(ctx context.Context, projectID, actor string, excludeEventID uuid.UUID) ([]CountryCount, error) {
+	query := `
+		SELECT ` + countrySQL + ` AS country, COUNT(*)
+		FROM events
+		WHERE actor = $1
+		  AND id <> $2
+		  AND ($3::text = '' OR project_id = $3)
+		  AND ` + countrySQL + ` IS NOT NULL
+		GROUP BY 1
+		ORDER BY 1
+	`
+	rows, err := s.db.QueryContext(ctx, query, actor, excludeEventID, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query known countries: %w", err)
+	}
+	defer rows.Close()
+
+	countries := []CountryCount{}
+	for rows.Next() {
+		var entry CountryCount
+		if err := rows.Scan(&entry.Country, &entry.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan known country: %w", err)
+		}
+		countries = append(countries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate known countries: %w", err)
+	}
+
+	return countries, nil
+}
+
+// HasRecentAlert reports whether an alert of the given type was raised for
+// the user in the project since the given time
+func (s *DetectionStorageImpl) HasRecentAlert(ctx context.Context, projectID, userID, alertType string, since time.Time) (bool, error) {
+	var exists bool
+	err := s.db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM alerts
+			WHERE user_id = $1 AND alert_type = $2 AND created_at > $3
+			  AND ($4::text = '' OR project_id = $4)
+		)
+	`, userID, alertType, since, projectID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check existing alert: %w", err)
+	}
+	return exists, nil
+}
+
+// HasRecentKeyAlert reports whether an alert of the given type was raised
+// for the API key in the project since the given time, matching the key_id
+// recorded in the alert's evidence
+func (s *DetectionStorageImpl) HasRecentKeyAlert(ctx context.Context, projectID, keyID, alertType string, since time.Time) (bool, error) {
+	var exists bool
+	err := s.db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM alerts
+			WHERE evidence->>'key_id' = $1 AND alert_type = $2 AND created_at > $3
+			  AND ($4::text = '' OR project_id = $4)
+		)
+	`, keyID, alertType, since, projectID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check existing key alert: %w", err)
+	}
+	return exists, nil
+}
+
+// FindOpenAlert returns the newest unresolved alert of alertType for userID
+// in the project, or nil if there is none
+func (s *DetectionStorageImpl) FindOpenAlert(ctx context.Context, projectID, alertType, userID string) (*models.Alert, error) {
+	return s.alertRepo.FindOpenAlert(ctx, projectID, alertType, userID)
+}
+
+// MergeEvidence folds a duplicate into an open alert's evidence
+func (s *DetectionStorageImpl) MergeEvidence(ctx context.Context, alertID uuid.UUID, delta models.EvidenceDelta) error {
+	return s.alertRepo.MergeEvidence(ctx, alertID, delta)
+}
+
+// GetUserProfile gets a user's risk profile, returning
+// storage.ErrUserProfileNotFound if the user has none yet
 func (s *DetectionStorageImpl) GetUserProfile(ctx context.Context, userID string) (*models.UserProfile, error) {
-	// MVP: Not implemented, return nil
-	return nil, nil
+	return s.profileRepo.GetUserProfile(ctx, userID)
 }
 
-// UpdateUserProfile updates a user profile (not needed for MVP, but required by interface)
+// UpdateUserProfile creates or updates a user's risk profile
 func (s *DetectionStorageImpl) UpdateUserProfile(ctx context.Context, profile *models.UserProfile) error {
-	// MVP: Not implemented, return nil
-	return nil
+	return s.profileRepo.UpsertUserProfile(ctx, profile)
+}
+
+// IsMuted reports whether an active mute suppresses alerts of alertType for
+// userID on resource
+func (s *DetectionStorageImpl) IsMuted(ctx context.Context, alertType, userID, resource string) (bool, error) {
+	return s.muteRepo.IsMuted(ctx, alertType, userID, resource)
+}
+
+// ActiveMaintenanceWindow returns the maintenance window covering alerts of
+// alertType at the given time, or nil if there is none
+func (s *DetectionStorageImpl) ActiveMaintenanceWindow(ctx context.Context, alertType string, at time.Time) (*models.MaintenanceWindow, error) {
+	return s.windowRepo.ActiveWindow(ctx, alertType, at)
+}
+
+// GetActorContext summarizes the actor's activity in the project between since
+// and until: the event count, the distinct source IPs and the latest
+// successful login up to until, which may predate since
+func (s *DetectionStorageImpl) GetActorContext(ctx context.Context, projectID, actor string, since, until time.Time) (*ActorContext, error) {
+	var (
+		snapshot    ActorContext
+		lastSuccess sql.NullTime
+	)
+	err := s.db.QueryRowContext(ctx, `
+		SELECT
+			COUNT(*) FILTER (WHERE timestamp >= $3),
+			COALESCE(ARRAY_AGG(DISTINCT ip) FILTER (WHERE timestamp >= $3 AND ip <> ''), '{}'),
+			MAX(timestamp) FILTER (WHERE event_type IN ('auth.success', 'login.success'))
+		FROM events
+		WHERE actor = $1
+		  AND ($2::text = '' OR project_id = $2)
+		  AND timestamp <= $4
+	`, actor, projectID, since, until).Scan(&snapshot.RecentEventCount, pq.Array(&snapshot.DistinctIPs), &lastSuccess)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query actor context: %w", err)
+	}
+	if lastSuccess.Valid {
+		snapshot.LastSuccessAt = &lastSuccess.Time
+	}
+	return &snapshot, nil
+}
+
+// GetKeyCreationEvent returns the latest apiKey.create event in the project
+// for keyID at or after since, or nil if there is none
+func (s *DetectionStorageImpl) GetKeyCreationEvent(ctx context.Context, projectID, keyID string, since time.Time) (*models.Event, error) {
+	filter := storage.EventFilter{
+		EventType: "apiKey.create",
+		ProjectID: projectID,
+		From:      &since,
+		Raw:       []storage.RawFilter{{Path: []string{"key_id"}, Operator: storage.RawEquals, Value: keyID}},
+	}
+	events, err := s.eventRepo.ListEvents(ctx, 1, 0, filter, storage.EventOrderTimestampDesc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query key creation: %w", err)
+	}
+	if len(events) == 0 {
+		return nil, nil
+	}
+	return events[0], nil
 }
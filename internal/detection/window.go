@@ -0,0 +1,115 @@
+package detection
+
+import (
+	"sync"
+	"time"
+)
+
+// sweepEvery controls how many Add calls happen between full sweeps of idle keys
+const sweepEvery = 1000
+
+// slidingWindowCounter counts recent occurrences per key within a fixed
+// window. It is safe for concurrent use.
+type slidingWindowCounter struct {
+	mu        sync.Mutex
+	window    time.Duration
+	entries   map[string]*windowEntries
+	startedAt time.Time
+	// sweptTo is the latest cutoff of a full sweep; entries at or before it
+	// may have been dropped for any key
+	sweptTo time.Time
+	adds    int
+	now     func() time.Time
+}
+
+// windowEntries holds the occurrences of one key and the latest cutoff they
+// were pruned to
+type windowEntries struct {
+	times    []time.Time
+	prunedTo time.Time
+}
+
+func newSlidingWindowCounter(window time.Duration) *slidingWindowCounter {
+	return &slidingWindowCounter{
+		window:    window,
+		entries:   make(map[string]*windowEntries),
+		startedAt: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Add records an occurrence for key at ts and returns the number of
+// occurrences for key in the window ending at ts. The window is anchored on
+// the event time, like the database queries the counter stands in for.
+//
+// complete reports whether the count can be trusted without consulting the
+// database: it is false when the window began before the counter started,
+// as for backfills and late events, or reaches back past entries already
+// pruned for a newer occurrence.
+func (c *slidingWindowCounter) Add(key string, ts time.Time) (count int, complete bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cutoff := ts.Add(-c.window)
+	entries, ok := c.entries[key]
+	if !ok {
+		entries = &windowEntries{}
+		c.entries[key] = entries
+	}
+	complete = !cutoff.Before(c.startedAt) && !cutoff.Before(c.sweptTo) && !cutoff.Before(entries.prunedTo)
+
+	entries.times = append(pruneBefore(entries.times, cutoff), ts)
+	if cutoff.After(entries.prunedTo) {
+		entries.prunedTo = cutoff
+	}
+
+	c.adds++
+	if c.adds%sweepEvery == 0 {
+		c.sweep(cutoff)
+	}
+
+	for _, entry := range entries.times {
+		if !entry.After(ts) {
+			count++
+		}
+	}
+	return count, complete
+}
+
+// SetWindow changes the window length. Entries older than a longer window
+// were already dropped, so growing it restarts the warm-up period.
+func (c *slidingWindowCounter) SetWindow(window time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if window == c.window {
+		return
+	}
+	if window > c.window {
+		c.startedAt = c.now()
+	}
+	c.window = window
+}
+
+// sweep drops expired entries for every key; callers must hold mu
+func (c *slidingWindowCounter) sweep(cutoff time.Time) {
+	if cutoff.After(c.sweptTo) {
+		c.sweptTo = cutoff
+	}
+	for key, entries := range c.entries {
+		entries.times = pruneBefore(entries.times, cutoff)
+		if len(entries.times) == 0 {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// pruneBefore drops timestamps at or before cutoff, keeping insertion order
+func pruneBefore(entries []time.Time, cutoff time.Time) []time.Time {
+	kept := entries[:0]
+	for _, ts := range entries {
+		if ts.After(cutoff) {
+			kept = append(kept, ts)
+		}
+	}
+	return kept
+}
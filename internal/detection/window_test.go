@@ -0,0 +1,95 @@
+package detection
+
+import (
+	"testing"
+	"time"
+)
+
+// newTestCounter returns a counter whose clock is fixed at now and that
+// started a day earlier
+func newTestCounter(window time.Duration, now time.Time) *slidingWindowCounter {
+	counter := newSlidingWindowCounter(window)
+	counter.now = func() time.Time { return now }
+	counter.startedAt = now.Add(-24 * time.Hour)
+	return counter
+}
+
+func TestSlidingWindowCounterUsesEventTime(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	counter := newTestCounter(15*time.Minute, now)
+
+	// A backfill from two hours ago is counted within its own window, not
+	// dropped for being older than the wall-clock window
+	backfill := now.Add(-2 * time.Hour)
+	for idx := 0; idx < 3; idx++ {
+		if got, _ := counter.Add("alice", backfill.Add(time.Duration(idx)*time.Minute)); got != idx+1 {
+			t.Fatalf("Add #%d = %d, want %d", idx, got, idx+1)
+		}
+	}
+
+	// An event a full window later only sees itself
+	if got, _ := counter.Add("alice", backfill.Add(20*time.Minute)); got != 1 {
+		t.Errorf("Add after the window = %d, want 1", got)
+	}
+	if got, _ := counter.Add("bob", backfill); got != 1 {
+		t.Errorf("Add for another key = %d, want 1", got)
+	}
+}
+
+func TestSlidingWindowCounterIgnoresLaterEntries(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	counter := newTestCounter(15*time.Minute, now)
+
+	counter.Add("alice", now)
+	counter.Add("alice", now.Add(-time.Minute))
+	// A late event is counted against the window ending at its own time
+	if got, _ := counter.Add("alice", now.Add(-10*time.Minute)); got != 1 {
+		t.Errorf("late Add = %d, want 1", got)
+	}
+}
+
+func TestSlidingWindowCounterCompleteness(t *testing.T) {
+	window := 15 * time.Minute
+	startedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	counter := newSlidingWindowCounter(window)
+	counter.startedAt = startedAt
+
+	tests := []struct {
+		name         string
+		key          string
+		ts           time.Time
+		wantComplete bool
+	}{
+		{name: "window began before start", key: "alice", ts: startedAt.Add(10 * time.Minute)},
+		{name: "backfill older than start", key: "alice", ts: startedAt.Add(-time.Hour)},
+		{name: "window began at start", key: "alice", ts: startedAt.Add(window), wantComplete: true},
+		{name: "in order", key: "alice", ts: startedAt.Add(window + time.Minute), wantComplete: true},
+		{name: "newer event prunes", key: "alice", ts: startedAt.Add(2 * time.Hour), wantComplete: true},
+		{name: "late event behind the pruned entries", key: "alice", ts: startedAt.Add(time.Hour)},
+		{name: "late event for another key", key: "bob", ts: startedAt.Add(time.Hour), wantComplete: true},
+	}
+	for _, tt := range tests {
+		if _, complete := counter.Add(tt.key, tt.ts); complete != tt.wantComplete {
+			t.Errorf("%s: complete = %v, want %v", tt.name, complete, tt.wantComplete)
+		}
+	}
+}
+
+func TestSlidingWindowCounterSetWindow(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	counter := newTestCounter(15*time.Minute, now)
+	if _, complete := counter.Add("alice", now.Add(-10*time.Minute)); !complete {
+		t.Fatal("count incomplete a day after start")
+	}
+
+	counter.SetWindow(5 * time.Minute)
+	got, complete := counter.Add("alice", now)
+	if got != 1 || !complete {
+		t.Errorf("Add with a 5 minute window = %d (complete %v), want a complete count of 1", got, complete)
+	}
+
+	counter.SetWindow(30 * time.Minute)
+	if _, complete := counter.Add("alice", now.Add(time.Minute)); complete {
+		t.Error("growing the window kept counts complete")
+	}
+}
@@ -0,0 +1,103 @@
+package ingestion
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/scaleway/audit-sentinel/internal/metrics"
+	"github.com/scaleway/audit-sentinel/internal/models"
+)
+
+// Queue full policies of an AsyncProcessor
+const (
+	// QueueFullBlock makes ingestion wait for room in the detection queue
+	QueueFullBlock = "block"
+	// QueueFullDrop skips detection for events that do not fit in the queue
+	QueueFullDrop = "drop"
+)
+
+// AsyncProcessor runs detection on a pool of workers fed by a buffered
+// queue, so slow rules do not hold up fetching and storing events
+type AsyncProcessor struct {
+	next         EventProcessor
+	queue        chan queuedEvent
+	dropWhenFull bool
+	wg           sync.WaitGroup
+	closeOnce    sync.Once
+}
+
+type queuedEvent struct {
+	ctx   context.Context
+	event *models.Event
+}
+
+// NewAsyncProcessor starts workers goroutines passing queued events to next.
+// fullPolicy is QueueFullBlock or QueueFullDrop.
+func NewAsyncProcessor(next EventProcessor, workers, queueSize int, fullPolicy string) *AsyncProcessor {
+	p := &AsyncProcessor{
+		next:         next,
+		queue:        make(chan queuedEvent, queueSize),
+		dropWhenFull: fullPolicy == QueueFullDrop,
+	}
+	for n := 0; n < workers; n++ {
+		p.wg.Add(1)
+		go p.work()
+	}
+	return p
+}
+
+// ProcessEvent queues event for detection. Detection outlives the
+// ingestion cycle that stored the event, so ctx cancellation only aborts
+// waiting for room in the queue.
+func (p *AsyncProcessor) ProcessEvent(ctx context.Context, event *models.Event) error {
+	queued := queuedEvent{ctx: context.WithoutCancel(ctx), event: event}
+
+	if p.dropWhenFull {
+		select {
+		case p.queue <- queued:
+		default:
+			metrics.DroppedDetections.Inc()
+			log.Printf("Detection queue full, skipping detection for event %s", event.EventID)
+		}
+		return nil
+	}
+
+	select {
+	case p.queue <- queued:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *AsyncProcessor) work() {
+	defer p.wg.Done()
+	for queued := range p.queue {
+		metrics.DetectionQueueDepth.Set(float64(len(p.queue)))
+		// The wrapped processor logs its own failures
+		_ = p.next.ProcessEvent(queued.ctx, queued.event)
+	}
+}
+
+// Close stops accepting events and waits for the workers to drain the
+// queue, giving up when ctx expires. ProcessEvent must not be called after
+// Close.
+func (p *AsyncProcessor) Close(ctx context.Context) error {
+	p.closeOnce.Do(func() {
+		close(p.queue)
+	})
+
+	drained := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,33 @@
+package ingestion
+
+import (
+	"net/netip"
+	"strings"
+
+	"github.com/scaleway/audit-sentinel/internal/models"
+	"github.com/scaleway/audit-sentinel/internal/threatintel"
+)
+
+// ipScopeKey is the raw payload field holding the event's IP scope
+const ipScopeKey = "ip_scope"
+
+// IPReputation looks event IPs up in a threat intel blocklist
+type IPReputation interface {
+	Lookup(ip string) (threatintel.Match, bool)
+}
+
+// classifyIP returns models.IPScopeInternal for private (RFC 1918, IPv6
+// unique local), loopback and link-local addresses, models.IPScopePublic for
+// any other address, and an empty scope if ip cannot be parsed
+func classifyIP(ip string) string {
+	addr, err := netip.ParseAddr(strings.Trim(strings.TrimSpace(ip), "[]"))
+	if err != nil {
+		return ""
+	}
+	addr = addr.Unmap()
+
+	if addr.IsPrivate() || addr.IsLoopback() || addr.IsLinkLocalUnicast() || addr.IsUnspecified() {
+		return models.IPScopeInternal
+	}
+	return models.IPScopePublic
+}
@@ -0,0 +1,47 @@
+package ingestion
+
+import (
+	"testing"
+
+	"github.com/scaleway/audit-sentinel/internal/models"
+)
+
+func TestClassifyIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{ip: "10.1.2.3", want: models.IPScopeInternal},
+		{ip: "172.16.0.1", want: models.IPScopeInternal},
+		{ip: "172.31.255.255", want: models.IPScopeInternal},
+		{ip: "192.168.1.10", want: models.IPScopeInternal},
+		{ip: "127.0.0.1", want: models.IPScopeInternal},
+		{ip: "169.254.10.20", want: models.IPScopeInternal},
+		{ip: "0.0.0.0", want: models.IPScopeInternal},
+		{ip: "172.32.0.1", want: models.IPScopePublic},
+		{ip: "8.8.8.8", want: models.IPScopePublic},
+		{ip: "203.0.113.7", want: models.IPScopePublic},
+		{ip: " 51.15.0.1 ", want: models.IPScopePublic},
+
+		{ip: "::1", want: models.IPScopeInternal},
+		{ip: "fd12:3456:789a::1", want: models.IPScopeInternal},
+		{ip: "fe80::1", want: models.IPScopeInternal},
+		{ip: "[fe80::1]", want: models.IPScopeInternal},
+		{ip: "::ffff:192.168.0.1", want: models.IPScopeInternal},
+		{ip: "::", want: models.IPScopeInternal},
+		{ip: "2001:4860:4860::8888", want: models.IPScopePublic},
+		{ip: "2001:bc8::1", want: models.IPScopePublic},
+		{ip: "::ffff:8.8.8.8", want: models.IPScopePublic},
+
+		{ip: "", want: ""},
+		{ip: "not-an-ip", want: ""},
+		{ip: "10.0.0.1:443", want: ""},
+		{ip: "256.1.1.1", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := classifyIP(tt.ip); got != tt.want {
+			t.Errorf("classifyIP(%q) = %q, want %q", tt.ip, got, tt.want)
+		}
+	}
+}
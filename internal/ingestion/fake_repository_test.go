@@ -0,0 +1,96 @@
+package ingestion
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/scaleway/audit-sentinel/internal/config"
+	"github.com/scaleway/audit-sentinel/internal/models"
+	"github.com/scaleway/audit-sentinel/pkg/scaleway"
+)
+
+// fakeRepository is an in-memory EventRepository
+type fakeRepository struct {
+	mu     sync.Mutex
+	events []*models.Event
+}
+
+func (r *fakeRepository) StoreEvent(ctx context.Context, event *models.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *fakeRepository) GetLastTenantEventTimestamp(ctx context.Context, projectID, organizationID string) (*time.Time, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var last *time.Time
+	for _, event := range r.events {
+		if event.ProjectID == projectID && event.OrganizationID == organizationID && (last == nil || event.Timestamp.After(*last)) {
+			ts := event.Timestamp
+			last = &ts
+		}
+	}
+	return last, nil
+}
+
+func (r *fakeRepository) EventExists(ctx context.Context, eventID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, event := range r.events {
+		if event.EventID == eventID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeRepository) SeenBefore(ctx context.Context, projectID, actor, ip string) (actorSeen, ipSeen bool, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, event := range r.events {
+		if projectID != "" && event.ProjectID != projectID {
+			continue
+		}
+		actorSeen = actorSeen || event.Actor == actor
+		ipSeen = ipSeen || event.IP == ip
+	}
+	return actorSeen, ipSeen, nil
+}
+
+// stored returns the stored events in order
+func (r *fakeRepository) stored() []*models.Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*models.Event(nil), r.events...)
+}
+
+// newTestIngestor returns an ingestor storing into repo that fetches the
+// given tenants ("project:organization") from the Scaleway API at apiURL
+func newTestIngestor(apiURL string, repo EventRepository, tenants ...string) *Ingestor {
+	cfg := &config.Config{
+		Scaleway: config.ScalewayConfig{ProjectID: "project-1", Tenants: tenants},
+		Ingestion: config.IngestionConfig{
+			PollIntervalSeconds: 60,
+			MaxRangeSpan:        24 * time.Hour,
+		},
+	}
+	client := scaleway.NewClient("test-secret-key", "project-1", "", apiURL)
+	return NewIngestor(cfg, client, repo)
+}
+
+// auditEvent builds a fetched audit event for the tenant
+func auditEvent(id, eventType, actor, ip, projectID string, ts time.Time) *scaleway.AuditEvent {
+	return &scaleway.AuditEvent{
+		ID:        id,
+		Type:      eventType,
+		Actor:     actor,
+		IP:        ip,
+		Timestamp: ts,
+		Source:    "audit",
+		Tenant:    scaleway.Tenant{ProjectID: projectID},
+		Raw:       map[string]any{"id": id, "actor": actor},
+	}
+}
@@ -2,22 +2,74 @@ package ingestion
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/scaleway/audit-sentinel/internal/config"
+	"github.com/scaleway/audit-sentinel/internal/metrics"
 	"github.com/scaleway/audit-sentinel/internal/models"
+	"github.com/scaleway/audit-sentinel/internal/tracing"
 	"github.com/scaleway/audit-sentinel/pkg/scaleway"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/errgroup"
 )
 
+// tracer records ingestion spans
+var tracer = tracing.Tracer("ingestion")
+
+// maxSkipReasons caps the sample of skip reasons kept per cycle
+const maxSkipReasons = 5
+
+// ErrIngestionRunning is returned by Ingest when another cycle is in progress
+var ErrIngestionRunning = errors.New("ingestion already running")
+
+// ErrInvalidRange is returned by IngestRange for an empty or oversized window
+var ErrInvalidRange = errors.New("invalid ingestion range")
+
 // Ingestor handles event ingestion from Scaleway API
 type Ingestor struct {
 	config     *config.Config
 	client     *scaleway.Client
 	repository EventRepository
 	processor  EventProcessor
+	types      *typeNormalizer
+	// tenants are fetched in order on every cycle
+	tenants    []scaleway.Tenant
+	reputation IPReputation
+	// logs samples the per-event failure lines
+	logs *logSampler
+
+	mu    sync.Mutex
+	stats Stats
+	// cycleSkipped accumulates the malformed entries of the running cycle
+	cycleSkipped skippedEntries
+	// cycleFailed counts the events of the running cycle that failed to ingest
+	cycleFailed int
+}
+
+// skippedEntries totals malformed entries across fetches
+type skippedEntries struct {
+	count   int
+	reasons []string
+}
+
+// Stats describes the state of ingestion cycles
+type Stats struct {
+	Running             bool       `json:"running"`
+	LastStartedAt       *time.Time `json:"last_started_at"`
+	LastSuccessStart    *time.Time `json:"last_success_started_at"`
+	LastSuccessEnd      *time.Time `json:"last_success_finished_at"`
+	LastEventsFetched   int        `json:"last_events_fetched"`
+	LastSkippedEntries  int        `json:"last_skipped_malformed"`
+	LastSkipReasons     []string   `json:"last_skip_reasons,omitempty"`
+	LastFailedEvents    int        `json:"last_failed_events"`
+	LastError           string     `json:"last_error,omitempty"`
+	PollIntervalSeconds int        `json:"poll_interval_seconds"`
 }
 
 // EventProcessor defines the interface for event processing
@@ -28,8 +80,13 @@ type EventProcessor interface {
 // EventRepository defines the interface for event storage
 type EventRepository interface {
 	StoreEvent(ctx context.Context, event *models.Event) error
-	GetLastEventTimestamp(ctx context.Context) (*time.Time, error)
+	// GetLastTenantEventTimestamp returns the newest event timestamp stored
+	// for the tenant, or nil if it has none
+	GetLastTenantEventTimestamp(ctx context.Context, projectID, organizationID string) (*time.Time, error)
 	EventExists(ctx context.Context, eventID string) (bool, error)
+	// SeenBefore reports whether any event was stored in the project for
+	// actor and for ip
+	SeenBefore(ctx context.Context, projectID, actor, ip string) (actorSeen, ipSeen bool, err error)
 }
 
 // Event represents an ingested event
@@ -40,17 +97,42 @@ type Event struct {
 	Actor     string
 	Resource  string
 	IP        string
+	IPScope   string
 	Region    string
 	Timestamp time.Time
 }
 
 // NewIngestor creates a new event ingestor
 func NewIngestor(cfg *config.Config, client *scaleway.Client, repo EventRepository) *Ingestor {
-	return &Ingestor{
+	i := &Ingestor{
 		config:     cfg,
 		client:     client,
 		repository: repo,
-		processor:  nil, 
+		processor:  nil,
+		types:      newTypeNormalizer(cfg.Ingestion.EventTypeMappings),
+		logs:       newLogSampler(cfg.Ingestion.LogSampleFirst, cfg.Ingestion.LogSampleEvery),
+	}
+	for _, tenant := range cfg.Scaleway.TenantList() {
+		i.tenants = append(i.tenants, scaleway.Tenant{
+			ProjectID:      tenant.ProjectID,
+			OrganizationID: tenant.OrganizationID,
+		})
+	}
+	client.SetSkipHandler(i.recordSkipped)
+	return i
+}
+
+// recordSkipped counts malformed entries dropped by the client
+func (i *Ingestor) recordSkipped(report scaleway.SkipReport) {
+	metrics.MalformedEvents.WithLabelValues(report.Source).Add(float64(report.Skipped))
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.cycleSkipped.count += report.Skipped
+	for _, reason := range report.Reasons {
+		if len(i.cycleSkipped.reasons) < maxSkipReasons {
+			i.cycleSkipped.reasons = append(i.cycleSkipped.reasons, report.Source+": "+reason)
+		}
 	}
 }
 
@@ -59,6 +141,11 @@ func (i *Ingestor) SetProcessor(processor EventProcessor) {
 	i.processor = processor
 }
 
+// SetIPReputation sets the blocklist events' IPs are checked against
+func (i *Ingestor) SetIPReputation(reputation IPReputation) {
+	i.reputation = reputation
+}
+
 // Start begins periodic ingestion
 func (i *Ingestor) Start(ctx context.Context) error {
 	ticker := time.NewTicker(time.Duration(i.config.Ingestion.PollIntervalSeconds) * time.Second)
@@ -74,116 +161,386 @@ func (i *Ingestor) Start(ctx context.Context) error {
 		case <-ctx.Done():
 			return ctx.Err()
 		case <-ticker.C:
-			if err := i.Ingest(ctx); err != nil {
+			err := i.Ingest(ctx)
+			if errors.Is(err, ErrIngestionRunning) {
+				log.Println("Skipping scheduled ingestion: previous cycle still running")
+			} else if err != nil {
 				log.Printf("Ingestion failed: %v", err)
 			}
 		}
 	}
 }
 
-// Ingest fetches and stores events from Scaleway API
+// Stats returns a snapshot of the ingestion statistics
+func (i *Ingestor) Stats() Stats {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	stats := i.stats
+	stats.PollIntervalSeconds = i.config.Ingestion.PollIntervalSeconds
+	return stats
+}
+
+// Ingest fetches and stores events from Scaleway API, recording the outcome
+// of the cycle in the ingestion statistics. Only one cycle runs at a time;
+// a call made while another is in progress returns ErrIngestionRunning.
 func (i *Ingestor) Ingest(ctx context.Context) error {
+	started := time.Now()
+	i.mu.Lock()
+	if i.stats.Running {
+		i.mu.Unlock()
+		return ErrIngestionRunning
+	}
+	i.stats.Running = true
+	i.stats.LastStartedAt = &started
+	i.cycleSkipped = skippedEntries{}
+	i.cycleFailed = 0
+	i.mu.Unlock()
+
+	ctx, span := tracer.Start(ctx, "ingestion.cycle")
+	fetched, err := i.ingest(ctx)
+	span.SetAttributes(attribute.Int("events_fetched", fetched))
+	tracing.End(span, err)
+
+	finished := time.Now()
+	i.mu.Lock()
+	i.stats.Running = false
+	if err != nil {
+		i.stats.LastError = err.Error()
+	} else {
+		i.stats.LastError = ""
+		i.stats.LastSuccessStart = &started
+		i.stats.LastSuccessEnd = &finished
+		i.stats.LastEventsFetched = fetched
+		i.stats.LastSkippedEntries = i.cycleSkipped.count
+		i.stats.LastSkipReasons = i.cycleSkipped.reasons
+		i.stats.LastFailedEvents = i.cycleFailed
+	}
+	i.mu.Unlock()
+
+	return err
+}
+
+// IngestRange fetches and stores the events between from and to, regardless
+// of the ingestion cursor, to backfill a gap. Each tenant's cursor is its
+// newest stored event, so the window is cut off there: newer events are left
+// to the regular cycle. It shares the single-run guard with Ingest but does not update the
+// cycle statistics. It returns the number of events fetched.
+func (i *Ingestor) IngestRange(ctx context.Context, from, to time.Time) (int, error) {
+	if !from.Before(to) {
+		return 0, fmt.Errorf("%w: from must be before to", ErrInvalidRange)
+	}
+	if span := to.Sub(from); span > i.config.Ingestion.MaxRangeSpan {
+		return 0, fmt.Errorf("%w: span %s exceeds the maximum of %s", ErrInvalidRange, span, i.config.Ingestion.MaxRangeSpan)
+	}
+
+	i.mu.Lock()
+	if i.stats.Running {
+		i.mu.Unlock()
+		return 0, ErrIngestionRunning
+	}
+	i.stats.Running = true
+	i.mu.Unlock()
+
+	defer func() {
+		i.mu.Lock()
+		i.stats.Running = false
+		i.mu.Unlock()
+	}()
+
+	log.Printf("Starting range ingestion from %s to %s...", from.Format(time.RFC3339), to.Format(time.RFC3339))
+
+	fetched := 0
+	var errs []error
+	for _, tenant := range i.tenants {
+		n, err := i.ingestTenantRange(ctx, tenant, from, to)
+		fetched += n
+		if err != nil {
+			errs = append(errs, fmt.Errorf("tenant %s: %w", tenant, err))
+		}
+	}
+	return fetched, errors.Join(errs...)
+}
+
+// ingestTenantRange backfills one tenant's events between from and to, cut
+// off at the tenant's cursor
+func (i *Ingestor) ingestTenantRange(ctx context.Context, tenant scaleway.Tenant, from, to time.Time) (int, error) {
+	cursor, err := i.repository.GetLastTenantEventTimestamp(ctx, tenant.ProjectID, tenant.OrganizationID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get last event timestamp: %w", err)
+	}
+	if cursor != nil && to.After(*cursor) {
+		log.Printf("Range ingestion for tenant %s capped at the cursor %s", tenant, cursor.Format(time.RFC3339))
+		to = *cursor
+		if !from.Before(to) {
+			return 0, nil
+		}
+	}
+
+	auditEvents, authEvents, err := i.fetchBoth(ctx,
+		func(ctx context.Context) ([]*scaleway.AuditEvent, error) {
+			return i.client.FetchAuditEventsBetween(ctx, tenant, from, to)
+		},
+		func(ctx context.Context) ([]*scaleway.AuditEvent, error) {
+			return i.client.FetchAuthenticationEventsBetween(ctx, tenant, from, to)
+		},
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	events := make([]*scaleway.AuditEvent, 0, len(auditEvents)+len(authEvents))
+	events = append(events, auditEvents...)
+	events = append(events, authEvents...)
+
+	return len(events), i.storeEvents(ctx, events)
+}
+
+// ingest runs one ingestion cycle over every tenant and returns the number of
+// events fetched. A failing tenant does not stop the others.
+func (i *Ingestor) ingest(ctx context.Context) (int, error) {
 	log.Println("Starting event ingestion...")
 
+	fetched := 0
+	var errs []error
+	for _, tenant := range i.tenants {
+		if ctx.Err() != nil {
+			return fetched, ctx.Err()
+		}
+		n, err := i.ingestTenant(ctx, tenant)
+		fetched += n
+		if err != nil {
+			errs = append(errs, fmt.Errorf("tenant %s: %w", tenant, err))
+		}
+	}
+	return fetched, errors.Join(errs...)
+}
+
+// ingestTenant fetches and stores the tenant's events newer than its cursor
+func (i *Ingestor) ingestTenant(ctx context.Context, tenant scaleway.Tenant) (fetched int, err error) {
+	ctx, span := tracer.Start(ctx, "ingestion.tenant", trace.WithAttributes(tracing.AttrTenant.String(tenant.String())))
+	defer func() { tracing.End(span, err) }()
+
 	// Get last event timestamp to determine fetch window
-	lastTimestamp, err := i.repository.GetLastEventTimestamp(ctx)
+	lastTimestamp, err := i.repository.GetLastTenantEventTimestamp(ctx, tenant.ProjectID, tenant.OrganizationID)
 	if err != nil {
-		log.Printf("Failed to get last event timestamp: %v", err)
+		log.Printf("Failed to get last event timestamp for tenant %s: %v", tenant, err)
 		// Continue with default window
 	}
-
-	// Fetch audit trail events
-	auditEvents, err := i.client.FetchAuditEvents(ctx, lastTimestamp)
-	if err != nil {
-		return fmt.Errorf("failed to fetch audit events: %w", err)
+	if lastTimestamp != nil {
+		since := lastTimestamp.Add(-i.config.Ingestion.SinceOverlap)
+		lastTimestamp = &since
 	}
 
-	// Fetch authentication events
-	authEvents, err := i.client.FetchAuthenticationEvents(ctx, lastTimestamp)
+	// Fetch audit trail and authentication events
+	auditEvents, authEvents, err := i.fetchBoth(ctx,
+		func(ctx context.Context) ([]*scaleway.AuditEvent, error) {
+			return i.client.FetchAuditEvents(ctx, tenant, lastTimestamp)
+		},
+		func(ctx context.Context) ([]*scaleway.AuditEvent, error) {
+			return i.client.FetchAuthenticationEvents(ctx, tenant, lastTimestamp)
+		},
+	)
 	if err != nil {
-		return fmt.Errorf("failed to fetch authentication events: %w", err)
+		return 0, err
 	}
 
-	log.Printf("Fetched %d audit events and %d authentication events from Scaleway API", len(auditEvents), len(authEvents))
+	log.Printf("Fetched %d audit events and %d authentication events for tenant %s from Scaleway API", len(auditEvents), len(authEvents), tenant)
 
 	events := make([]*scaleway.AuditEvent, 0, len(auditEvents)+len(authEvents))
 	events = append(events, auditEvents...)
 	events = append(events, authEvents...)
 	if len(events) == 0 {
-		log.Println("No new events to ingest")
-		return nil
+		log.Printf("No new events to ingest for tenant %s", tenant)
+		return 0, nil
 	}
 
-	// Process and store events
-	for _, scalewayEvent := range events {
-		// Check for duplicates
-		exists, err := i.repository.EventExists(ctx, scalewayEvent.ID)
-		if err != nil {
-			log.Printf("Error checking event existence: %v", err)
-			continue
+	return len(events), i.storeEvents(ctx, events)
+}
+
+// fetchFunc fetches one kind of event for a tenant
+type fetchFunc func(ctx context.Context) ([]*scaleway.AuditEvent, error)
+
+// fetchBoth runs the audit and authentication fetches, concurrently unless
+// INGEST_PARALLEL_FETCH is off. Either failing fails both, and cancels the
+// other when they run concurrently.
+func (i *Ingestor) fetchBoth(ctx context.Context, fetchAudit, fetchAuth fetchFunc) (audit, auth []*scaleway.AuditEvent, err error) {
+	if !i.config.Ingestion.ParallelFetch {
+		if audit, err = fetchAudit(ctx); err != nil {
+			return nil, nil, fmt.Errorf("failed to fetch audit events: %w", err)
 		}
-		if exists {
-			continue
+		if auth, err = fetchAuth(ctx); err != nil {
+			return nil, nil, fmt.Errorf("failed to fetch authentication events: %w", err)
 		}
+		return audit, auth, nil
+	}
 
-		// Ensure raw map exists and annotate source for downstream consumers
-		if scalewayEvent.Raw == nil {
-			scalewayEvent.Raw = map[string]any{}
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.Go(func() (err error) {
+		if audit, err = fetchAudit(groupCtx); err != nil {
+			return fmt.Errorf("failed to fetch audit events: %w", err)
 		}
-		if scalewayEvent.Source != "" {
-			scalewayEvent.Raw["source"] = scalewayEvent.Source
+		return nil
+	})
+	group.Go(func() (err error) {
+		if auth, err = fetchAuth(groupCtx); err != nil {
+			return fmt.Errorf("failed to fetch authentication events: %w", err)
 		}
+		return nil
+	})
+	if err := group.Wait(); err != nil {
+		return nil, nil, err
+	}
+	return audit, auth, nil
+}
 
-		// Convert Scaleway event to ingestion event
-		event := &Event{
-			EventID:   scalewayEvent.ID,
-			Raw:       scalewayEvent.Raw,
-			EventType: scalewayEvent.Type,
-			Actor:     scalewayEvent.Actor,
-			Resource:  scalewayEvent.Resource,
-			IP:        scalewayEvent.IP,
-			Timestamp: scalewayEvent.Timestamp,
-		}
+// markFirstSeen flags the event in raw when it is the first stored for its
+// actor or IP, so rules need not query for it. On error the flags are left
+// unset rather than guessed.
+func (i *Ingestor) markFirstSeen(ctx context.Context, event *models.Event) {
+	actorSeen, ipSeen, err := i.repository.SeenBefore(ctx, event.ProjectID, event.Actor, event.IP)
+	if err != nil {
+		i.logs.Printf("first_seen", "Failed to check first-seen actor and IP for event %s: %v", event.EventID, err)
+		return
+	}
+	if !actorSeen {
+		event.Raw[models.RawActorFirstSeen] = true
+	}
+	if !ipSeen {
+		event.Raw[models.RawIPFirstSeen] = true
+	}
+}
 
-		// Enrich event (geo IP, etc.)
-		enrichedEvent := i.enrichEvent(event)
-
-		// Convert to models.Event for storage
-		modelEvent := &models.Event{
-			ID:           uuid.New(),
-			EventID:      enrichedEvent.EventID,
-			Raw:          enrichedEvent.Raw,
-			EventType:    enrichedEvent.EventType,
-			Actor:        enrichedEvent.Actor,
-			Resource:     enrichedEvent.Resource,
-			IP:           enrichedEvent.IP,
-			Region:       enrichedEvent.Region,
-			Timestamp:    enrichedEvent.Timestamp,
-			IngestFailed: false,
-			CreatedAt:    time.Now(),
-		}
+// storeEvents ingests fetched events one by one, stopping early if ctx is
+// cancelled. Per-event failures are logged sampled; the closing summary
+// counts all of them.
+func (i *Ingestor) storeEvents(ctx context.Context, events []*scaleway.AuditEvent) error {
+	processed, failed := 0, 0
+	defer func() {
+		i.logs.Flush()
+		i.mu.Lock()
+		i.cycleFailed += failed
+		i.mu.Unlock()
+	}()
 
-		// Store event
-		if err := i.repository.StoreEvent(ctx, modelEvent); err != nil {
-			log.Printf("Failed to store event %s: %v", scalewayEvent.ID, err)
-			continue
+	for _, scalewayEvent := range events {
+		// Stop early on shutdown; remaining events are picked up next cycle
+		if ctx.Err() != nil {
+			log.Printf("Ingestion cancelled after %d of %d events (%d failed)", processed, len(events), failed)
+			return ctx.Err()
 		}
+		processed++
 
-		// Process event through detection engine (if available)
-		if i.processor != nil {
-			if err := i.processor.ProcessEvent(ctx, modelEvent); err != nil {
-				log.Printf("Failed to process event %s through detection: %v", scalewayEvent.ID, err)
-				// Continue even if detection fails
-			}
+		if _, err := i.IngestEvent(ctx, scalewayEvent); err != nil {
+			failed++
+			i.logs.Printf("ingest", "Failed to ingest event %s: %v", scalewayEvent.ID, err)
 		}
 	}
 
-	log.Printf("Ingestion completed: %d events processed", len(events))
+	log.Printf("Ingestion completed: %d events processed, %d failed", len(events), failed)
 	return nil
 }
 
+// IngestEvent dedups, enriches, stores and runs detection on a single event.
+// It reports whether the event was newly stored; duplicates return false with
+// no error. Detection failures are logged but do not fail ingestion.
+func (i *Ingestor) IngestEvent(ctx context.Context, scalewayEvent *scaleway.AuditEvent) (stored bool, err error) {
+	ctx, span := tracer.Start(ctx, "ingestion.ingest_event", trace.WithAttributes(tracing.AttrEventID.String(scalewayEvent.ID)))
+	defer func() { tracing.End(span, err) }()
+
+	// Check for duplicates
+	exists, err := i.repository.EventExists(ctx, scalewayEvent.ID)
+	if err != nil {
+		return false, fmt.Errorf("error checking event existence: %w", err)
+	}
+	if exists {
+		return false, nil
+	}
+
+	// Ensure raw map exists and annotate source for downstream consumers
+	if scalewayEvent.Raw == nil {
+		scalewayEvent.Raw = map[string]any{}
+	}
+	if scalewayEvent.Source != "" {
+		scalewayEvent.Raw["source"] = scalewayEvent.Source
+	}
+
+	// Rules match canonical types; keep the type Scaleway sent in raw
+	eventType := i.types.normalize(scalewayEvent.Type)
+	if eventType != scalewayEvent.Type {
+		scalewayEvent.Raw[originalEventTypeKey] = scalewayEvent.Type
+	}
+
+	// Rules group by actor, so fold case variants of an email into one
+	actor := normalizeActor(scalewayEvent.Actor)
+	if actor != scalewayEvent.Actor {
+		scalewayEvent.Raw[originalActorKey] = scalewayEvent.Actor
+	}
+
+	// Convert Scaleway event to ingestion event
+	event := &Event{
+		EventID:   scalewayEvent.ID,
+		Raw:       scalewayEvent.Raw,
+		EventType: eventType,
+		Actor:     actor,
+		Resource:  scalewayEvent.Resource,
+		IP:        scalewayEvent.IP,
+		Timestamp: scalewayEvent.Timestamp,
+	}
+
+	// Enrich event (geo IP, etc.)
+	enrichedEvent := i.enrichEvent(event)
+
+	// Convert to models.Event for storage
+	modelEvent := &models.Event{
+		ID:             uuid.New(),
+		EventID:        enrichedEvent.EventID,
+		Raw:            enrichedEvent.Raw,
+		EventType:      enrichedEvent.EventType,
+		Actor:          enrichedEvent.Actor,
+		Resource:       enrichedEvent.Resource,
+		IP:             enrichedEvent.IP,
+		IPScope:        enrichedEvent.IPScope,
+		Region:         enrichedEvent.Region,
+		ProjectID:      scalewayEvent.Tenant.ProjectID,
+		OrganizationID: scalewayEvent.Tenant.OrganizationID,
+		Timestamp:      enrichedEvent.Timestamp,
+		IngestFailed:   false,
+		CreatedAt:      time.Now(),
+	}
+
+	i.markFirstSeen(ctx, modelEvent)
+
+	// Store event
+	if err := i.repository.StoreEvent(ctx, modelEvent); err != nil {
+		return false, fmt.Errorf("failed to store event: %w", err)
+	}
+
+	// Process event through detection engine (if available)
+	if i.processor != nil {
+		if err := i.processor.ProcessEvent(ctx, modelEvent); err != nil {
+			i.logs.Printf("detection", "Failed to process event %s through detection: %v", scalewayEvent.ID, err)
+			// Continue even if detection fails
+		}
+	}
+
+	return true, nil
+}
+
 // enrichEvent enriches event with additional data (geo IP, etc.)
 func (i *Ingestor) enrichEvent(event *Event) *Event {
+	if scope := classifyIP(event.IP); scope != "" {
+		event.IPScope = scope
+		event.Raw[ipScopeKey] = scope
+	}
+	if i.reputation != nil && event.IP != "" {
+		if match, ok := i.reputation.Lookup(event.IP); ok {
+			event.Raw[models.RawThreatIntel] = map[string]any{
+				"source":        match.Source,
+				"matched_entry": match.Entry,
+			}
+		}
+	}
 
 	return event
 }
@@ -0,0 +1,286 @@
+package ingestion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/scaleway/audit-sentinel/internal/config"
+	"github.com/scaleway/audit-sentinel/internal/models"
+	"github.com/scaleway/audit-sentinel/pkg/scaleway"
+)
+
+// slowFetch returns a fetch that waits delay, like an API round trip, before
+// returning events or err
+func slowFetch(delay time.Duration, events []*scaleway.AuditEvent, err error) fetchFunc {
+	return func(ctx context.Context) ([]*scaleway.AuditEvent, error) {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		return events, err
+	}
+}
+
+func TestFetchBothFailsOnEitherFetch(t *testing.T) {
+	fetchErr := errors.New("upstream unavailable")
+	for _, parallel := range []bool{false, true} {
+		i := &Ingestor{config: &config.Config{Ingestion: config.IngestionConfig{ParallelFetch: parallel}}}
+		events := []*scaleway.AuditEvent{{ID: "evt-1"}}
+
+		_, _, err := i.fetchBoth(context.Background(), slowFetch(0, events, nil), slowFetch(0, nil, fetchErr))
+		if !errors.Is(err, fetchErr) {
+			t.Errorf("parallel=%v: error = %v, want the authentication fetch error", parallel, err)
+		}
+
+		audit, auth, err := i.fetchBoth(context.Background(), slowFetch(0, events, nil), slowFetch(0, events, nil))
+		if err != nil || len(audit) != 1 || len(auth) != 1 {
+			t.Errorf("parallel=%v: got %d audit and %d auth events (%v), want 1 each", parallel, len(audit), len(auth), err)
+		}
+	}
+}
+
+// BenchmarkFetchBoth compares fetching the audit and authentication events
+// one after the other with fetching them concurrently
+func BenchmarkFetchBoth(b *testing.B) {
+	fetch := slowFetch(time.Millisecond, []*scaleway.AuditEvent{{ID: "evt-1"}}, nil)
+	for _, bench := range []struct {
+		name     string
+		parallel bool
+	}{
+		{name: "sequential"},
+		{name: "parallel", parallel: true},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			i := &Ingestor{config: &config.Config{Ingestion: config.IngestionConfig{ParallelFetch: bench.parallel}}}
+			ctx := context.Background()
+			for n := 0; n < b.N; n++ {
+				if _, _, err := i.fetchBoth(ctx, fetch, fetch); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestIngestRejectsOverlappingCycles(t *testing.T) {
+	// The first audit fetch blocks until released, holding its cycle open
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var auditFetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/audit/") && auditFetches.Add(1) == 1 {
+			close(started)
+			<-release
+		}
+		fmt.Fprint(w, `{"events": [], "login_logs": []}`)
+	}))
+	defer server.Close()
+	releaseOnce := sync.OnceFunc(func() { close(release) })
+	defer releaseOnce()
+	ingestor := newTestIngestor(server.URL, &fakeRepository{})
+
+	results := make(chan error, 2)
+	for n := 0; n < 2; n++ {
+		go func() { results <- ingestor.Ingest(context.Background()) }()
+	}
+
+	<-started
+	select {
+	case err := <-results:
+		if !errors.Is(err, ErrIngestionRunning) {
+			t.Fatalf("overlapping Ingest = %v, want ErrIngestionRunning", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("overlapping Ingest did not return while the first cycle was running")
+	}
+	if !ingestor.Stats().Running {
+		t.Error("stats do not report the running cycle")
+	}
+
+	releaseOnce()
+	if err := <-results; err != nil {
+		t.Fatalf("first Ingest: %v", err)
+	}
+	if n := auditFetches.Load(); n != 1 {
+		t.Errorf("made %d audit fetches, want 1 from the single cycle that ran", n)
+	}
+	if ingestor.Stats().Running {
+		t.Error("stats still report a running cycle")
+	}
+	if err := ingestor.Ingest(context.Background()); err != nil {
+		t.Errorf("Ingest after the cycle finished: %v", err)
+	}
+}
+
+func TestIngestTagsEventsWithTheirTenant(t *testing.T) {
+	now := time.Now().UTC().Add(-time.Hour)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		project := r.URL.Query().Get("project_id")
+		if !strings.HasPrefix(r.URL.Path, "/iam/") {
+			fmt.Fprint(w, `{"events": []}`)
+			return
+		}
+		// Each tenant sees the same actor fail to log in
+		fmt.Fprintf(w, `{"login_logs": [
+			{"id": "%[1]s-1", "event_type": "auth.failed", "actor": "alice@example.com", "ip": "203.0.113.7", "timestamp": %[2]q},
+			{"id": "%[1]s-2", "event_type": "auth.failed", "actor": "alice@example.com", "ip": "203.0.113.7", "timestamp": %[2]q}
+		]}`, project, now.Format(time.RFC3339))
+	}))
+	defer server.Close()
+
+	repo := &fakeRepository{}
+	ingestor := newTestIngestor(server.URL, repo, "project-a:org-1", "project-b:org-1")
+	if err := ingestor.Ingest(context.Background()); err != nil {
+		t.Fatalf("Ingest: %v", err)
+	}
+
+	perProject := map[string]int{}
+	for _, event := range repo.stored() {
+		if !strings.HasPrefix(event.EventID, event.ProjectID+"-") || event.OrganizationID != "org-1" {
+			t.Errorf("event %s tagged %s/%s, want its own tenant", event.EventID, event.ProjectID, event.OrganizationID)
+		}
+		perProject[event.ProjectID]++
+	}
+	if perProject["project-a"] != 2 || perProject["project-b"] != 2 {
+		t.Errorf("stored events per project = %v, want 2 each", perProject)
+	}
+}
+
+func TestIngestEventFoldsActorCase(t *testing.T) {
+	repo := &fakeRepository{}
+	ingestor := newTestIngestor("http://127.0.0.1:0", repo)
+	now := time.Now().UTC()
+
+	for idx, actor := range []string{"User@Example.com", "user@example.com", " USER@EXAMPLE.COM ", "other@example.com"} {
+		event := auditEvent(fmt.Sprintf("evt-%d", idx), "login.failed", actor, "203.0.113.7", "project-1", now)
+		if _, err := ingestor.IngestEvent(context.Background(), event); err != nil {
+			t.Fatalf("IngestEvent: %v", err)
+		}
+	}
+
+	failures := map[string]int{}
+	for _, event := range repo.stored() {
+		if event.EventType == "auth.failed" {
+			failures[event.Actor]++
+		}
+	}
+	if failures["user@example.com"] != 3 || len(failures) != 2 {
+		t.Errorf("failed logins per actor = %v, want the 3 case variants counted together", failures)
+	}
+
+	stored := repo.stored()
+	if got := stored[0].Raw[originalActorKey]; got != "User@Example.com" {
+		t.Errorf("original actor = %v, want User@Example.com preserved", got)
+	}
+	if _, ok := stored[1].Raw[originalActorKey]; ok {
+		t.Error("original actor recorded for an already normalized actor")
+	}
+}
+
+func TestIngestEventMarksFirstSeen(t *testing.T) {
+	repo := &fakeRepository{}
+	ingestor := newTestIngestor("http://127.0.0.1:0", repo)
+	now := time.Now().UTC()
+
+	events := []struct {
+		actor, ip                   string
+		wantActorFirst, wantIPFirst bool
+	}{
+		{actor: "alice@example.com", ip: "203.0.113.7", wantActorFirst: true, wantIPFirst: true},
+		{actor: "alice@example.com", ip: "203.0.113.7"},
+		{actor: "alice@example.com", ip: "198.51.100.2", wantIPFirst: true},
+		{actor: "bob@example.com", ip: "203.0.113.7", wantActorFirst: true},
+		{actor: "bob@example.com", ip: "198.51.100.2"},
+	}
+	for idx, tt := range events {
+		event := auditEvent(fmt.Sprintf("evt-%d", idx), "auth.success", tt.actor, tt.ip, "project-1", now)
+		if _, err := ingestor.IngestEvent(context.Background(), event); err != nil {
+			t.Fatalf("IngestEvent: %v", err)
+		}
+		stored := repo.stored()[idx]
+		if got := stored.Raw[models.RawActorFirstSeen] == true; got != tt.wantActorFirst {
+			t.Errorf("event %d by %s: actor first seen = %v, want %v", idx, tt.actor, got, tt.wantActorFirst)
+		}
+		if got := stored.Raw[models.RawIPFirstSeen] == true; got != tt.wantIPFirst {
+			t.Errorf("event %d from %s: IP first seen = %v, want %v", idx, tt.ip, got, tt.wantIPFirst)
+		}
+	}
+}
+
+func TestIngestEventMarksFirstSeenPerTenant(t *testing.T) {
+	repo := &fakeRepository{}
+	ingestor := newTestIngestor("http://127.0.0.1:0", repo)
+	now := time.Now().UTC()
+
+	// The same actor and IP in a second project are new to that project
+	for idx, project := range []string{"project-a", "project-a", "project-b"} {
+		event := auditEvent(fmt.Sprintf("evt-%d", idx), "auth.success", "alice@example.com", "203.0.113.7", project, now)
+		if _, err := ingestor.IngestEvent(context.Background(), event); err != nil {
+			t.Fatalf("IngestEvent: %v", err)
+		}
+	}
+
+	for idx, want := range []bool{true, false, true} {
+		raw := repo.stored()[idx].Raw
+		if got := raw[models.RawActorFirstSeen] == true; got != want {
+			t.Errorf("event %d: actor first seen = %v, want %v", idx, got, want)
+		}
+		if got := raw[models.RawIPFirstSeen] == true; got != want {
+			t.Errorf("event %d: IP first seen = %v, want %v", idx, got, want)
+		}
+	}
+}
+
+// seenErrRepository fails every first-seen lookup
+type seenErrRepository struct {
+	fakeRepository
+}
+
+func (r *seenErrRepository) SeenBefore(ctx context.Context, projectID, actor, ip string) (bool, bool, error) {
+	return false, false, errors.New("connection refused")
+}
+
+func TestIngestEventLeavesFirstSeenUnsetOnError(t *testing.T) {
+	repo := &seenErrRepository{}
+	ingestor := newTestIngestor("http://127.0.0.1:0", repo)
+
+	event := auditEvent("evt-1", "auth.success", "alice@example.com", "203.0.113.7", "project-1", time.Now())
+	if _, err := ingestor.IngestEvent(context.Background(), event); err != nil {
+		t.Fatalf("IngestEvent: %v", err)
+	}
+	raw := repo.stored()[0].Raw
+	if _, ok := raw[models.RawActorFirstSeen]; ok {
+		t.Error("actor first seen set although the lookup failed")
+	}
+	if _, ok := raw[models.RawIPFirstSeen]; ok {
+		t.Error("IP first seen set although the lookup failed")
+	}
+}
+
+func TestIngestEventKeepsOriginalType(t *testing.T) {
+	repo := &fakeRepository{}
+	ingestor := newTestIngestor("http://127.0.0.1:0", repo)
+
+	for idx, eventType := range []string{"LoginFailed", "auth.failed"} {
+		event := auditEvent(fmt.Sprintf("evt-%d", idx), eventType, "alice@example.com", "203.0.113.7", "project-1", time.Now())
+		if _, err := ingestor.IngestEvent(context.Background(), event); err != nil {
+			t.Fatalf("IngestEvent: %v", err)
+		}
+	}
+	stored := repo.stored()
+	if stored[0].EventType != "auth.failed" || stored[0].Raw[originalEventTypeKey] != "LoginFailed" {
+		t.Errorf("stored %q with original %v, want auth.failed keeping LoginFailed", stored[0].EventType, stored[0].Raw[originalEventTypeKey])
+	}
+	if _, ok := stored[1].Raw[originalEventTypeKey]; ok {
+		t.Error("original type recorded for an already canonical type")
+	}
+}
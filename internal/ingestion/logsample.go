@@ -0,0 +1,68 @@
+package ingestion
+
+import (
+	"log"
+	"sort"
+	"sync"
+)
+
+// logSampler rate-limits per-event log lines. For each key it logs the
+// leading lines, then only one in every few, and counts the rest so Flush
+// can summarize them. A failure hitting every event of an outage then costs a
+// handful of lines instead of one per event.
+type logSampler struct {
+	first int
+	every int
+
+	mu         sync.Mutex
+	seen       map[string]int
+	suppressed map[string]int
+}
+
+// newLogSampler creates a sampler; first <= 0 disables sampling and every
+// <= 0 suppresses everything past the first messages
+func newLogSampler(first, every int) *logSampler {
+	return &logSampler{
+		first:      first,
+		every:      every,
+		seen:       map[string]int{},
+		suppressed: map[string]int{},
+	}
+}
+
+// Printf logs like log.Printf unless the key is past its sample
+func (s *logSampler) Printf(key, format string, args ...any) {
+	if s.first > 0 {
+		s.mu.Lock()
+		s.seen[key]++
+		n := s.seen[key]
+		keep := n <= s.first || (s.every > 0 && (n-s.first)%s.every == 0)
+		if !keep {
+			s.suppressed[key]++
+		}
+		s.mu.Unlock()
+		if !keep {
+			return
+		}
+	}
+	log.Printf(format, args...)
+}
+
+// Flush logs how many lines of each key were suppressed and starts a new
+// sample
+func (s *logSampler) Flush() {
+	s.mu.Lock()
+	suppressed := s.suppressed
+	s.seen = map[string]int{}
+	s.suppressed = map[string]int{}
+	s.mu.Unlock()
+
+	keys := make([]string, 0, len(suppressed))
+	for key := range suppressed {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		log.Printf("Suppressed %d more %q log lines", suppressed[key], key)
+	}
+}
@@ -0,0 +1,98 @@
+package ingestion
+
+import (
+	"strings"
+)
+
+// Canonical event types matched by the detection rules
+const (
+	EventTypeAuthFailed   = "auth.failed"
+	EventTypeAuthSuccess  = "auth.success"
+	EventTypeForbidden    = "forbidden"
+	EventTypeAPIKeyCreate = "apiKey.create"
+)
+
+// originalEventTypeKey is the raw payload key preserving a renamed event type
+const originalEventTypeKey = "original_event_type"
+
+// originalActorKey is the raw payload key preserving an actor changed by
+// normalizeActor
+const originalActorKey = "original_actor"
+
+// defaultEventTypes maps the type strings seen across Scaleway APIs, in
+// lower case, to their canonical type
+var defaultEventTypes = map[string]string{
+	"auth.failed":           EventTypeAuthFailed,
+	"auth.failure":          EventTypeAuthFailed,
+	"login.failed":          EventTypeAuthFailed,
+	"login.failure":         EventTypeAuthFailed,
+	"loginfailed":           EventTypeAuthFailed,
+	"authentication.failed": EventTypeAuthFailed,
+	"user.login.failed":     EventTypeAuthFailed,
+
+	"auth.success":           EventTypeAuthSuccess,
+	"auth.succeeded":         EventTypeAuthSuccess,
+	"login.success":          EventTypeAuthSuccess,
+	"login.succeeded":        EventTypeAuthSuccess,
+	"loginsucceeded":         EventTypeAuthSuccess,
+	"loginsuccess":           EventTypeAuthSuccess,
+	"authentication.success": EventTypeAuthSuccess,
+
+	"forbidden":         EventTypeForbidden,
+	"permission.denied": EventTypeForbidden,
+	"permissiondenied":  EventTypeForbidden,
+	"access.denied":     EventTypeForbidden,
+	"accessdenied":      EventTypeForbidden,
+
+	"apikey.create":      EventTypeAPIKeyCreate,
+	"apikey.created":     EventTypeAPIKeyCreate,
+	"api_key.create":     EventTypeAPIKeyCreate,
+	"api_key.created":    EventTypeAPIKeyCreate,
+	"iam.api_key.create": EventTypeAPIKeyCreate,
+	"apikeycreated":      EventTypeAPIKeyCreate,
+	"createapikey":       EventTypeAPIKeyCreate,
+}
+
+// typeNormalizer maps raw event types to canonical ones. Lookups ignore
+// case; unknown types are kept as they are.
+type typeNormalizer struct {
+	types map[string]string
+}
+
+// newTypeNormalizer builds a normalizer from the default table plus
+// mappings of the form "raw=canonical", which take precedence. Malformed
+// mappings are rejected by config validation and ignored here.
+func newTypeNormalizer(mappings []string) *typeNormalizer {
+	types := make(map[string]string, len(defaultEventTypes)+len(mappings))
+	for raw, canonical := range defaultEventTypes {
+		types[raw] = canonical
+	}
+	for _, mapping := range mappings {
+		raw, canonical, ok := strings.Cut(mapping, "=")
+		raw, canonical = strings.TrimSpace(raw), strings.TrimSpace(canonical)
+		if !ok || raw == "" || canonical == "" {
+			continue
+		}
+		types[strings.ToLower(raw)] = canonical
+	}
+	return &typeNormalizer{types: types}
+}
+
+// normalize returns the canonical type for eventType
+func (n *typeNormalizer) normalize(eventType string) string {
+	if canonical, ok := n.types[strings.ToLower(strings.TrimSpace(eventType))]; ok {
+		return canonical
+	}
+	return eventType
+}
+
+// normalizeActor trims actor and lower-cases it if it is an email address, so
+// case variants of the same identity aggregate together. Other identifiers,
+// such as API access keys, keep their case.
+func normalizeActor(actor string) string {
+	actor = strings.TrimSpace(actor)
+	if strings.Contains(actor, "@") {
+		return strings.ToLower(actor)
+	}
+	return actor
+}
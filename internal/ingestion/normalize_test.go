@@ -0,0 +1,73 @@
+package ingestion
+
+import "testing"
+
+func TestNormalizeActor(t *testing.T) {
+	tests := []struct {
+		actor string
+		want  string
+	}{
+		{actor: "user@example.com", want: "user@example.com"},
+		{actor: "User@Example.COM", want: "user@example.com"},
+		{actor: "  USER@example.com\t", want: "user@example.com"},
+		{actor: "SCWXXXXXXXXXXXXXXXXX", want: "SCWXXXXXXXXXXXXXXXXX"},
+		{actor: " 6f1c2a9e-Api-Key ", want: "6f1c2a9e-Api-Key"},
+		{actor: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeActor(tt.actor); got != tt.want {
+			t.Errorf("normalizeActor(%q) = %q, want %q", tt.actor, got, tt.want)
+		}
+	}
+}
+
+func TestTypeNormalizer(t *testing.T) {
+	normalizer := newTypeNormalizer([]string{
+		"Custom.Login.Failure=auth.failed",
+		" policy.attach = iam.policy.attach ",
+		"login.failed=login.failed.override",
+		"no-separator",
+		"=auth.failed",
+		"orphan=",
+		"  =  ",
+	})
+
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		// Defaults, matched regardless of case and spacing
+		{raw: "auth.failed", want: EventTypeAuthFailed},
+		{raw: "LoginFailed", want: EventTypeAuthFailed},
+		{raw: " Authentication.Failed ", want: EventTypeAuthFailed},
+		{raw: "login.succeeded", want: EventTypeAuthSuccess},
+		{raw: "PermissionDenied", want: EventTypeForbidden},
+		{raw: "iam.api_key.create", want: EventTypeAPIKeyCreate},
+		// Configured mappings add types and override defaults
+		{raw: "custom.login.failure", want: EventTypeAuthFailed},
+		{raw: "POLICY.ATTACH", want: "iam.policy.attach"},
+		{raw: "login.failed", want: "login.failed.override"},
+		// Malformed mappings are ignored
+		{raw: "no-separator", want: "no-separator"},
+		{raw: "orphan", want: "orphan"},
+		// Unknown types are kept as sent
+		{raw: "Instance.Server.Create", want: "Instance.Server.Create"},
+		{raw: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := normalizer.normalize(tt.raw); got != tt.want {
+			t.Errorf("normalize(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestTypeNormalizerDefaults(t *testing.T) {
+	normalizer := newTypeNormalizer(nil)
+	for raw, canonical := range defaultEventTypes {
+		if got := normalizer.normalize(raw); got != canonical {
+			t.Errorf("normalize(%q) = %q, want %q", raw, got, canonical)
+		}
+	}
+}
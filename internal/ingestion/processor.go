@@ -11,6 +11,7 @@ import (
 // Processor handles event processing through detection engine
 type Processor struct {
 	detectionEngine *detection.Engine
+	retrier         *DetectionRetrier
 }
 
 // NewProcessor creates a new event processor
@@ -20,10 +21,18 @@ func NewProcessor(engine *detection.Engine) *Processor {
 	}
 }
 
+// SetRetrier queues events whose detection fails on retrier
+func (p *Processor) SetRetrier(retrier *DetectionRetrier) {
+	p.retrier = retrier
+}
+
 // ProcessEvent processes an event through the detection engine
 func (p *Processor) ProcessEvent(ctx context.Context, event *models.Event) error {
 	if err := p.detectionEngine.ProcessEvent(ctx, event); err != nil {
 		log.Printf("Failed to process event %s: %v", event.EventID, err)
+		if p.retrier != nil {
+			p.retrier.Queue(ctx, event, err)
+		}
 		return err
 	}
 	return nil
@@ -0,0 +1,148 @@
+package ingestion
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/scaleway/audit-sentinel/internal/detection"
+	"github.com/scaleway/audit-sentinel/internal/metrics"
+	"github.com/scaleway/audit-sentinel/internal/models"
+)
+
+// retryBatchSize bounds the failed detections retried per pass
+const retryBatchSize = 100
+
+// maxRetryDelay caps the exponential backoff between detection retries
+const maxRetryDelay = 24 * time.Hour
+
+// DetectionFailureStore persists events whose detection failed
+type DetectionFailureStore interface {
+	// MarkDetectionFailed records the rules that failed on the event after
+	// attempts tries; a nil retryAt dead-letters it
+	MarkDetectionFailed(ctx context.Context, id uuid.UUID, rules []string, attempts int, retryAt *time.Time) error
+	ClearDetectionFailure(ctx context.Context, id uuid.UUID) error
+	ListDueDetectionFailures(ctx context.Context, now time.Time, limit int) ([]*models.DetectionFailure, error)
+	CountDetectionFailures(ctx context.Context) (pending, dead int, err error)
+}
+
+// DetectionRetrier reruns the failed rules of events whose detection failed,
+// backing off exponentially and dead-lettering an event once it runs out of
+// attempts
+type DetectionRetrier struct {
+	engine      *detection.Engine
+	store       DetectionFailureStore
+	backoff     time.Duration
+	maxAttempts int
+}
+
+// NewDetectionRetrier creates a retrier giving each event up to maxAttempts
+// detection attempts in total
+func NewDetectionRetrier(engine *detection.Engine, store DetectionFailureStore, backoff time.Duration, maxAttempts int) *DetectionRetrier {
+	return &DetectionRetrier{
+		engine:      engine,
+		store:       store,
+		backoff:     backoff,
+		maxAttempts: maxAttempts,
+	}
+}
+
+// Start retries due detections every interval until ctx is cancelled
+func (r *DetectionRetrier) Start(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.RetryDue(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			log.Printf("Detection retry failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// RetryDue retries one batch of the detections whose retry is due, then
+// refreshes the failure metrics
+func (r *DetectionRetrier) RetryDue(ctx context.Context) error {
+	failures, err := r.store.ListDueDetectionFailures(ctx, time.Now(), retryBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, failure := range failures {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		r.retry(ctx, failure)
+	}
+
+	pending, dead, err := r.store.CountDetectionFailures(ctx)
+	if err != nil {
+		return err
+	}
+	metrics.DetectionFailures.WithLabelValues("pending").Set(float64(pending))
+	metrics.DetectionFailures.WithLabelValues("dead").Set(float64(dead))
+	return nil
+}
+
+// Queue records the first, failed detection attempt of event. Errors other
+// than a *detection.DetectionError name no rules to retry and are ignored.
+func (r *DetectionRetrier) Queue(ctx context.Context, event *models.Event, err error) {
+	var detectionErr *detection.DetectionError
+	if !errors.As(err, &detectionErr) {
+		return
+	}
+	r.record(ctx, event, detectionErr.Rules, 1, err)
+}
+
+// retry reruns the failed rules of one event and records the outcome
+func (r *DetectionRetrier) retry(ctx context.Context, failure *models.DetectionFailure) {
+	event := failure.Event
+	err := r.engine.RetryEvent(ctx, event, failure.Rules)
+	if err == nil {
+		if err := r.store.ClearDetectionFailure(ctx, event.ID); err != nil {
+			log.Printf("Failed to clear detection failure of event %s: %v", event.EventID, err)
+		}
+		log.Printf("Detection retry succeeded for event %s after %d attempts", event.EventID, failure.Attempts+1)
+		return
+	}
+
+	// Only the rules that failed again are retried next time
+	rules := failure.Rules
+	var detectionErr *detection.DetectionError
+	if errors.As(err, &detectionErr) {
+		rules = detectionErr.Rules
+	}
+	r.record(ctx, event, rules, failure.Attempts+1, err)
+}
+
+// record schedules the next retry of rules on event after attempts failed
+// attempts, or dead-letters the event if it has none left
+func (r *DetectionRetrier) record(ctx context.Context, event *models.Event, rules []string, attempts int, err error) {
+	var retryAt *time.Time
+	if attempts < r.maxAttempts {
+		next := time.Now().Add(retryDelay(r.backoff, attempts))
+		retryAt = &next
+	} else {
+		log.Printf("Detection for event %s failed %d times, dead-lettering it: %v", event.EventID, attempts, err)
+	}
+	if err := r.store.MarkDetectionFailed(ctx, event.ID, rules, attempts, retryAt); err != nil {
+		log.Printf("Failed to record detection failure of event %s: %v", event.EventID, err)
+	}
+}
+
+// retryDelay is the wait before the retry following attempt number attempts,
+// doubling backoff on every attempt up to maxRetryDelay
+func retryDelay(backoff time.Duration, attempts int) time.Duration {
+	delay := backoff
+	for n := 1; n < attempts && delay < maxRetryDelay; n++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
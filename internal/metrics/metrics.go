@@ -0,0 +1,91 @@
+// Package metrics defines the Prometheus metrics exported by Audit Sentinel.
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "audit_sentinel"
+
+var (
+	// RuleTimeouts counts rule evaluations cancelled for exceeding the rule timeout
+	RuleTimeouts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "detection",
+		Name:      "rule_timeouts_total",
+		Help:      "Number of rule evaluations cancelled for exceeding the rule timeout.",
+	}, []string{"rule"})
+
+	// RuleErrors counts rule evaluations that returned an error
+	RuleErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "detection",
+		Name:      "rule_errors_total",
+		Help:      "Number of rule evaluations that failed.",
+	}, []string{"rule"})
+
+	// SuppressedAlerts counts alerts dropped before storage, by reason
+	SuppressedAlerts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "detection",
+		Name:      "suppressed_alerts_total",
+		Help:      "Number of alerts suppressed instead of stored.",
+	}, []string{"alert_type", "reason"})
+
+	// MalformedEvents counts fetched entries dropped because they could not be parsed
+	MalformedEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "ingestion",
+		Name:      "malformed_events_total",
+		Help:      "Number of fetched entries skipped because they could not be parsed.",
+	}, []string{"source"})
+
+	// DroppedDetections counts stored events skipped by detection because
+	// the detection queue was full
+	DroppedDetections = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "ingestion",
+		Name:      "dropped_detections_total",
+		Help:      "Number of stored events not run through detection because the queue was full.",
+	})
+
+	// DetectionQueueDepth is the number of events waiting for a detection worker
+	DetectionQueueDepth = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "ingestion",
+		Name:      "detection_queue_depth",
+		Help:      "Number of stored events waiting for a detection worker.",
+	})
+
+	// DetectionFailures is the number of events whose detection failed, by
+	// state: pending a retry or dead-lettered
+	DetectionFailures = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "ingestion",
+		Name:      "detection_failures",
+		Help:      "Number of events whose detection failed, pending a retry or dead-lettered.",
+	}, []string{"state"})
+
+	// DatabaseUp is 1 when the latest database health check succeeded
+	DatabaseUp = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "database",
+		Name:      "up",
+		Help:      "Whether the latest database health check succeeded (1) or failed (0).",
+	})
+)
+
+// NewServer returns an HTTP server exposing /metrics on the given port
+func NewServer(port int) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	return &http.Server{
+		Addr:    fmt.Sprintf(":%d", port),
+		Handler: mux,
+	}
+}
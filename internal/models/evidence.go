@@ -0,0 +1,107 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrEvidenceMissing is returned by the Evidence getters for a key that is
+// absent, null or empty
+var ErrEvidenceMissing = errors.New("missing from alert evidence")
+
+// Evidence holds the details a rule recorded about an alert. It is stored as
+// JSON, so values read back from the database have JSON types (numbers are
+// float64); the getters accept both those and the Go types rules set.
+type Evidence map[string]any
+
+// GetString returns the string stored under key
+func (e Evidence) GetString(key string) (string, error) {
+	value, ok := e[key]
+	if !ok || value == nil {
+		return "", fmt.Errorf("evidence %q: %w", key, ErrEvidenceMissing)
+	}
+	str, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("evidence %q is a %T, not a string", key, value)
+	}
+	if str == "" {
+		return "", fmt.Errorf("evidence %q: %w", key, ErrEvidenceMissing)
+	}
+	return str, nil
+}
+
+// GetInt returns the integer stored under key
+func (e Evidence) GetInt(key string) (int, error) {
+	value, ok := e[key]
+	if !ok || value == nil {
+		return 0, fmt.Errorf("evidence %q: %w", key, ErrEvidenceMissing)
+	}
+	switch v := value.(type) {
+	case int:
+		return v, nil
+	case int64:
+		return int(v), nil
+	case float64:
+		if v == math.Trunc(v) {
+			return int(v), nil
+		}
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return int(n), nil
+		}
+	}
+	return 0, fmt.Errorf("evidence %q is a %T (%v), not an integer", key, value, value)
+}
+
+// GetStrings returns the list of strings stored under key
+func (e Evidence) GetStrings(key string) ([]string, error) {
+	value, ok := e[key]
+	if !ok || value == nil {
+		return nil, fmt.Errorf("evidence %q: %w", key, ErrEvidenceMissing)
+	}
+	switch v := value.(type) {
+	case []string:
+		return v, nil
+	case []any:
+		strs := make([]string, 0, len(v))
+		for _, item := range v {
+			str, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("evidence %q holds a %T, not a string", key, item)
+			}
+			strs = append(strs, str)
+		}
+		return strs, nil
+	}
+	return nil, fmt.Errorf("evidence %q is a %T, not a list of strings", key, value)
+}
+
+// GetBool returns the boolean stored under key
+func (e Evidence) GetBool(key string) (bool, error) {
+	value, ok := e[key]
+	if !ok || value == nil {
+		return false, fmt.Errorf("evidence %q: %w", key, ErrEvidenceMissing)
+	}
+	b, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("evidence %q is a %T, not a boolean", key, value)
+	}
+	return b, nil
+}
+
+// EvidenceDelta is what a duplicate of an open alert adds to its evidence
+type EvidenceDelta struct {
+	// Occurrences is added to the alert's occurrence count, which starts
+	// at 1 for the alert itself
+	Occurrences int
+	LastSeen    time.Time
+	// IPAddresses are added to the alert's set of IP addresses
+	IPAddresses []string
+	// EventRefs are appended to the alert's event references
+	EventRefs []uuid.UUID
+}
@@ -6,33 +6,80 @@ import (
 	"github.com/google/uuid"
 )
 
-// Event represents a Scaleway audit trail event
+// Event represents a Scaleway audit trail event. ProjectID and
+// OrganizationID identify the tenant it was ingested for.
 type Event struct {
-	ID           uuid.UUID      `json:"id" db:"id"`
-	EventID      string         `json:"event_id" db:"event_id"`
-	Raw          map[string]any `json:"raw" db:"raw"`
-	EventType    string         `json:"event_type" db:"event_type"`
-	Actor        string         `json:"actor" db:"actor"`
-	Resource     string         `json:"resource" db:"resource"`
-	IP           string         `json:"ip" db:"ip"`
-	Region       string         `json:"region" db:"region"`
-	Timestamp    time.Time      `json:"timestamp" db:"timestamp"`
-	IngestFailed bool           `json:"ingest_failed" db:"ingest_failed"`
-	CreatedAt    time.Time      `json:"created_at" db:"created_at"`
+	ID             uuid.UUID      `json:"id" db:"id"`
+	EventID        string         `json:"event_id" db:"event_id"`
+	Raw            map[string]any `json:"raw" db:"raw"`
+	EventType      string         `json:"event_type" db:"event_type"`
+	Actor          string         `json:"actor" db:"actor"`
+	Resource       string         `json:"resource" db:"resource"`
+	IP             string         `json:"ip" db:"ip"`
+	IPScope        string         `json:"ip_scope,omitempty" db:"ip_scope"`
+	Region         string         `json:"region" db:"region"`
+	ProjectID      string         `json:"project_id,omitempty" db:"project_id"`
+	OrganizationID string         `json:"organization_id,omitempty" db:"organization_id"`
+	Timestamp      time.Time      `json:"timestamp" db:"timestamp"`
+	IngestFailed   bool           `json:"ingest_failed" db:"ingest_failed"`
+	CreatedAt      time.Time      `json:"created_at" db:"created_at"`
+}
+
+// IP scopes assigned to events at ingestion
+const (
+	IPScopeInternal = "internal"
+	IPScopePublic   = "public"
+)
+
+// RawThreatIntel is the raw payload field holding the blocklist match for an
+// event's IP
+const RawThreatIntel = "threat_intel"
+
+// Raw payload fields set to true on the first event stored for an actor or
+// a source IP
+const (
+	RawActorFirstSeen = "actor_first_seen"
+	RawIPFirstSeen    = "ip_first_seen"
+)
+
+// DetectionFailure is an event whose detection failed for some rules and is
+// queued to be retried for them
+type DetectionFailure struct {
+	Event    *Event
+	Rules    []string
+	Attempts int
 }
 
 // Alert represents a security alert
 type Alert struct {
-	ID          uuid.UUID      `json:"id" db:"id"`
-	EventRefs   []uuid.UUID    `json:"event_refs" db:"event_refs"`
-	AlertType   string         `json:"alert_type" db:"alert_type"`
-	Severity    Severity       `json:"severity" db:"severity"`
-	UserID      string         `json:"user_id" db:"user_id"`
-	Description string         `json:"description" db:"description"`
-	Status      AlertStatus    `json:"status" db:"status"`
-	Evidence    map[string]any `json:"evidence" db:"evidence"`
-	CreatedAt   time.Time      `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at" db:"updated_at"`
+	ID          uuid.UUID   `json:"id" db:"id"`
+	EventRefs   []uuid.UUID `json:"event_refs" db:"event_refs"`
+	AlertType   string      `json:"alert_type" db:"alert_type"`
+	Severity    Severity    `json:"severity" db:"severity"`
+	UserID      string      `json:"user_id" db:"user_id"`
+	ProjectID   string      `json:"project_id,omitempty" db:"project_id"`
+	Description string      `json:"description" db:"description"`
+	Status      AlertStatus `json:"status" db:"status"`
+	Evidence    Evidence    `json:"evidence" db:"evidence"`
+	// AcknowledgedAt and AcknowledgedBy record who claimed the alert; they
+	// are independent of Status
+	AcknowledgedAt *time.Time `json:"acknowledged_at" db:"acknowledged_at"`
+	AcknowledgedBy string     `json:"acknowledged_by,omitempty" db:"acknowledged_by"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// Incident groups a user's alerts raised close together in time. It is
+// derived from alerts on read and not stored.
+type Incident struct {
+	UserID     string      `json:"user_id"`
+	ProjectID  string      `json:"project_id,omitempty"`
+	Severity   Severity    `json:"severity"`
+	StartedAt  time.Time   `json:"started_at"`
+	EndedAt    time.Time   `json:"ended_at"`
+	AlertCount int         `json:"alert_count"`
+	AlertIDs   []uuid.UUID `json:"alert_ids"`
+	AlertTypes []string    `json:"alert_types"`
 }
 
 // Severity represents alert severity level
@@ -55,6 +102,81 @@ const (
 	AlertStatusFalsePositive AlertStatus = "FALSE_POSITIVE"
 )
 
+// IsTerminal reports whether the status closes the alert; only reopening it
+// moves it on from there
+func (s AlertStatus) IsTerminal() bool {
+	return s == AlertStatusResolved || s == AlertStatusFalsePositive
+}
+
+// AlertStatusChange records one transition of an alert's status
+type AlertStatusChange struct {
+	ID         uuid.UUID   `json:"id" db:"id"`
+	AlertID    uuid.UUID   `json:"alert_id" db:"alert_id"`
+	FromStatus AlertStatus `json:"from_status" db:"from_status"`
+	ToStatus   AlertStatus `json:"to_status" db:"to_status"`
+	Actor      string      `json:"actor" db:"actor"`
+	Reason     string      `json:"reason,omitempty" db:"reason"`
+	At         time.Time   `json:"at" db:"at"`
+}
+
+// AlertMute suppresses new alerts of AlertType until ExpiresAt. An empty
+// UserID or Resource matches any value.
+type AlertMute struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	AlertType string    `json:"alert_type" db:"alert_type"`
+	UserID    string    `json:"user_id,omitempty" db:"user_id"`
+	Resource  string    `json:"resource,omitempty" db:"resource"`
+	Reason    string    `json:"reason,omitempty" db:"reason"`
+	CreatedBy string    `json:"created_by" db:"created_by"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	ExpiresAt time.Time `json:"expires_at" db:"expires_at"`
+}
+
+// MaintenanceMode selects what happens to alerts raised during maintenance
+type MaintenanceMode string
+
+const (
+	// MaintenanceSuppress drops alerts instead of storing them
+	MaintenanceSuppress MaintenanceMode = "suppress"
+	// MaintenanceTag stores alerts marked during_maintenance, without
+	// notifying anyone or remediating automatically
+	MaintenanceTag MaintenanceMode = "tag"
+)
+
+// EvidenceDuringMaintenance marks alerts raised inside a maintenance window
+const EvidenceDuringMaintenance = "during_maintenance"
+
+// EvidenceContext holds the snapshot of the user's recent activity taken when
+// the alert was raised
+const EvidenceContext = "context"
+
+// Evidence keys accumulated when duplicates are merged into an open alert
+const (
+	EvidenceOccurrences = "occurrences"
+	EvidenceLastSeen    = "last_seen"
+	EvidenceIPAddresses = "ip_addresses"
+)
+
+// MaintenanceWindow is a planned period of expected anomalous activity. An
+// empty AlertType applies the window to every alert type.
+type MaintenanceWindow struct {
+	ID        uuid.UUID       `json:"id" db:"id"`
+	StartsAt  time.Time       `json:"starts_at" db:"starts_at"`
+	EndsAt    time.Time       `json:"ends_at" db:"ends_at"`
+	AlertType string          `json:"alert_type,omitempty" db:"alert_type"`
+	Mode      MaintenanceMode `json:"mode" db:"mode"`
+	Reason    string          `json:"reason,omitempty" db:"reason"`
+	CreatedBy string          `json:"created_by" db:"created_by"`
+	CreatedAt time.Time       `json:"created_at" db:"created_at"`
+}
+
+// DuringMaintenance reports whether the alert was raised in a maintenance
+// window
+func (a *Alert) DuringMaintenance() bool {
+	during, _ := a.Evidence.GetBool(EvidenceDuringMaintenance)
+	return during
+}
+
 // RemediationLog represents a remediation action
 type RemediationLog struct {
 	ID         uuid.UUID      `json:"id" db:"id"`
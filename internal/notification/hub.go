@@ -0,0 +1,82 @@
+package notification
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/scaleway/audit-sentinel/internal/models"
+)
+
+// subscriberBuffer is the number of alerts queued for a slow subscriber
+// before further alerts to it are dropped
+const subscriberBuffer = 64
+
+// Hub fans stored alerts out to in-process subscribers such as live API
+// streams. Delivery is best effort: a subscriber that falls behind misses
+// alerts rather than blocking detection.
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[chan *models.Alert]struct{}
+	closed      bool
+}
+
+// NewHub creates an empty hub
+func NewHub() *Hub {
+	return &Hub{subscribers: make(map[chan *models.Alert]struct{})}
+}
+
+// Publish sends alert to every subscriber without blocking
+func (h *Hub) Publish(alert *models.Alert) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.subscribers {
+		select {
+		case ch <- alert:
+		default:
+			log.Printf("Dropping alert %s for slow stream subscriber", alert.ID)
+		}
+	}
+}
+
+// Subscribe returns a channel receiving alerts published from now on. The
+// channel is closed when ctx is done or the hub is closed.
+func (h *Hub) Subscribe(ctx context.Context) <-chan *models.Alert {
+	ch := make(chan *models.Alert, subscriberBuffer)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		close(ch)
+		return ch
+	}
+	h.subscribers[ch] = struct{}{}
+
+	go func() {
+		<-ctx.Done()
+		h.unsubscribe(ch)
+	}()
+	return ch
+}
+
+// unsubscribe removes and closes ch unless Close already did
+func (h *Hub) unsubscribe(ch chan *models.Alert) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subscribers[ch]; ok {
+		delete(h.subscribers, ch)
+		close(ch)
+	}
+}
+
+// Close disconnects all subscribers and rejects new ones
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for ch := range h.subscribers {
+		delete(h.subscribers, ch)
+		close(ch)
+	}
+}
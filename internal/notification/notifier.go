@@ -0,0 +1,172 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/scaleway/audit-sentinel/internal/models"
+	"github.com/scaleway/audit-sentinel/internal/storage"
+)
+
+const (
+	// pollInterval is how often the outbox is checked for due notifications
+	pollInterval = 5 * time.Second
+	// batchSize is the number of notifications delivered per outbox read
+	batchSize = 50
+	// baseBackoff and maxBackoff bound the delay before retrying a delivery
+	baseBackoff = 10 * time.Second
+	maxBackoff  = 30 * time.Minute
+)
+
+// Notifier delivers an alert to an external system
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, alert *models.Alert) error
+}
+
+// Outbox stores alert notifications awaiting delivery
+type Outbox interface {
+	PendingNotifications(ctx context.Context, limit int) ([]storage.OutboxEntry, error)
+	GetAlert(ctx context.Context, id uuid.UUID) (*models.Alert, error)
+	MarkNotificationSent(ctx context.Context, id uuid.UUID) error
+	MarkNotificationFailed(ctx context.Context, id uuid.UUID, nextAttempt time.Time, reason string) error
+}
+
+// Dispatcher delivers notifications queued in the outbox to notifiers in the
+// background, retrying failures with exponential backoff. Notifications are
+// delivered at least once: a crash between sending and marking an entry sent
+// causes it to be sent again. A single dispatcher should run per database.
+type Dispatcher struct {
+	outbox    Outbox
+	notifiers []Notifier
+	wake      chan struct{}
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewDispatcher creates a dispatcher and starts its delivery loop
+func NewDispatcher(outbox Outbox, notifiers ...Notifier) *Dispatcher {
+	d := &Dispatcher{
+		outbox:    outbox,
+		notifiers: notifiers,
+		wake:      make(chan struct{}, 1),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go d.run()
+	return d
+}
+
+// Publish signals that a new alert was stored with a pending notification,
+// so delivery does not wait for the next poll. The alert itself is read back
+// from the outbox.
+func (d *Dispatcher) Publish(alert *models.Alert) {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Close stops the delivery loop after a final pass over due notifications,
+// giving up when ctx expires. Undelivered notifications stay in the outbox
+// and are sent after the next start.
+func (d *Dispatcher) Close(ctx context.Context) error {
+	d.closeOnce.Do(func() {
+		close(d.stop)
+	})
+
+	select {
+	case <-d.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (d *Dispatcher) run() {
+	defer close(d.done)
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.stop:
+			d.deliverPending(context.Background())
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+		d.deliverPending(context.Background())
+	}
+}
+
+// deliverPending sends due notifications until the outbox has none left
+func (d *Dispatcher) deliverPending(ctx context.Context) {
+	for {
+		entries, err := d.outbox.PendingNotifications(ctx, batchSize)
+		if err != nil {
+			log.Printf("Failed to read notification outbox: %v", err)
+			return
+		}
+
+		for _, entry := range entries {
+			d.deliver(ctx, entry)
+		}
+
+		if len(entries) < batchSize {
+			return
+		}
+	}
+}
+
+// deliver sends one notification to every notifier, scheduling a retry of
+// the whole entry if any of them fails
+func (d *Dispatcher) deliver(ctx context.Context, entry storage.OutboxEntry) {
+	alert, err := d.outbox.GetAlert(ctx, entry.AlertID)
+	if err != nil {
+		d.fail(ctx, entry, err)
+		return
+	}
+
+	var failures []string
+	for _, notifier := range d.notifiers {
+		if err := notifier.Notify(ctx, alert); err != nil {
+			log.Printf("Notifier %s failed for alert %s: %v", notifier.Name(), alert.ID, err)
+			failures = append(failures, notifier.Name()+": "+err.Error())
+		}
+	}
+	if len(failures) > 0 {
+		d.fail(ctx, entry, errors.New(strings.Join(failures, "; ")))
+		return
+	}
+
+	if err := d.outbox.MarkNotificationSent(ctx, entry.ID); err != nil {
+		log.Printf("Failed to mark notification %s sent: %v", entry.ID, err)
+	}
+}
+
+func (d *Dispatcher) fail(ctx context.Context, entry storage.OutboxEntry, cause error) {
+	next := time.Now().Add(backoff(entry.Attempts))
+	if err := d.outbox.MarkNotificationFailed(ctx, entry.ID, next, cause.Error()); err != nil {
+		log.Printf("Failed to reschedule notification %s: %v", entry.ID, err)
+	}
+}
+
+// backoff returns the retry delay after the given number of failed attempts
+func backoff(attempts int) time.Duration {
+	delay := baseBackoff
+	for i := 0; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay
+}
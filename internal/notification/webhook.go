@@ -0,0 +1,111 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/scaleway/audit-sentinel/internal/models"
+)
+
+const (
+	// SignatureHeader carries the hex HMAC-SHA256 of the request body
+	SignatureHeader = "X-Audit-Sentinel-Signature"
+
+	webhookMaxAttempts = 3
+	webhookBaseBackoff = time.Second
+)
+
+// WebhookNotifier POSTs alerts as JSON to a generic HTTP endpoint
+type WebhookNotifier struct {
+	url        string
+	secret     string
+	httpClient *http.Client
+}
+
+// NewWebhookNotifier creates a webhook notifier. When secret is non-empty each
+// request is signed so the receiver can verify it came from us.
+func NewWebhookNotifier(url, secret string) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:    url,
+		secret: secret,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+func (n *WebhookNotifier) Name() string {
+	return "webhook"
+}
+
+// Notify sends the alert, retrying on network errors and 5xx responses
+func (n *WebhookNotifier) Notify(ctx context.Context, alert *models.Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("failed to marshal alert: %w", err)
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= webhookMaxAttempts; attempt++ {
+		retry, err := n.send(ctx, body)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retry || attempt == webhookMaxAttempts {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(webhookBaseBackoff * time.Duration(1<<(attempt-1))):
+		}
+	}
+
+	return fmt.Errorf("webhook delivery failed after retries: %w", lastErr)
+}
+
+// send performs a single delivery attempt and reports whether it is retryable
+func (n *WebhookNotifier) send(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.secret != "" {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(n.secret, body))
+	}
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("failed to post webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 500 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return true, fmt.Errorf("webhook returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+	if resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return false, fmt.Errorf("webhook returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	return false, nil
+}
+
+// Sign returns the hex-encoded HMAC-SHA256 of body using secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
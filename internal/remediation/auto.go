@@ -0,0 +1,78 @@
+package remediation
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/scaleway/audit-sentinel/internal/config"
+	"github.com/scaleway/audit-sentinel/internal/models"
+)
+
+// autoRemediationActor is recorded as the actor of automatic actions
+const autoRemediationActor = "auto-remediation"
+
+// AutoRemediator locks the users behind high-confidence alerts as soon as
+// they are raised, when AUTO_REMEDIATE is enabled
+type AutoRemediator struct {
+	config  *config.Config
+	service *Service
+}
+
+// NewAutoRemediator creates a new automatic remediator
+func NewAutoRemediator(cfg *config.Config, service *Service) *AutoRemediator {
+	return &AutoRemediator{
+		config:  cfg,
+		service: service,
+	}
+}
+
+// Remediate locks the alert's user if the alert qualifies. Only CRITICAL
+// alerts of an allowlisted type are acted on, exempt accounts are skipped,
+// and locks are withheld while LOCK_ACTION_CONFIRM requires a human.
+func (a *AutoRemediator) Remediate(ctx context.Context, alert *models.Alert) error {
+	cfg := a.config.Remediation
+	if !cfg.AutoRemediate || alert.Severity != models.SeverityCritical || alert.UserID == "" {
+		return nil
+	}
+	if !containsFold(cfg.AutoRemediateAlertTypes, alert.AlertType) {
+		return nil
+	}
+
+	if containsFold(cfg.AutoRemediateExempt, alert.UserID) {
+		log.Printf("Auto-remediation skipped for alert %s: %s is exempt", alert.ID, alert.UserID)
+		return nil
+	}
+	if a.config.Security.LockActionConfirm {
+		log.Printf("Auto-remediation skipped for alert %s: locking %s requires confirmation (LOCK_ACTION_CONFIRM)", alert.ID, alert.UserID)
+		return nil
+	}
+
+	reason := "automatic response to " + alert.AlertType + " alert"
+	if cfg.DryRun {
+		log.Printf("Auto-remediation dry run: would lock %s for alert %s", alert.UserID, alert.ID)
+		return a.service.repository.LogRemediation(ctx, &models.RemediationLog{
+			AlertID:    alert.ID,
+			ActorUser:  autoRemediationActor,
+			ActionType: models.ActionTypeLockUser,
+			Payload: map[string]any{
+				"user_id": alert.UserID,
+				"reason":  reason,
+			},
+			Result: "dry_run",
+		})
+	}
+
+	log.Printf("Auto-remediation: locking %s for alert %s (%s)", alert.UserID, alert.ID, alert.AlertType)
+	return a.service.LockUserWithAlert(ctx, alert.ID, alert.UserID, autoRemediationActor, reason)
+}
+
+// containsFold reports whether values contains value, ignoring case
+func containsFold(values []string, value string) bool {
+	for _, candidate := range values {
+		if strings.EqualFold(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+package remediation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/scaleway/audit-sentinel/internal/models"
+)
+
+// actionListAPIKeys names the key lookup step in a containment summary
+const actionListAPIKeys = "list_api_keys"
+
+// ContainmentResult summarizes a ContainUser run. IncidentID is also stored
+// in the payload of every remediation log written by the run.
+type ContainmentResult struct {
+	IncidentID string              `json:"incident_id"`
+	UserID     string              `json:"user_id"`
+	Actions    []ContainmentAction `json:"actions"`
+	Succeeded  int                 `json:"succeeded"`
+	Failed     int                 `json:"failed"`
+}
+
+// ContainmentAction is the outcome of one step of a containment. LogError is
+// set when the step ran but its remediation log could not be written.
+type ContainmentAction struct {
+	Action   string `json:"action"`
+	Target   string `json:"target"`
+	Result   string `json:"result"`
+	Error    string `json:"error,omitempty"`
+	LogError string `json:"log_error,omitempty"`
+}
+
+func (r *ContainmentResult) record(action, target string, err error) {
+	r.recordLogged(action, target, err, nil)
+}
+
+// recordLogged is record for a step whose remediation log failed with logErr
+func (r *ContainmentResult) recordLogged(action, target string, err, logErr error) {
+	step := ContainmentAction{Action: action, Target: target, Result: "success"}
+	if logErr != nil {
+		step.LogError = logErr.Error()
+	}
+	switch {
+	case errors.Is(err, ErrProtectedAccount):
+		step.Result = resultBlockedProtected
+		step.Error = err.Error()
+		r.Failed++
+	case err != nil:
+		step.Result = resultFailed
+		step.Error = err.Error()
+		r.Failed++
+	default:
+		r.Succeeded++
+	}
+	r.Actions = append(r.Actions, step)
+}
+
+// ContainUser locks a user and revokes all of their API keys, continuing past
+// individual failures. Every step is logged with a shared incident ID. It
+// fails with ErrProtectedAccount, without acting, if the user is protected.
+func (s *Service) ContainUser(ctx context.Context, userID, actor, reason string) (*ContainmentResult, error) {
+	incidentID := uuid.New().String()
+	payload := func(extra map[string]any) map[string]any {
+		p := map[string]any{
+			"user_id":     userID,
+			"reason":      reason,
+			"incident_id": incidentID,
+		}
+		for k, v := range extra {
+			p[k] = v
+		}
+		return p
+	}
+
+	if target, ok := s.protectedTarget(userID); ok {
+		return nil, s.blockProtected(ctx, &models.RemediationLog{
+			ActorUser:  actor,
+			ActionType: models.ActionTypeLockUser,
+			Payload:    payload(nil),
+		}, target)
+	}
+
+	result := &ContainmentResult{IncidentID: incidentID, UserID: userID}
+
+	lockErr := s.client.LockUser(ctx, userID)
+	lockLog := &models.RemediationLog{
+		ActorUser:  actor,
+		ActionType: models.ActionTypeLockUser,
+		Payload:    payload(nil),
+	}
+	// The lock has happened by now, so a failure to log it is reported in
+	// the result rather than abandoning the rest of the containment
+	var logErr error
+	if lockErr != nil {
+		s.logFailure(ctx, lockLog, lockErr)
+	} else {
+		lockLog.Result = "success"
+		if err := s.repository.LogRemediation(ctx, lockLog); err != nil {
+			logErr = fmt.Errorf("failed to log user lock: %w", err)
+			log.Printf("Containment %s: %v", incidentID, logErr)
+		}
+	}
+	result.recordLogged(string(models.ActionTypeLockUser), userID, lockErr, logErr)
+
+	keys, err := s.client.ListAPIKeys(ctx, userID)
+	if err != nil {
+		result.record(actionListAPIKeys, userID, err)
+		return result, nil
+	}
+
+	for _, key := range keys {
+		keyLog := &models.RemediationLog{
+			ActorUser:  actor,
+			ActionType: models.ActionTypeRevokeKey,
+			Payload:    payload(map[string]any{"key_id": key.AccessKey}),
+		}
+
+		if target, ok := s.protectedTarget(key.AccessKey); ok {
+			result.record(string(models.ActionTypeRevokeKey), key.AccessKey, s.blockProtected(ctx, keyLog, target))
+			continue
+		}
+
+		revokeErr := s.client.RevokeAPIKey(ctx, key.AccessKey)
+		var logErr error
+		if revokeErr != nil {
+			s.logFailure(ctx, keyLog, revokeErr)
+		} else {
+			keyLog.Result = "success"
+			if err := s.repository.LogRemediation(ctx, keyLog); err != nil {
+				logErr = fmt.Errorf("failed to log key revocation: %w", err)
+				log.Printf("Containment %s: %v", incidentID, logErr)
+			}
+		}
+		result.recordLogged(string(models.ActionTypeRevokeKey), key.AccessKey, revokeErr, logErr)
+	}
+
+	return result, nil
+}
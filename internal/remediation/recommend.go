@@ -0,0 +1,55 @@
+package remediation
+
+import (
+	"strings"
+
+	"github.com/scaleway/audit-sentinel/internal/models"
+)
+
+// noRecommendation in a mapping removes the default recommendation for an
+// alert type
+const noRecommendation = "none"
+
+// defaultRecommendedActions is the action suggested for each alert type
+// before any configured overrides
+var defaultRecommendedActions = map[string]models.ActionType{
+	"successful_login_after_brute_force": models.ActionTypeLockUser,
+	"forbidden_sensitive_resource":       models.ActionTypeLockUser,
+	"api_key_creation":                   models.ActionTypeRevokeKey,
+	"new_key_rapid_use":                  models.ActionTypeRevokeKey,
+}
+
+// Recommender suggests the remediation action to pre-select for an alert
+type Recommender struct {
+	actions map[string]models.ActionType
+}
+
+// NewRecommender builds a recommender from the default table plus mappings
+// of the form "alert_type=action", which take precedence. An action of
+// "none" drops the recommendation. Malformed mappings are rejected by config
+// validation and ignored here.
+func NewRecommender(mappings []string) *Recommender {
+	actions := make(map[string]models.ActionType, len(defaultRecommendedActions)+len(mappings))
+	for alertType, action := range defaultRecommendedActions {
+		actions[alertType] = action
+	}
+	for _, mapping := range mappings {
+		alertType, action, ok := strings.Cut(mapping, "=")
+		alertType, action = strings.TrimSpace(alertType), strings.TrimSpace(action)
+		if !ok || alertType == "" || action == "" {
+			continue
+		}
+		if action == noRecommendation {
+			delete(actions, alertType)
+			continue
+		}
+		actions[alertType] = models.ActionType(action)
+	}
+	return &Recommender{actions: actions}
+}
+
+// Recommend returns the action recommended for alerts of alertType, or ""
+// if there is none
+func (r *Recommender) Recommend(alertType string) models.ActionType {
+	return r.actions[alertType]
+}
@@ -2,7 +2,9 @@ package remediation
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 
 	"github.com/google/uuid"
 	"github.com/scaleway/audit-sentinel/internal/config"
@@ -10,6 +12,17 @@ import (
 	"github.com/scaleway/audit-sentinel/pkg/scaleway"
 )
 
+// ErrProtectedAccount is returned when a remediation targets an account on
+// the PROTECTED_ACCOUNTS safe-list
+var ErrProtectedAccount = errors.New("target is a protected account")
+
+const (
+	// resultFailed is the remediation log result for failed actions
+	resultFailed = "failed"
+	// resultBlockedProtected is the remediation log result for blocked actions
+	resultBlockedProtected = "blocked_protected"
+)
+
 // Service handles remediation actions
 type Service struct {
 	config     *config.Config
@@ -32,8 +45,70 @@ func NewService(cfg *config.Config, client *scaleway.Client, repo RemediationRep
 	}
 }
 
+// protectedTarget returns the first of targets on the protected safe-list
+func (s *Service) protectedTarget(targets ...string) (string, bool) {
+	for _, target := range targets {
+		if target != "" && containsFold(s.config.Remediation.ProtectedAccounts, target) {
+			return target, true
+		}
+	}
+	return "", false
+}
+
+// blockProtected logs a remediation refused because its target is protected
+// and returns the matching error
+func (s *Service) blockProtected(ctx context.Context, logEntry *models.RemediationLog, target string) error {
+	logEntry.Result = resultBlockedProtected
+	if err := s.repository.LogRemediation(ctx, logEntry); err != nil {
+		return fmt.Errorf("failed to log blocked remediation: %w", err)
+	}
+	return fmt.Errorf("%w: %s", ErrProtectedAccount, target)
+}
+
+// logFailure records a failed remediation, adding the failure details to
+// its payload under "error"
+func (s *Service) logFailure(ctx context.Context, logEntry *models.RemediationLog, err error) {
+	logEntry.Result = resultFailed
+	if logEntry.Payload == nil {
+		logEntry.Payload = map[string]any{}
+	}
+	logEntry.Payload["error"] = failureDetails(err)
+	if logErr := s.repository.LogRemediation(ctx, logEntry); logErr != nil {
+		log.Printf("Failed to log failed %s remediation: %v", logEntry.ActionType, logErr)
+	}
+}
+
+// failureDetails describes err for the remediation log, including the HTTP
+// status and Scaleway error code when the Scaleway API rejected the call
+func failureDetails(err error) map[string]any {
+	details := map[string]any{"message": err.Error()}
+
+	var apiErr *scaleway.APIError
+	if errors.As(err, &apiErr) {
+		details["status_code"] = apiErr.StatusCode
+		if apiErr.Code != "" {
+			details["scaleway_code"] = apiErr.Code
+		}
+		if apiErr.Message != "" {
+			details["scaleway_message"] = apiErr.Message
+		}
+	}
+	return details
+}
+
 // LockUser locks a user account via Scaleway IAM
 func (s *Service) LockUser(ctx context.Context, userID string, actor string, reason string) error {
+	if target, ok := s.protectedTarget(userID); ok {
+		return s.blockProtected(ctx, &models.RemediationLog{
+			ActorUser:  actor,
+			ActionType: models.ActionTypeLockUser,
+			Payload: map[string]any{
+				"user_id": userID,
+				"reason":  reason,
+			},
+		}, target)
+	}
+
 	// Call Scaleway IAM API to lock user
 	if err := s.client.LockUser(ctx, userID); err != nil {
 		// Log failed remediation attempt
@@ -44,9 +119,8 @@ func (s *Service) LockUser(ctx context.Context, userID string, actor string, rea
 				"user_id": userID,
 				"reason":  reason,
 			},
-			Result: fmt.Sprintf("failed: %v", err),
 		}
-		_ = s.repository.LogRemediation(ctx, log)
+		s.logFailure(ctx, log, err)
 		return fmt.Errorf("failed to lock user: %w", err)
 	}
 
@@ -76,9 +150,8 @@ func (s *Service) UnlockUser(ctx context.Context, userID string, actor string, r
 				"user_id": userID,
 				"reason":  reason,
 			},
-			Result: fmt.Sprintf("failed: %v", err),
 		}
-		_ = s.repository.LogRemediation(ctx, log)
+		s.logFailure(ctx, log, err)
 		return fmt.Errorf("failed to unlock user: %w", err)
 	}
 
@@ -98,6 +171,17 @@ func (s *Service) UnlockUser(ctx context.Context, userID string, actor string, r
 
 // RevokeAPIKey revokes an API key via Scaleway IAM
 func (s *Service) RevokeAPIKey(ctx context.Context, keyID string, actor string, reason string) error {
+	if target, ok := s.protectedTarget(keyID); ok {
+		return s.blockProtected(ctx, &models.RemediationLog{
+			ActorUser:  actor,
+			ActionType: models.ActionTypeRevokeKey,
+			Payload: map[string]any{
+				"key_id": keyID,
+				"reason": reason,
+			},
+		}, target)
+	}
+
 	// Call Scaleway IAM API to revoke key
 	if err := s.client.RevokeAPIKey(ctx, keyID); err != nil {
 		// Log failed remediation attempt
@@ -108,9 +192,8 @@ func (s *Service) RevokeAPIKey(ctx context.Context, keyID string, actor string,
 				"key_id": keyID,
 				"reason": reason,
 			},
-			Result: fmt.Sprintf("failed: %v", err),
 		}
-		_ = s.repository.LogRemediation(ctx, log)
+		s.logFailure(ctx, log, err)
 		return fmt.Errorf("failed to revoke API key: %w", err)
 	}
 
@@ -130,6 +213,19 @@ func (s *Service) RevokeAPIKey(ctx context.Context, keyID string, actor string,
 
 // LockUserWithAlert locks a user account and associates the action with an alert
 func (s *Service) LockUserWithAlert(ctx context.Context, alertID uuid.UUID, userID string, actor string, reason string) error {
+	if target, ok := s.protectedTarget(userID); ok {
+		return s.blockProtected(ctx, &models.RemediationLog{
+			ID:         uuid.New(),
+			AlertID:    alertID,
+			ActorUser:  actor,
+			ActionType: models.ActionTypeLockUser,
+			Payload: map[string]any{
+				"user_id": userID,
+				"reason":  reason,
+			},
+		}, target)
+	}
+
 	// Call Scaleway IAM API to lock user
 	if err := s.client.LockUser(ctx, userID); err != nil {
 		// Log failed remediation attempt
@@ -142,9 +238,8 @@ func (s *Service) LockUserWithAlert(ctx context.Context, alertID uuid.UUID, user
 				"user_id": userID,
 				"reason":  reason,
 			},
-			Result: fmt.Sprintf("failed: %v", err),
 		}
-		_ = s.repository.LogRemediation(ctx, log)
+		s.logFailure(ctx, log, err)
 		return fmt.Errorf("failed to lock user: %w", err)
 	}
 
@@ -178,9 +273,8 @@ func (s *Service) UnlockUserWithAlert(ctx context.Context, alertID uuid.UUID, us
 				"user_id": userID,
 				"reason":  reason,
 			},
-			Result: fmt.Sprintf("failed: %v", err),
 		}
-		_ = s.repository.LogRemediation(ctx, log)
+		s.logFailure(ctx, log, err)
 		return fmt.Errorf("failed to unlock user: %w", err)
 	}
 
@@ -200,8 +294,28 @@ func (s *Service) UnlockUserWithAlert(ctx context.Context, alertID uuid.UUID, us
 	return s.repository.LogRemediation(ctx, log)
 }
 
-// RevokeAPIKeyWithAlert revokes an API key and associates the action with an alert
+// RevokeAPIKeyWithAlert revokes an API key and associates the action with an
+// alert. The key is protected if either it or the alert's user is.
 func (s *Service) RevokeAPIKeyWithAlert(ctx context.Context, alertID uuid.UUID, keyID string, actor string, reason string) error {
+	// Fail closed: without the alert the key owner cannot be checked
+	// against the protected safe-list
+	alert, err := s.repository.GetAlert(ctx, alertID.String())
+	if err != nil {
+		return fmt.Errorf("failed to look up alert %s: %w", alertID, err)
+	}
+	if target, ok := s.protectedTarget(keyID, alert.UserID); ok {
+		return s.blockProtected(ctx, &models.RemediationLog{
+			ID:         uuid.New(),
+			AlertID:    alertID,
+			ActorUser:  actor,
+			ActionType: models.ActionTypeRevokeKey,
+			Payload: map[string]any{
+				"key_id": keyID,
+				"reason": reason,
+			},
+		}, target)
+	}
+
 	// Call Scaleway IAM API to revoke key
 	if err := s.client.RevokeAPIKey(ctx, keyID); err != nil {
 		// Log failed remediation attempt
@@ -214,9 +328,8 @@ func (s *Service) RevokeAPIKeyWithAlert(ctx context.Context, alertID uuid.UUID,
 				"key_id": keyID,
 				"reason": reason,
 			},
-			Result: fmt.Sprintf("failed: %v", err),
 		}
-		_ = s.repository.LogRemediation(ctx, log)
+		s.logFailure(ctx, log, err)
 		return fmt.Errorf("failed to revoke API key: %w", err)
 	}
 
@@ -0,0 +1,193 @@
+package remediation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/scaleway/audit-sentinel/internal/config"
+	"github.com/scaleway/audit-sentinel/internal/models"
+	"github.com/scaleway/audit-sentinel/pkg/scaleway"
+)
+
+// fakeRepository is an in-memory RemediationRepository
+type fakeRepository struct {
+	mu       sync.Mutex
+	alerts   map[string]*models.Alert
+	alertErr error
+	logs     []*models.RemediationLog
+	logErr   error
+}
+
+func (r *fakeRepository) LogRemediation(ctx context.Context, log *models.RemediationLog) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.logErr != nil {
+		return r.logErr
+	}
+	r.logs = append(r.logs, log)
+	return nil
+}
+
+func (r *fakeRepository) GetAlert(ctx context.Context, alertID string) (*models.Alert, error) {
+	if r.alertErr != nil {
+		return nil, r.alertErr
+	}
+	alert, ok := r.alerts[alertID]
+	if !ok {
+		return nil, errors.New("alert not found")
+	}
+	return alert, nil
+}
+
+// fakeIAM is an httptest server standing in for Scaleway IAM. It lists keys
+// for any user and records the keys revoked and users updated through it.
+type fakeIAM struct {
+	server  *httptest.Server
+	mu      sync.Mutex
+	keys    []string
+	revoked []string
+	locked  []string
+}
+
+func newFakeIAM(t *testing.T, keys ...string) *fakeIAM {
+	t.Helper()
+	iam := &fakeIAM{keys: keys}
+	iam.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		iam.mu.Lock()
+		defer iam.mu.Unlock()
+		switch r.Method {
+		case http.MethodGet:
+			list := []map[string]string{}
+			for _, key := range iam.keys {
+				list = append(list, map[string]string{"access_key": key})
+			}
+			json.NewEncoder(w).Encode(map[string]any{"api_keys": list, "total_count": len(list)})
+		case http.MethodPut:
+			iam.locked = append(iam.locked, path.Base(r.URL.Path))
+			w.Write([]byte(`{}`))
+		case http.MethodDelete:
+			iam.revoked = append(iam.revoked, path.Base(r.URL.Path))
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	t.Cleanup(iam.server.Close)
+	return iam
+}
+
+func newTestService(iam *fakeIAM, repo *fakeRepository, protected ...string) *Service {
+	cfg := &config.Config{Remediation: config.RemediationConfig{ProtectedAccounts: protected}}
+	client := scaleway.NewClient("test-secret-key", "project-1", "org-1", iam.server.URL)
+	return NewService(cfg, client, repo)
+}
+
+func TestRevokeAPIKeyWithAlert(t *testing.T) {
+	alertID := uuid.New()
+	tests := []struct {
+		name        string
+		protected   []string
+		alertErr    error
+		wantErr     error
+		wantRevoked bool
+		wantResult  string
+	}{
+		{
+			name:        "not protected",
+			wantRevoked: true,
+			wantResult:  "success",
+		},
+		{
+			name:       "protected key",
+			protected:  []string{"SCWKEY123"},
+			wantErr:    ErrProtectedAccount,
+			wantResult: resultBlockedProtected,
+		},
+		{
+			name:       "protected alert owner",
+			protected:  []string{"admin@example.com"},
+			wantErr:    ErrProtectedAccount,
+			wantResult: resultBlockedProtected,
+		},
+		{
+			name:     "alert lookup fails",
+			alertErr: errors.New("connection refused"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			iam := newFakeIAM(t)
+			repo := &fakeRepository{
+				alerts: map[string]*models.Alert{
+					alertID.String(): {ID: alertID, UserID: "admin@example.com"},
+				},
+				alertErr: tt.alertErr,
+			}
+			svc := newTestService(iam, repo, tt.protected...)
+
+			err := svc.RevokeAPIKeyWithAlert(context.Background(), alertID, "SCWKEY123", "analyst@example.com", "compromised")
+
+			switch {
+			case tt.alertErr != nil:
+				if !errors.Is(err, tt.alertErr) {
+					t.Errorf("error = %v, want the lookup error", err)
+				}
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("error = %v, want %v", err, tt.wantErr)
+				}
+			case err != nil:
+				t.Fatalf("RevokeAPIKeyWithAlert: %v", err)
+			}
+
+			if revoked := len(iam.revoked) > 0; revoked != tt.wantRevoked {
+				t.Errorf("key revoked = %v, want %v (requests %v)", revoked, tt.wantRevoked, iam.revoked)
+			}
+			if tt.wantResult == "" {
+				if len(repo.logs) != 0 {
+					t.Errorf("logged %d remediations, want none", len(repo.logs))
+				}
+				return
+			}
+			if len(repo.logs) != 1 || repo.logs[0].Result != tt.wantResult {
+				t.Fatalf("remediation logs = %+v, want one %q entry", repo.logs, tt.wantResult)
+			}
+			if repo.logs[0].AlertID != alertID {
+				t.Errorf("log alert ID = %s, want %s", repo.logs[0].AlertID, alertID)
+			}
+		})
+	}
+}
+
+func TestContainUserContinuesPastLogFailures(t *testing.T) {
+	iam := newFakeIAM(t, "SCWKEY1", "SCWKEY2")
+	repo := &fakeRepository{logErr: errors.New("connection refused")}
+	svc := newTestService(iam, repo)
+
+	result, err := svc.ContainUser(context.Background(), "user-1", "analyst@example.com", "account takeover")
+	if err != nil {
+		t.Fatalf("ContainUser: %v", err)
+	}
+
+	if len(iam.locked) != 1 || len(iam.revoked) != 2 {
+		t.Errorf("locked %v and revoked %v, want the user locked and both keys revoked", iam.locked, iam.revoked)
+	}
+	if result.Succeeded != 3 || result.Failed != 0 {
+		t.Errorf("result = %d succeeded, %d failed, want 3 and 0", result.Succeeded, result.Failed)
+	}
+	if len(result.Actions) != 3 {
+		t.Fatalf("got %d actions, want 3", len(result.Actions))
+	}
+	for _, action := range result.Actions {
+		if action.Result != "success" || action.LogError == "" {
+			t.Errorf("action %s on %s = %q with log error %q, want success with the log failure recorded",
+				action.Action, action.Target, action.Result, action.LogError)
+		}
+	}
+}
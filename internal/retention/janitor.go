@@ -0,0 +1,68 @@
+package retention
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/scaleway/audit-sentinel/internal/config"
+)
+
+// EventPurger defines the storage needed to purge old events
+type EventPurger interface {
+	DeleteEventsBefore(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// Janitor periodically purges events older than the configured retention
+type Janitor struct {
+	config *config.Config
+	events EventPurger
+}
+
+// NewJanitor creates a new retention janitor
+func NewJanitor(cfg *config.Config, events EventPurger) *Janitor {
+	return &Janitor{
+		config: cfg,
+		events: events,
+	}
+}
+
+// Enabled reports whether an event retention period is configured
+func (j *Janitor) Enabled() bool {
+	return j.config.Retention.EventRetentionDays > 0
+}
+
+// Start purges old events on every janitor interval until ctx is cancelled
+func (j *Janitor) Start(ctx context.Context) error {
+	ticker := time.NewTicker(j.config.Retention.JanitorInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := j.Purge(ctx); err != nil {
+			log.Printf("Retention purge failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge deletes events older than the retention period once and returns the
+// number of rows removed
+func (j *Janitor) Purge(ctx context.Context) (int64, error) {
+	if !j.Enabled() {
+		return 0, nil
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -j.config.Retention.EventRetentionDays)
+	deleted, err := j.events.DeleteEventsBefore(ctx, cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	log.Printf("Retention purge removed %d events older than %s", deleted, cutoff.Format(time.RFC3339))
+	return deleted, nil
+}
@@ -0,0 +1,20 @@
+package storage
+
+import "errors"
+
+var (
+	// ErrEventNotFound is returned when no event matches the lookup
+	ErrEventNotFound = errors.New("event not found")
+	// ErrAlertNotFound is returned when no alert matches the lookup
+	ErrAlertNotFound = errors.New("alert not found")
+	// ErrUserProfileNotFound is returned when a user has no profile yet
+	ErrUserProfileNotFound = errors.New("user profile not found")
+	// ErrInvalidStatusTransition is returned when an alert cannot move from
+	// its current status to the requested one
+	ErrInvalidStatusTransition = errors.New("invalid alert status transition")
+	// ErrMuteNotFound is returned when no alert mute matches the lookup
+	ErrMuteNotFound = errors.New("alert mute not found")
+	// ErrMaintenanceWindowNotFound is returned when no maintenance window
+	// matches the lookup
+	ErrMaintenanceWindowNotFound = errors.New("maintenance window not found")
+)
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/scaleway/audit-sentinel/internal/metrics"
+)
+
+// healthPingTimeout bounds each ping made by the health checker
+const healthPingTimeout = 2 * time.Second
+
+// HealthStatus is the outcome of the latest database health check
+type HealthStatus struct {
+	Healthy   bool
+	CheckedAt time.Time
+	Latency   time.Duration
+	Err       error
+}
+
+// HealthChecker pings the database on an interval and keeps the latest
+// result, logging each transition between healthy and unhealthy
+type HealthChecker struct {
+	db       *sql.DB
+	interval time.Duration
+
+	mu     sync.RWMutex
+	status HealthStatus
+	// downSince is when the database was first seen unhealthy in the
+	// current outage
+	downSince time.Time
+}
+
+// NewHealthChecker creates a health checker pinging db every interval
+func NewHealthChecker(db *sql.DB, interval time.Duration) *HealthChecker {
+	return &HealthChecker{
+		db:       db,
+		interval: interval,
+	}
+}
+
+// Start checks the database immediately and then on every interval until ctx
+// is cancelled
+func (h *HealthChecker) Start(ctx context.Context) error {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	for {
+		h.Check(ctx)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check pings the database once and records the result
+func (h *HealthChecker) Check(ctx context.Context) HealthStatus {
+	pingCtx, cancel := context.WithTimeout(ctx, healthPingTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := h.db.PingContext(pingCtx)
+	status := HealthStatus{
+		Healthy:   err == nil,
+		CheckedAt: time.Now(),
+		Latency:   time.Since(start),
+		Err:       err,
+	}
+
+	h.mu.Lock()
+	previous := h.status
+	h.status = status
+	downSince := h.downSince
+	switch {
+	case !status.Healthy && downSince.IsZero():
+		h.downSince = status.CheckedAt
+	case status.Healthy:
+		h.downSince = time.Time{}
+	}
+	h.mu.Unlock()
+
+	if status.Healthy {
+		metrics.DatabaseUp.Set(1)
+	} else {
+		metrics.DatabaseUp.Set(0)
+	}
+
+	switch {
+	case !status.Healthy && downSince.IsZero():
+		log.Printf("Database became unhealthy: %v", err)
+	case status.Healthy && !downSince.IsZero():
+		log.Printf("Database is healthy again after %s", status.CheckedAt.Sub(downSince).Round(time.Second))
+	case status.Healthy && previous.CheckedAt.IsZero():
+		log.Printf("Database is healthy")
+	}
+
+	return status
+}
+
+// Status returns the result of the latest check. CheckedAt is zero if no
+// check has run yet.
+func (h *HealthChecker) Status() HealthStatus {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.status
+}
@@ -3,18 +3,33 @@ package storage
 import (
 	"database/sql"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	_ "github.com/lib/pq"
+	"github.com/scaleway/audit-sentinel/migrations"
 )
 
+// defaultMigrationsDir is the on-disk directory new migrations are created in
+const defaultMigrationsDir = "migrations"
+
+// MigrationStatus describes whether a migration file has been applied
+type MigrationStatus struct {
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
 // Migrator handles database migrations
 type Migrator struct {
-	dbURL string
-	db    *sql.DB
+	dbURL  string
+	db     *sql.DB
+	source fs.FS  // where migration files are read from
+	dir    string // on-disk directory used by Create
 }
 
 // NewMigrator creates a new migrator instance
@@ -29,11 +44,20 @@ func NewMigrator(dbURL string) (*Migrator, error) {
 	}
 
 	return &Migrator{
-		dbURL: dbURL,
-		db:    db,
+		dbURL:  dbURL,
+		db:     db,
+		source: migrations.FS,
+		dir:    defaultMigrationsDir,
 	}, nil
 }
 
+// UseDirectory reads migrations from an on-disk directory instead of the set
+// embedded in the binary, which is handy while developing new migrations
+func (m *Migrator) UseDirectory(dir string) {
+	m.source = os.DirFS(dir)
+	m.dir = dir
+}
+
 // Close closes the database connection
 func (m *Migrator) Close() error {
 	if m.db != nil {
@@ -50,25 +74,13 @@ func (m *Migrator) Up() error {
 	}
 
 	// Get all migration files
-	migrationsDir := "migrations"
-	files, err := os.ReadDir(migrationsDir)
+	upFiles, err := m.listUpMigrations()
 	if err != nil {
-		return fmt.Errorf("failed to read migrations directory: %w", err)
+		return err
 	}
 
-	// Filter and sort migration files
-	var upFiles []string
-	for _, file := range files {
-		if strings.HasSuffix(file.Name(), ".up.sql") {
-			upFiles = append(upFiles, filepath.Join(migrationsDir, file.Name()))
-		}
-	}
-	sort.Strings(upFiles)
-
 	// Execute each migration
-	for _, file := range upFiles {
-		migrationName := filepath.Base(file)
-
+	for _, migrationName := range upFiles {
 		// Check if already applied
 		applied, err := m.isMigrationApplied(migrationName)
 		if err != nil {
@@ -80,20 +92,23 @@ func (m *Migrator) Up() error {
 		}
 
 		// Read migration file
-		sql, err := os.ReadFile(file)
+		migrationSQL, err := fs.ReadFile(m.source, migrationName)
 		if err != nil {
-			return fmt.Errorf("failed to read migration file %s: %w", file, err)
+			return fmt.Errorf("failed to read migration file %s: %w", migrationName, err)
 		}
 
-		// Execute migration
+		// Execute migration and record it atomically
 		fmt.Printf("Applying migration: %s\n", migrationName)
-		if _, err := m.db.Exec(string(sql)); err != nil {
-			return fmt.Errorf("failed to execute migration %s: %w", migrationName, err)
-		}
-
-		// Record migration
-		if err := m.recordMigration(migrationName); err != nil {
-			return fmt.Errorf("failed to record migration: %w", err)
+		if err := m.inTransaction(func(tx *sql.Tx) error {
+			if _, err := tx.Exec(string(migrationSQL)); err != nil {
+				return fmt.Errorf("failed to execute migration %s: %w", migrationName, err)
+			}
+			if _, err := tx.Exec("INSERT INTO schema_migrations (name) VALUES ($1)", migrationName); err != nil {
+				return fmt.Errorf("failed to record migration: %w", err)
+			}
+			return nil
+		}); err != nil {
+			return err
 		}
 
 		fmt.Printf("Migration %s applied successfully\n", migrationName)
@@ -102,13 +117,78 @@ func (m *Migrator) Up() error {
 	return nil
 }
 
+// Status lists every migration file and whether it has been applied
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	if err := m.createMigrationsTable(); err != nil {
+		return nil, fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	upFiles, err := m.listUpMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := m.db.Query("SELECT name, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[string]time.Time)
+	for rows.Next() {
+		var name string
+		var appliedAt time.Time
+		if err := rows.Scan(&name, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan migration record: %w", err)
+		}
+		applied[name] = appliedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate migration records: %w", err)
+	}
+
+	statuses := make([]MigrationStatus, 0, len(upFiles))
+	for _, name := range upFiles {
+		status := MigrationStatus{Name: name}
+		if appliedAt, ok := applied[name]; ok {
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// Version returns the name of the most recently applied migration, or an
+// empty string if none has been applied
+func (m *Migrator) Version() (string, error) {
+	if err := m.createMigrationsTable(); err != nil {
+		return "", fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	var name string
+	err := m.db.QueryRow(`
+		SELECT name FROM schema_migrations
+		ORDER BY applied_at DESC, name DESC
+		LIMIT 1
+	`).Scan(&name)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get current version: %w", err)
+	}
+	return name, nil
+}
+
 // Down rolls back the last migration
 func (m *Migrator) Down() error {
 	// Get the last applied migration
 	var lastMigration string
 	err := m.db.QueryRow(`
 		SELECT name FROM schema_migrations 
-		ORDER BY applied_at DESC 
+		ORDER BY applied_at DESC, name DESC
 		LIMIT 1
 	`).Scan(&lastMigration)
 	if err == sql.ErrNoRows {
@@ -118,38 +198,119 @@ func (m *Migrator) Down() error {
 		return fmt.Errorf("failed to get last migration: %w", err)
 	}
 
+	return m.rollback(lastMigration)
+}
+
+// DownTo rolls back every migration applied after target, leaving target
+// itself applied
+func (m *Migrator) DownTo(target string) error {
+	rows, err := m.db.Query(`
+		SELECT name FROM schema_migrations
+		ORDER BY applied_at DESC, name DESC
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to list applied migrations: %w", err)
+	}
+
+	var applied []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan migration record: %w", err)
+		}
+		applied = append(applied, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate migration records: %w", err)
+	}
+
+	// Collect the migrations newer than the target before touching anything,
+	// so an unknown target doesn't roll back the whole schema
+	var toRollback []string
+	found := false
+	for _, name := range applied {
+		if name == target {
+			found = true
+			break
+		}
+		toRollback = append(toRollback, name)
+	}
+	if !found {
+		return fmt.Errorf("target migration %s is not applied", target)
+	}
+
+	if len(toRollback) == 0 {
+		fmt.Printf("Already at migration %s, nothing to rollback\n", target)
+		return nil
+	}
+
+	for _, name := range toRollback {
+		if err := m.rollback(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rollback executes the down file for an applied migration and removes its
+// record in a single transaction
+func (m *Migrator) rollback(migrationName string) error {
 	// Find corresponding down file
-	migrationsDir := "migrations"
-	downFile := filepath.Join(migrationsDir, strings.Replace(lastMigration, ".up.sql", ".down.sql", 1))
+	downFile := strings.Replace(migrationName, ".up.sql", ".down.sql", 1)
 
 	// Check if down file exists
-	if _, err := os.Stat(downFile); os.IsNotExist(err) {
+	if _, err := fs.Stat(m.source, downFile); err != nil {
 		return fmt.Errorf("down migration file not found: %s", downFile)
 	}
 
 	// Read and execute down migration
-	sql, err := os.ReadFile(downFile)
+	migrationSQL, err := fs.ReadFile(m.source, downFile)
 	if err != nil {
 		return fmt.Errorf("failed to read down migration: %w", err)
 	}
 
-	fmt.Printf("Rolling back migration: %s\n", lastMigration)
-	if _, err := m.db.Exec(string(sql)); err != nil {
-		return fmt.Errorf("failed to execute down migration: %w", err)
+	fmt.Printf("Rolling back migration: %s\n", migrationName)
+	if err := m.inTransaction(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(string(migrationSQL)); err != nil {
+			return fmt.Errorf("failed to execute down migration %s: %w", migrationName, err)
+		}
+		if _, err := tx.Exec("DELETE FROM schema_migrations WHERE name = $1", migrationName); err != nil {
+			return fmt.Errorf("failed to remove migration record: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return err
 	}
 
-	// Remove migration record
-	if _, err := m.db.Exec("DELETE FROM schema_migrations WHERE name = $1", lastMigration); err != nil {
-		return fmt.Errorf("failed to remove migration record: %w", err)
+	fmt.Printf("Migration %s rolled back successfully\n", migrationName)
+	return nil
+}
+
+// inTransaction runs fn inside a transaction, rolling back if it fails
+func (m *Migrator) inTransaction(fn func(tx *sql.Tx) error) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	if err := fn(tx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+		}
+		return err
 	}
 
-	fmt.Printf("Migration %s rolled back successfully\n", lastMigration)
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
 	return nil
 }
 
-// Create creates a new migration file
+// Create creates a new migration file in the on-disk migrations directory
 func (m *Migrator) Create(name string) error {
-	migrationsDir := "migrations"
+	migrationsDir := m.dir
 
 	// Get next migration number
 	nextNum := m.getNextMigrationNumber()
@@ -191,16 +352,26 @@ func (m *Migrator) isMigrationApplied(name string) (bool, error) {
 	return count > 0, nil
 }
 
-// recordMigration records that a migration has been applied
-func (m *Migrator) recordMigration(name string) error {
-	_, err := m.db.Exec("INSERT INTO schema_migrations (name) VALUES ($1)", name)
-	return err
+// listUpMigrations returns the sorted names of all up migration files
+func (m *Migrator) listUpMigrations() ([]string, error) {
+	files, err := fs.ReadDir(m.source, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	var upFiles []string
+	for _, file := range files {
+		if strings.HasSuffix(file.Name(), ".up.sql") {
+			upFiles = append(upFiles, file.Name())
+		}
+	}
+	sort.Strings(upFiles)
+	return upFiles, nil
 }
 
 // getNextMigrationNumber gets the next migration number
 func (m *Migrator) getNextMigrationNumber() string {
-	migrationsDir := "migrations"
-	files, err := os.ReadDir(migrationsDir)
+	files, err := os.ReadDir(m.dir)
 	if err != nil {
 		return "002"
 	}
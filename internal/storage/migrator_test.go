@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/scaleway/audit-sentinel/migrations"
+)
+
+// appliedMigrations returns the names of the applied migrations in order
+func appliedMigrations(t *testing.T, m *Migrator) []string {
+	t.Helper()
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	var applied []string
+	for _, status := range statuses {
+		if status.Applied {
+			applied = append(applied, status.Name)
+		}
+	}
+	return applied
+}
+
+// tableExists reports whether the named table exists in the public schema
+func tableExists(t *testing.T, m *Migrator, table string) bool {
+	t.Helper()
+	var exists bool
+	if err := m.db.QueryRow("SELECT to_regclass($1) IS NOT NULL", "public."+table).Scan(&exists); err != nil {
+		t.Fatalf("failed to look up table %s: %v", table, err)
+	}
+	return exists
+}
+
+func TestMigratorUpAndDownTo(t *testing.T) {
+	m := newTestMigrator(t)
+	all, err := fs.Glob(migrations.FS, "*.up.sql")
+	if err != nil || len(all) < 3 {
+		t.Fatalf("found %d embedded migrations (%v), want at least 3", len(all), err)
+	}
+
+	if err := m.Up(); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if applied := appliedMigrations(t, m); len(applied) != len(all) {
+		t.Fatalf("applied %d migrations, want all %d", len(applied), len(all))
+	}
+	if err := m.Up(); err != nil {
+		t.Fatalf("second Up: %v", err)
+	}
+
+	if err := m.DownTo("does_not_exist.up.sql"); err == nil {
+		t.Error("DownTo an unapplied target succeeded")
+	}
+	if applied := appliedMigrations(t, m); len(applied) != len(all) {
+		t.Fatalf("DownTo an unknown target left %d of %d migrations applied", len(applied), len(all))
+	}
+
+	if err := m.DownTo(all[0]); err != nil {
+		t.Fatalf("DownTo %s: %v", all[0], err)
+	}
+	if applied := appliedMigrations(t, m); len(applied) != 1 || applied[0] != all[0] {
+		t.Fatalf("applied after DownTo = %v, want only %s", applied, all[0])
+	}
+	if version, err := m.Version(); err != nil || version != all[0] {
+		t.Errorf("Version = %q (%v), want %s", version, err, all[0])
+	}
+	if err := m.DownTo(all[0]); err != nil {
+		t.Errorf("DownTo the current version: %v", err)
+	}
+
+	// The down files must leave a schema the up files apply to cleanly
+	if err := m.Up(); err != nil {
+		t.Fatalf("Up after DownTo: %v", err)
+	}
+	if err := m.Down(); err != nil {
+		t.Fatalf("Down: %v", err)
+	}
+	if applied := appliedMigrations(t, m); len(applied) != len(all)-1 {
+		t.Errorf("Down left %d migrations applied, want %d", len(applied), len(all)-1)
+	}
+}
+
+func TestMigratorUpRollsBackFailedMigration(t *testing.T) {
+	m := newTestMigrator(t)
+	dir := t.TempDir()
+	files := map[string]string{
+		"001_first.up.sql":    "CREATE TABLE first_table (id INT);",
+		"001_first.down.sql":  "DROP TABLE first_table;",
+		"002_broken.up.sql":   "CREATE TABLE partial_table (id INT);\nINSERT INTO missing_table VALUES (1);",
+		"002_broken.down.sql": "DROP TABLE partial_table;",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m.UseDirectory(dir)
+
+	err := m.Up()
+	if err == nil || !strings.Contains(err.Error(), "002_broken.up.sql") {
+		t.Fatalf("Up = %v, want the broken migration's error", err)
+	}
+	if applied := appliedMigrations(t, m); len(applied) != 1 || applied[0] != "001_first.up.sql" {
+		t.Errorf("applied = %v, want only the first migration recorded", applied)
+	}
+	if !tableExists(t, m, "first_table") {
+		t.Error("the migration before the failure was not kept")
+	}
+	if tableExists(t, m, "partial_table") {
+		t.Error("the failed migration left a partial schema")
+	}
+}
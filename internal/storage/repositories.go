@@ -4,11 +4,13 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/scaleway/audit-sentinel/internal/models"
 )
 
@@ -30,8 +32,9 @@ func (r *EventRepository) StoreEvent(ctx context.Context, event *models.Event) e
 	}
 
 	query := `
-		INSERT INTO events (id, event_id, raw, event_type, actor, resource, ip, region, timestamp, ingest_failed, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		INSERT INTO events (id, event_id, raw, event_type, actor, resource, ip, ip_scope, region,
+			project_id, organization_id, timestamp, ingest_failed, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), $9, NULLIF($10, ''), NULLIF($11, ''), $12, $13, $14)
 		ON CONFLICT (event_id) DO NOTHING
 	`
 
@@ -50,7 +53,10 @@ func (r *EventRepository) StoreEvent(ctx context.Context, event *models.Event) e
 		event.Actor,
 		event.Resource,
 		event.IP,
+		event.IPScope,
 		event.Region,
+		event.ProjectID,
+		event.OrganizationID,
 		event.Timestamp,
 		event.IngestFailed,
 		event.CreatedAt,
@@ -78,6 +84,162 @@ func (r *EventRepository) GetLastEventTimestamp(ctx context.Context) (*time.Time
 	return &timestamp.Time, nil
 }
 
+// GetLastTenantEventTimestamp gets the timestamp of the most recent event
+// ingested for the tenant, or nil if it has none
+func (r *EventRepository) GetLastTenantEventTimestamp(ctx context.Context, projectID, organizationID string) (*time.Time, error) {
+	var timestamp sql.NullTime
+	err := r.db.QueryRowContext(ctx, `
+		SELECT MAX(timestamp) FROM events
+		WHERE COALESCE(organization_id, '') = $1 AND COALESCE(project_id, '') = $2
+	`, organizationID, projectID).Scan(&timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last tenant event timestamp: %w", err)
+	}
+	if !timestamp.Valid {
+		return nil, nil
+	}
+	return &timestamp.Time, nil
+}
+
+// CountEventsSince counts events whose timestamp is at or after since
+func (r *EventRepository) CountEventsSince(ctx context.Context, since time.Time) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM events WHERE timestamp >= $1", since).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count events: %w", err)
+	}
+	return count, nil
+}
+
+// MarkDetectionFailed records that detection failed for rules on the event,
+// after attempts tries in total. It is retried at retryAt, or dead-lettered
+// if retryAt is nil.
+func (r *EventRepository) MarkDetectionFailed(ctx context.Context, id uuid.UUID, rules []string, attempts int, retryAt *time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE events
+		SET detection_failed_rules = $2, detection_attempts = $3, detection_retry_at = $4
+		WHERE id = $1
+	`, id, pq.Array(rules), attempts, retryAt)
+	if err != nil {
+		return fmt.Errorf("failed to mark detection failure: %w", err)
+	}
+	return nil
+}
+
+// ClearDetectionFailure removes the event from the detection retry queue
+func (r *EventRepository) ClearDetectionFailure(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE events
+		SET detection_failed_rules = NULL, detection_retry_at = NULL
+		WHERE id = $1
+	`, id)
+	if err != nil {
+		return fmt.Errorf("failed to clear detection failure: %w", err)
+	}
+	return nil
+}
+
+// ListDueDetectionFailures returns up to limit events whose detection retry
+// is due at now, oldest retry first
+func (r *EventRepository) ListDueDetectionFailures(ctx context.Context, now time.Time, limit int) ([]*models.DetectionFailure, error) {
+	query := `SELECT ` + eventColumns + `, detection_failed_rules, detection_attempts
+		FROM events
+		WHERE detection_failed_rules IS NOT NULL AND detection_retry_at <= $1
+		ORDER BY detection_retry_at
+		LIMIT $2`
+	rows, err := r.db.QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query detection failures: %w", err)
+	}
+	defer rows.Close()
+
+	failures := []*models.DetectionFailure{}
+	for rows.Next() {
+		var failure models.DetectionFailure
+		var err error
+		failure.Event, err = scanEvent(scanWithExtra{rows, []any{pq.Array(&failure.Rules), &failure.Attempts}})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan detection failure: %w", err)
+		}
+		failures = append(failures, &failure)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate detection failures: %w", err)
+	}
+	return failures, nil
+}
+
+// CountDetectionFailures returns how many events are waiting for a detection
+// retry and how many were dead-lettered after running out of attempts
+func (r *EventRepository) CountDetectionFailures(ctx context.Context) (pending, dead int, err error) {
+	err = r.db.QueryRowContext(ctx, `
+		SELECT
+			COUNT(*) FILTER (WHERE detection_retry_at IS NOT NULL),
+			COUNT(*) FILTER (WHERE detection_retry_at IS NULL)
+		FROM events
+		WHERE detection_failed_rules IS NOT NULL
+	`).Scan(&pending, &dead)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to count detection failures: %w", err)
+	}
+	return pending, dead, nil
+}
+
+// scanWithExtra scans a row of eventColumns followed by extra columns into
+// extra, so scanEvent can read rows selecting more than an event
+type scanWithExtra struct {
+	row   interface{ Scan(...any) error }
+	extra []any
+}
+
+func (s scanWithExtra) Scan(dest ...any) error {
+	return s.row.Scan(append(dest, s.extra...)...)
+}
+
+// DeleteEventsBefore deletes events older than cutoff and returns how many
+// were removed. Events referenced by an unresolved alert are kept so the
+// alert's evidence stays intact.
+func (r *EventRepository) DeleteEventsBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	query := `
+		DELETE FROM events e
+		WHERE e.timestamp < $1
+		AND NOT EXISTS (
+			SELECT 1 FROM alerts a
+			WHERE a.event_refs @> ARRAY[e.id]
+			AND a.status IN ($2, $3)
+		)
+	`
+
+	result, err := r.db.ExecContext(ctx, query, cutoff, models.AlertStatusOpen, models.AlertStatusInvestigating)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete events: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count deleted events: %w", err)
+	}
+	return deleted, nil
+}
+
+// DeleteByActor deletes every event of the actor and returns the number of
+// rows removed
+func (r *EventRepository) DeleteByActor(ctx context.Context, actor string) (int64, error) {
+	return deleteEventsByActor(ctx, r.db, actor)
+}
+
+func deleteEventsByActor(ctx context.Context, db execer, actor string) (int64, error) {
+	result, err := db.ExecContext(ctx, `DELETE FROM events WHERE actor = $1`, actor)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete events: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count deleted events: %w", err)
+	}
+	return deleted, nil
+}
+
 // EventExists checks if an event with the given event_id exists
 func (r *EventRepository) EventExists(ctx context.Context, eventID string) (bool, error) {
 	var count int
@@ -88,18 +250,161 @@ func (r *EventRepository) EventExists(ctx context.Context, eventID string) (bool
 	return count > 0, nil
 }
 
+// SeenBefore reports whether any event was stored in the project for actor
+// and for ip. An empty actor or ip counts as seen; an empty projectID
+// matches all projects.
+func (r *EventRepository) SeenBefore(ctx context.Context, projectID, actor, ip string) (actorSeen, ipSeen bool, err error) {
+	err = r.db.QueryRowContext(ctx, `
+		SELECT
+			$1::text = '' OR EXISTS (
+				SELECT 1 FROM events WHERE actor = $1 AND ($3::text = '' OR project_id = $3)),
+			$2::text = '' OR EXISTS (
+				SELECT 1 FROM events WHERE ip = $2 AND ($3::text = '' OR project_id = $3))
+	`, actor, ip, projectID).Scan(&actorSeen, &ipSeen)
+	if err != nil {
+		return false, false, fmt.Errorf("failed to check seen actor and IP: %w", err)
+	}
+	return actorSeen, ipSeen, nil
+}
+
 // GetEventByID retrieves an event by its database ID
 func (r *EventRepository) GetEventByID(ctx context.Context, id uuid.UUID) (*models.Event, error) {
-	var event models.Event
-	var rawJSON []byte
+	return r.getEvent(ctx, "id", id)
+}
+
+// GetEventByEventID retrieves an event by the ID assigned by its source,
+// such as a Scaleway audit log ID
+func (r *EventRepository) GetEventByEventID(ctx context.Context, eventID string) (*models.Event, error) {
+	return r.getEvent(ctx, "event_id", eventID)
+}
+
+// getEvent retrieves the event whose column equals value. column must be a
+// unique column name, never user input.
+func (r *EventRepository) getEvent(ctx context.Context, column string, value interface{}) (*models.Event, error) {
+	query := `SELECT ` + eventColumns + ` FROM events WHERE ` + column + ` = $1`
+
+	event, err := scanEvent(r.db.QueryRowContext(ctx, query, value))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrEventNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get event: %w", err)
+	}
+	return event, nil
+}
+
+// EventFilter holds the optional filters shared by event listing and export
+type EventFilter struct {
+	EventType string
+	Actor     string
+	IPScope   string
+	ProjectID string
+	From      *time.Time
+	To        *time.Time
+	Raw       []RawFilter
+	// OmitRaw leaves the raw payload of matching events unloaded; it does not
+	// affect which events match
+	OmitRaw bool
+}
+
+// RawOperator is a comparison applied to a field of the raw event payload
+type RawOperator string
+
+const (
+	// RawEquals matches when the field's text equals the value
+	RawEquals RawOperator = "eq"
+	// RawContains matches when the field's text contains the value, ignoring case
+	RawContains RawOperator = "contains"
+)
+
+// RawFilter matches a field of the raw JSONB payload, addressed by its path
+// of nested keys (e.g. ["user_info", "email"])
+type RawFilter struct {
+	Path     []string
+	Operator RawOperator
+	Value    string
+}
+
+// eventColumns is the column list scanned by scanEvent
+const eventColumns = `id, event_id, raw, event_type, actor, resource, ip, COALESCE(ip_scope, ''), region,
+	COALESCE(project_id, ''), COALESCE(organization_id, ''), timestamp, ingest_failed, created_at`
 
+// eventColumnsWithoutRaw is eventColumns with a NULL in place of the raw
+// payload, which scanEvent then leaves unset
+var eventColumnsWithoutRaw = strings.Replace(eventColumns, "raw,", "NULL::jsonb,", 1)
+
+// buildEventQuery builds the filtered SELECT for events and returns the query,
+// its arguments and the next free parameter position
+func buildEventQuery(filter EventFilter) (string, []interface{}, int) {
+	columns := eventColumns
+	if filter.OmitRaw {
+		columns = eventColumnsWithoutRaw
+	}
 	query := `
-		SELECT id, event_id, raw, event_type, actor, resource, ip, region, timestamp, ingest_failed, created_at
+		SELECT ` + columns + `
 		FROM events
-		WHERE id = $1
+		WHERE 1=1
 	`
+	args := []interface{}{}
+	argPos := 1
 
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
+	if filter.EventType != "" {
+		query += fmt.Sprintf(" AND event_type = $%d", argPos)
+		args = append(args, filter.EventType)
+		argPos++
+	}
+
+	if filter.Actor != "" {
+		query += fmt.Sprintf(" AND actor = $%d", argPos)
+		args = append(args, filter.Actor)
+		argPos++
+	}
+
+	if filter.IPScope != "" {
+		query += fmt.Sprintf(" AND ip_scope = $%d", argPos)
+		args = append(args, filter.IPScope)
+		argPos++
+	}
+
+	if filter.ProjectID != "" {
+		query += fmt.Sprintf(" AND project_id = $%d", argPos)
+		args = append(args, filter.ProjectID)
+		argPos++
+	}
+
+	if filter.From != nil {
+		query += fmt.Sprintf(" AND timestamp >= $%d", argPos)
+		args = append(args, *filter.From)
+		argPos++
+	}
+
+	if filter.To != nil {
+		query += fmt.Sprintf(" AND timestamp < $%d", argPos)
+		args = append(args, *filter.To)
+		argPos++
+	}
+
+	for _, raw := range filter.Raw {
+		switch raw.Operator {
+		case RawContains:
+			query += fmt.Sprintf(" AND (raw #>> $%d::text[]) ILIKE '%%' || $%d || '%%'", argPos, argPos+1)
+			args = append(args, pqTextArray(raw.Path), escapeLike(raw.Value))
+		default:
+			query += fmt.Sprintf(" AND (raw #>> $%d::text[]) = $%d", argPos, argPos+1)
+			args = append(args, pqTextArray(raw.Path), raw.Value)
+		}
+		argPos += 2
+	}
+
+	return query, args, argPos
+}
+
+// scanEvent scans a row selected with eventColumns or eventColumnsWithoutRaw
+func scanEvent(row interface{ Scan(...any) error }) (*models.Event, error) {
+	var event models.Event
+	var rawJSON []byte
+
+	err := row.Scan(
 		&event.ID,
 		&event.EventID,
 		&rawJSON,
@@ -107,48 +412,102 @@ func (r *EventRepository) GetEventByID(ctx context.Context, id uuid.UUID) (*mode
 		&event.Actor,
 		&event.Resource,
 		&event.IP,
+		&event.IPScope,
 		&event.Region,
+		&event.ProjectID,
+		&event.OrganizationID,
 		&event.Timestamp,
 		&event.IngestFailed,
 		&event.CreatedAt,
 	)
-	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("event not found")
-	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to get event: %w", err)
+		return nil, err
 	}
 
-	if err := json.Unmarshal(rawJSON, &event.Raw); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal raw event: %w", err)
+	if rawJSON != nil {
+		if err := json.Unmarshal(rawJSON, &event.Raw); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal raw event: %w", err)
+		}
 	}
 
 	return &event, nil
 }
 
-// ListEvents retrieves events with optional filters
-func (r *EventRepository) ListEvents(ctx context.Context, limit, offset int, eventType, actor string) ([]*models.Event, error) {
-	query := `
-		SELECT id, event_id, raw, event_type, actor, resource, ip, region, timestamp, ingest_failed, created_at
-		FROM events
-		WHERE 1=1
-	`
-	args := []interface{}{}
-	argPos := 1
+// TypeCount is the number of rows of one type
+type TypeCount struct {
+	Type  string `json:"type"`
+	Count int    `json:"count"`
+}
 
-	if eventType != "" {
-		query += fmt.Sprintf(" AND event_type = $%d", argPos)
-		args = append(args, eventType)
-		argPos++
+// countTypes returns the rows of table grouped by column, ordered by type.
+// table and column must be constants, never user input.
+func countTypes(ctx context.Context, db *sql.DB, table, column string) ([]TypeCount, error) {
+	query := fmt.Sprintf(`SELECT %s, COUNT(*) FROM %s GROUP BY %s ORDER BY %s`, column, table, column, column)
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count %s by %s: %w", table, column, err)
 	}
+	defer rows.Close()
 
-	if actor != "" {
-		query += fmt.Sprintf(" AND actor = $%d", argPos)
-		args = append(args, actor)
-		argPos++
+	counts := []TypeCount{}
+	for rows.Next() {
+		var count TypeCount
+		if err := rows.Scan(&count.Type, &count.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan %s count: %w", column, err)
+		}
+		counts = append(counts, count)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate %s counts: %w", column, err)
+	}
+
+	return counts, nil
+}
+
+// DistinctEventTypes returns every stored event type with its event count
+func (r *EventRepository) DistinctEventTypes(ctx context.Context) ([]TypeCount, error) {
+	return countTypes(ctx, r.db, "events", "event_type")
+}
+
+// EventOrder selects the sort order of listed events
+type EventOrder string
+
+const (
+	EventOrderTimestampDesc EventOrder = "timestamp_desc"
+	EventOrderTimestampAsc  EventOrder = "timestamp_asc"
+	EventOrderCreatedAtDesc EventOrder = "created_at_desc"
+)
+
+// eventOrderClauses is the allowlist of ORDER BY clauses; each is backed by
+// an index. The id tiebreaker keeps pagination stable.
+var eventOrderClauses = map[EventOrder]string{
+	EventOrderTimestampDesc: "timestamp DESC, id",
+	EventOrderTimestampAsc:  "timestamp ASC, id",
+	EventOrderCreatedAtDesc: "created_at DESC, id",
+}
+
+// ParseEventOrder validates an order name; empty selects timestamp_desc
+func ParseEventOrder(value string) (EventOrder, error) {
+	if value == "" {
+		return EventOrderTimestampDesc, nil
+	}
+	order := EventOrder(value)
+	if _, ok := eventOrderClauses[order]; !ok {
+		return "", fmt.Errorf("unsupported order %q (expected timestamp_desc, timestamp_asc or created_at_desc)", value)
+	}
+	return order, nil
+}
+
+// ListEvents retrieves events with optional filters in the given order,
+// defaulting to newest first
+func (r *EventRepository) ListEvents(ctx context.Context, limit, offset int, filter EventFilter, order EventOrder) ([]*models.Event, error) {
+	orderBy, ok := eventOrderClauses[order]
+	if !ok {
+		orderBy = eventOrderClauses[EventOrderTimestampDesc]
 	}
 
-	query += " ORDER BY timestamp DESC LIMIT $" + fmt.Sprintf("%d", argPos) + " OFFSET $" + fmt.Sprintf("%d", argPos+1)
+	query, args, argPos := buildEventQuery(filter)
+	query += " ORDER BY " + orderBy + " LIMIT $" + fmt.Sprintf("%d", argPos) + " OFFSET $" + fmt.Sprintf("%d", argPos+1)
 	args = append(args, limit, offset)
 
 	rows, err := r.db.QueryContext(ctx, query, args...)
@@ -157,36 +516,104 @@ func (r *EventRepository) ListEvents(ctx context.Context, limit, offset int, eve
 	}
 	defer rows.Close()
 
-	var events []*models.Event
+	events := []*models.Event{}
 	for rows.Next() {
-		var event models.Event
-		var rawJSON []byte
+		event, err := scanEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan event: %w", err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate events: %w", err)
+	}
 
-		err := rows.Scan(
-			&event.ID,
-			&event.EventID,
-			&rawJSON,
-			&event.EventType,
-			&event.Actor,
-			&event.Resource,
-			&event.IP,
-			&event.Region,
-			&event.Timestamp,
-			&event.IngestFailed,
-			&event.CreatedAt,
-		)
+	return events, nil
+}
+
+// GetEventsByIDs retrieves the events with the given database IDs in a
+// single query, in the order of ids. IDs with no event, such as events
+// already purged, are skipped.
+func (r *EventRepository) GetEventsByIDs(ctx context.Context, ids []uuid.UUID) ([]*models.Event, error) {
+	if len(ids) == 0 {
+		return []*models.Event{}, nil
+	}
+
+	strs := make([]string, len(ids))
+	for i, id := range ids {
+		strs[i] = id.String()
+	}
+
+	query := `SELECT ` + eventColumns + ` FROM events WHERE id = ANY($1::uuid[])`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(strs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query events: %w", err)
+	}
+	defer rows.Close()
+
+	byID := make(map[uuid.UUID]*models.Event, len(ids))
+	for rows.Next() {
+		event, err := scanEvent(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan event: %w", err)
 		}
+		byID[event.ID] = event
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate events: %w", err)
+	}
 
-		if err := json.Unmarshal(rawJSON, &event.Raw); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal raw event: %w", err)
+	events := make([]*models.Event, 0, len(byID))
+	for _, id := range ids {
+		if event, ok := byID[id]; ok {
+			events = append(events, event)
 		}
+	}
+	return events, nil
+}
 
-		events = append(events, &event)
+// StreamEvents iterates over every event matching filter, oldest first,
+// calling fn for each row as it is read so large exports are never buffered
+// in memory. Iteration stops at the first error returned by fn.
+//
+// The cursor stays open for as long as the client reads, so it runs in a
+// read-only transaction with the connection's statement_timeout lifted;
+// ctx still bounds it.
+func (r *EventRepository) StreamEvents(ctx context.Context, filter EventFilter, fn func(*models.Event) error) error {
+	query, args, _ := buildEventQuery(filter)
+	query += " ORDER BY timestamp ASC"
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("failed to begin export transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	return events, nil
+	if _, err := tx.ExecContext(ctx, `SET LOCAL statement_timeout = 0`); err != nil {
+		return fmt.Errorf("failed to lift statement timeout: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query events: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan event: %w", err)
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate events: %w", err)
+	}
+	return nil
 }
 
 // AlertRepository implements alert storage operations
@@ -201,14 +628,40 @@ func NewAlertRepository(db *sql.DB) *AlertRepository {
 
 // StoreAlert stores an alert in the database
 func (r *AlertRepository) StoreAlert(ctx context.Context, alert *models.Alert) error {
+	return storeAlert(ctx, r.db, alert)
+}
+
+// StoreAlertWithOutbox stores an alert together with a pending notification
+// in one transaction, so the notification survives a crash before delivery
+func (r *AlertRepository) StoreAlertWithOutbox(ctx context.Context, alert *models.Alert) error {
+	return r.inTransaction(ctx, func(tx *sql.Tx) error {
+		if err := storeAlert(ctx, tx, alert); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx, `INSERT INTO notification_outbox (alert_id) VALUES ($1)`, alert.ID)
+		if err != nil {
+			return fmt.Errorf("failed to queue alert notification: %w", err)
+		}
+		return nil
+	})
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func storeAlert(ctx context.Context, db execer, alert *models.Alert) error {
 	evidenceJSON, err := json.Marshal(alert.Evidence)
 	if err != nil {
 		return fmt.Errorf("failed to marshal evidence: %w", err)
 	}
 
 	query := `
-		INSERT INTO alerts (id, event_refs, alert_type, severity, user_id, description, status, evidence, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		INSERT INTO alerts (id, event_refs, alert_type, severity, user_id, project_id, description, status, evidence,
+			created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8, $9, $10, $11)
 	`
 
 	if alert.ID == uuid.Nil {
@@ -227,12 +680,13 @@ func (r *AlertRepository) StoreAlert(ctx context.Context, alert *models.Alert) e
 	// Convert UUID array to PostgreSQL array format
 	eventRefsArray := pqArray(alert.EventRefs)
 
-	_, err = r.db.ExecContext(ctx, query,
+	_, err = db.ExecContext(ctx, query,
 		alert.ID,
 		eventRefsArray,
 		alert.AlertType,
 		alert.Severity,
 		alert.UserID,
+		alert.ProjectID,
 		alert.Description,
 		alert.Status,
 		evidenceJSON,
@@ -246,124 +700,711 @@ func (r *AlertRepository) StoreAlert(ctx context.Context, alert *models.Alert) e
 	return nil
 }
 
-// GetAlert retrieves an alert by ID
-func (r *AlertRepository) GetAlert(ctx context.Context, id uuid.UUID) (*models.Alert, error) {
+// alertColumns is the column list scanned by scanAlert
+const alertColumns = `id, event_refs, alert_type, severity, user_id, COALESCE(project_id, ''), description, status,
+	evidence, acknowledged_at, acknowledged_by, created_at, updated_at`
+
+// scanAlert scans a row selected with alertColumns
+func scanAlert(row interface{ Scan(...any) error }) (*models.Alert, error) {
 	var alert models.Alert
 	var evidenceJSON []byte
-	var eventRefs []uuid.UUID
-
-	query := `
-		SELECT id, event_refs, alert_type, severity, user_id, description, status, evidence, created_at, updated_at
-		FROM alerts
-		WHERE id = $1
-	`
-
 	var eventRefsStr string
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
+	var acknowledgedAt sql.NullTime
+	var acknowledgedBy sql.NullString
+
+	err := row.Scan(
 		&alert.ID,
 		&eventRefsStr,
 		&alert.AlertType,
 		&alert.Severity,
 		&alert.UserID,
+		&alert.ProjectID,
 		&alert.Description,
 		&alert.Status,
 		&evidenceJSON,
+		&acknowledgedAt,
+		&acknowledgedBy,
 		&alert.CreatedAt,
 		&alert.UpdatedAt,
 	)
-	if err == nil {
-		eventRefs = parseUUIDArray(eventRefsStr)
-	}
-	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("alert not found")
-	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to get alert: %w", err)
+		return nil, err
 	}
 
-	alert.EventRefs = eventRefs
+	alert.EventRefs = parseUUIDArray(eventRefsStr)
 	if err := json.Unmarshal(evidenceJSON, &alert.Evidence); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal evidence: %w", err)
 	}
+	if acknowledgedAt.Valid {
+		alert.AcknowledgedAt = &acknowledgedAt.Time
+		alert.AcknowledgedBy = acknowledgedBy.String
+	}
 
 	return &alert, nil
 }
 
-// ListAlerts retrieves alerts with optional filters
-func (r *AlertRepository) ListAlerts(ctx context.Context, limit, offset int, severity, status, userID string) ([]*models.Alert, error) {
-	query := `
-		SELECT id, event_refs, alert_type, severity, user_id, description, status, evidence, created_at, updated_at
-		FROM alerts
-		WHERE 1=1
-	`
-	args := []interface{}{}
-	argPos := 1
+// GetAlert retrieves an alert by ID
+func (r *AlertRepository) GetAlert(ctx context.Context, id uuid.UUID) (*models.Alert, error) {
+	query := `SELECT ` + alertColumns + ` FROM alerts WHERE id = $1`
 
-	if severity != "" {
-		query += fmt.Sprintf(" AND severity = $%d", argPos)
-		args = append(args, severity)
-		argPos++
+	alert, err := scanAlert(r.db.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAlertNotFound
 	}
-
-	if status != "" {
-		query += fmt.Sprintf(" AND status = $%d", argPos)
-		args = append(args, status)
-		argPos++
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alert: %w", err)
 	}
+	return alert, nil
+}
 
-	if userID != "" {
-		query += fmt.Sprintf(" AND user_id = $%d", argPos)
-		args = append(args, userID)
-		argPos++
+// FindOpenAlert returns the newest alert of alertType for userID in the
+// project that is still OPEN or INVESTIGATING, or nil if there is none. An
+// empty projectID matches all projects.
+func (r *AlertRepository) FindOpenAlert(ctx context.Context, projectID, alertType, userID string) (*models.Alert, error) {
+	query := `SELECT ` + alertColumns + `
+		FROM alerts
+		WHERE alert_type = $1 AND user_id = $2
+		  AND ($3::text = '' OR project_id = $3)
+		  AND status IN ($4, $5)
+		ORDER BY created_at DESC
+		LIMIT 1`
+
+	alert, err := scanAlert(r.db.QueryRowContext(ctx, query, alertType, userID, projectID,
+		models.AlertStatusOpen, models.AlertStatusInvestigating))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
 	}
-
-	query += " ORDER BY created_at DESC LIMIT $" + fmt.Sprintf("%d", argPos) + " OFFSET $" + fmt.Sprintf("%d", argPos+1)
-	args = append(args, limit, offset)
-
-	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query alerts: %w", err)
+		return nil, fmt.Errorf("failed to find open alert: %w", err)
+	}
+	return alert, nil
+}
+
+// MergeEvidence folds a duplicate into the alert's evidence in place: it adds
+// to the occurrence count, moves last_seen forward and extends the
+// ip_addresses set and the event references, so one alert tracks an ongoing
+// condition
+func (r *AlertRepository) MergeEvidence(ctx context.Context, alertID uuid.UUID, delta models.EvidenceDelta) error {
+	query := `
+		UPDATE alerts
+		SET evidence = COALESCE(evidence, '{}'::jsonb) || jsonb_build_object(
+				'` + models.EvidenceOccurrences + `', COALESCE((evidence->>'` + models.EvidenceOccurrences + `')::int, 1) + $2,
+				'` + models.EvidenceLastSeen + `', GREATEST(evidence->>'` + models.EvidenceLastSeen + `', $3::text),
+				'` + models.EvidenceIPAddresses + `', (
+					SELECT COALESCE(jsonb_agg(ip ORDER BY ip), '[]'::jsonb)
+					FROM (
+						SELECT jsonb_array_elements_text(CASE
+							WHEN jsonb_typeof(evidence->'` + models.EvidenceIPAddresses + `') = 'array'
+							THEN evidence->'` + models.EvidenceIPAddresses + `' ELSE '[]'::jsonb END) AS ip
+						UNION
+						SELECT unnest($4::text[])
+					) ips
+					WHERE ip <> ''
+				)
+			),
+			event_refs = event_refs || ARRAY(SELECT unnest($5::uuid[]) EXCEPT SELECT unnest(event_refs)),
+			updated_at = NOW()
+		WHERE id = $1
+	`
+	result, err := r.db.ExecContext(ctx, query, alertID, delta.Occurrences,
+		delta.LastSeen.UTC().Format(time.RFC3339), pq.Array(delta.IPAddresses), pqArray(delta.EventRefs))
+	if err != nil {
+		return fmt.Errorf("failed to merge alert evidence: %w", err)
+	}
+	merged, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to merge alert evidence: %w", err)
+	}
+	if merged == 0 {
+		return ErrAlertNotFound
+	}
+	return nil
+}
+
+// AcknowledgeAlert records that actor has picked up the alert, leaving its
+// status unchanged. An alert that is already acknowledged keeps its original
+// acknowledgement. It returns the alert as stored.
+func (r *AlertRepository) AcknowledgeAlert(ctx context.Context, id uuid.UUID, actor string) (*models.Alert, error) {
+	query := `
+		UPDATE alerts
+		SET acknowledged_at = COALESCE(acknowledged_at, NOW()),
+			acknowledged_by = COALESCE(acknowledged_by, $2)
+		WHERE id = $1
+		RETURNING ` + alertColumns
+
+	alert, err := scanAlert(r.db.QueryRowContext(ctx, query, id, actor))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAlertNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to acknowledge alert: %w", err)
+	}
+	return alert, nil
+}
+
+// AlertFilter holds the optional filters for listing alerts. From and To
+// bound the alert creation time.
+type AlertFilter struct {
+	Severity  string
+	Status    string
+	UserID    string
+	AlertType string
+	ProjectID string
+	From      *time.Time
+	To        *time.Time
+}
+
+// AlertOrder selects the sort order of listed alerts
+type AlertOrder string
+
+const (
+	AlertOrderCreatedDesc      AlertOrder = "created_desc"
+	AlertOrderSeverityDesc     AlertOrder = "severity_desc"
+	AlertOrderSeverityThenTime AlertOrder = "severity_then_time"
+)
+
+// severityRank orders severities from CRITICAL (4) down to LOW (1)
+const severityRank = `CASE severity WHEN 'CRITICAL' THEN 4 WHEN 'HIGH' THEN 3 WHEN 'MEDIUM' THEN 2 WHEN 'LOW' THEN 1 ELSE 0 END`
+
+// alertOrderClauses is the allowlist of ORDER BY clauses. severity_desc only
+// ranks by severity; severity_then_time puts the newest first within each
+// severity. The id tiebreaker keeps pagination stable.
+var alertOrderClauses = map[AlertOrder]string{
+	AlertOrderCreatedDesc:      "created_at DESC, id",
+	AlertOrderSeverityDesc:     severityRank + " DESC, id",
+	AlertOrderSeverityThenTime: severityRank + " DESC, created_at DESC, id",
+}
+
+// ParseAlertOrder validates an order name; empty selects created_desc
+func ParseAlertOrder(value string) (AlertOrder, error) {
+	if value == "" {
+		return AlertOrderCreatedDesc, nil
+	}
+	order := AlertOrder(value)
+	if _, ok := alertOrderClauses[order]; !ok {
+		return "", fmt.Errorf("unsupported sort %q (expected created_desc, severity_desc or severity_then_time)", value)
+	}
+	return order, nil
+}
+
+// IncidentFilter selects the alerts grouped into incidents. Alerts of the
+// same user and project belong to one incident while each follows the
+// previous one within Gap.
+type IncidentFilter struct {
+	Gap       time.Duration
+	UserID    string
+	ProjectID string
+	From      *time.Time
+	To        *time.Time
+}
+
+// ListIncidents groups matching alerts into incidents, most recently active
+// first. An incident's severity is the highest of its alerts.
+func (r *AlertRepository) ListIncidents(ctx context.Context, limit, offset int, filter IncidentFilter) ([]*models.Incident, error) {
+	where := `WHERE 1=1`
+	args := []interface{}{filter.Gap.Seconds()}
+	argPos := 2
+
+	if filter.UserID != "" {
+		where += fmt.Sprintf(" AND user_id = $%d", argPos)
+		args = append(args, filter.UserID)
+		argPos++
+	}
+
+	if filter.ProjectID != "" {
+		where += fmt.Sprintf(" AND project_id = $%d", argPos)
+		args = append(args, filter.ProjectID)
+		argPos++
+	}
+
+	if filter.From != nil {
+		where += fmt.Sprintf(" AND created_at >= $%d", argPos)
+		args = append(args, *filter.From)
+		argPos++
+	}
+
+	if filter.To != nil {
+		where += fmt.Sprintf(" AND created_at < $%d", argPos)
+		args = append(args, *filter.To)
+		argPos++
+	}
+
+	// An alert starts a new incident when it comes more than the gap after
+	// the user's previous alert; the running count of starts numbers them
+	query := `
+		WITH marked AS (
+			SELECT id, alert_type, user_id, COALESCE(project_id, '') AS project_id, created_at,
+				` + severityRank + ` AS rank,
+				CASE WHEN created_at - LAG(created_at) OVER w > $1 * INTERVAL '1 second' THEN 1 ELSE 0 END AS starts
+			FROM alerts
+			` + where + `
+			WINDOW w AS (PARTITION BY user_id, COALESCE(project_id, '') ORDER BY created_at, id)
+		), numbered AS (
+			SELECT *, SUM(starts) OVER (PARTITION BY user_id, project_id ORDER BY created_at, id) AS incident
+			FROM marked
+		)
+		SELECT user_id, project_id, MAX(rank), MIN(created_at), MAX(created_at), COUNT(*),
+			array_agg(id ORDER BY created_at, id), array_agg(DISTINCT alert_type)
+		FROM numbered
+		GROUP BY user_id, project_id, incident
+		ORDER BY MAX(created_at) DESC, user_id, project_id
+		LIMIT $` + fmt.Sprintf("%d", argPos) + ` OFFSET $` + fmt.Sprintf("%d", argPos+1)
+	args = append(args, limit, offset)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query incidents: %w", err)
 	}
 	defer rows.Close()
 
-	var alerts []*models.Alert
+	incidents := []*models.Incident{}
 	for rows.Next() {
-		var alert models.Alert
-		var evidenceJSON []byte
-		var eventRefsStr string
-		err := rows.Scan(
-			&alert.ID,
-			&eventRefsStr,
-			&alert.AlertType,
-			&alert.Severity,
-			&alert.UserID,
-			&alert.Description,
-			&alert.Status,
-			&evidenceJSON,
-			&alert.CreatedAt,
-			&alert.UpdatedAt,
-		)
+		var incident models.Incident
+		var rank int
+		var alertIDs, alertTypes pq.StringArray
+		err := rows.Scan(&incident.UserID, &incident.ProjectID, &rank, &incident.StartedAt, &incident.EndedAt,
+			&incident.AlertCount, &alertIDs, &alertTypes)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan alert: %w", err)
+			return nil, fmt.Errorf("failed to scan incident: %w", err)
 		}
 
-		alert.EventRefs = parseUUIDArray(eventRefsStr)
-		if err := json.Unmarshal(evidenceJSON, &alert.Evidence); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal evidence: %w", err)
+		incident.Severity = severityForRank(rank)
+		incident.AlertTypes = alertTypes
+		for _, id := range alertIDs {
+			parsed, err := uuid.Parse(id)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse incident alert ID: %w", err)
+			}
+			incident.AlertIDs = append(incident.AlertIDs, parsed)
 		}
+		incidents = append(incidents, &incident)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate incidents: %w", err)
+	}
+
+	return incidents, nil
+}
+
+// severityForRank maps a severityRank value back to its severity
+func severityForRank(rank int) models.Severity {
+	switch rank {
+	case 4:
+		return models.SeverityCritical
+	case 3:
+		return models.SeverityHigh
+	case 2:
+		return models.SeverityMedium
+	case 1:
+		return models.SeverityLow
+	default:
+		return ""
+	}
+}
 
-		alerts = append(alerts, &alert)
+// ListAlerts retrieves alerts with optional filters in the given order,
+// defaulting to newest first
+func (r *AlertRepository) ListAlerts(ctx context.Context, limit, offset int, filter AlertFilter, order AlertOrder) ([]*models.Alert, error) {
+	orderBy, ok := alertOrderClauses[order]
+	if !ok {
+		orderBy = alertOrderClauses[AlertOrderCreatedDesc]
+	}
+
+	query := `SELECT ` + alertColumns + ` FROM alerts WHERE 1=1`
+	args := []interface{}{}
+	argPos := 1
+
+	if filter.Severity != "" {
+		query += fmt.Sprintf(" AND severity = $%d", argPos)
+		args = append(args, filter.Severity)
+		argPos++
+	}
+
+	if filter.Status != "" {
+		query += fmt.Sprintf(" AND status = $%d", argPos)
+		args = append(args, filter.Status)
+		argPos++
+	}
+
+	if filter.UserID != "" {
+		query += fmt.Sprintf(" AND user_id = $%d", argPos)
+		args = append(args, filter.UserID)
+		argPos++
+	}
+
+	if filter.AlertType != "" {
+		query += fmt.Sprintf(" AND alert_type = $%d", argPos)
+		args = append(args, filter.AlertType)
+		argPos++
+	}
+
+	if filter.ProjectID != "" {
+		query += fmt.Sprintf(" AND project_id = $%d", argPos)
+		args = append(args, filter.ProjectID)
+		argPos++
+	}
+
+	if filter.From != nil {
+		query += fmt.Sprintf(" AND created_at >= $%d", argPos)
+		args = append(args, *filter.From)
+		argPos++
+	}
+
+	if filter.To != nil {
+		query += fmt.Sprintf(" AND created_at < $%d", argPos)
+		args = append(args, *filter.To)
+		argPos++
+	}
+
+	query += " ORDER BY " + orderBy + " LIMIT $" + fmt.Sprintf("%d", argPos) + " OFFSET $" + fmt.Sprintf("%d", argPos+1)
+	args = append(args, limit, offset)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alerts: %w", err)
+	}
+	defer rows.Close()
+
+	alerts := []*models.Alert{}
+	for rows.Next() {
+		alert, err := scanAlert(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan alert: %w", err)
+		}
+		alerts = append(alerts, alert)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate alerts: %w", err)
 	}
 
 	return alerts, nil
 }
 
-// UpdateAlertStatus updates an alert's status
-func (r *AlertRepository) UpdateAlertStatus(ctx context.Context, id uuid.UUID, status models.AlertStatus) error {
-	query := `UPDATE alerts SET status = $1, updated_at = $2 WHERE id = $3`
-	_, err := r.db.ExecContext(ctx, query, status, time.Now(), id)
+// alertStatusTransitions lists the statuses each status may move to. A true
+// value means the transition needs a reason, as reopening an alert does.
+var alertStatusTransitions = map[models.AlertStatus]map[models.AlertStatus]bool{
+	models.AlertStatusOpen: {
+		models.AlertStatusInvestigating: false,
+		models.AlertStatusResolved:      false,
+		models.AlertStatusFalsePositive: false,
+	},
+	models.AlertStatusInvestigating: {
+		models.AlertStatusOpen:          false,
+		models.AlertStatusResolved:      false,
+		models.AlertStatusFalsePositive: false,
+	},
+	models.AlertStatusResolved: {
+		models.AlertStatusOpen: true,
+	},
+	models.AlertStatusFalsePositive: {
+		models.AlertStatusOpen: true,
+	},
+}
+
+// checkStatusTransition reports whether an alert may move from one status to
+// another, wrapping ErrInvalidStatusTransition if not
+func checkStatusTransition(from, to models.AlertStatus, reason string) error {
+	needsReason, ok := alertStatusTransitions[from][to]
+	if !ok {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidStatusTransition, from, to)
+	}
+	if needsReason && strings.TrimSpace(reason) == "" {
+		return fmt.Errorf("%w: %s to %s requires a reason", ErrInvalidStatusTransition, from, to)
+	}
+	return nil
+}
+
+// UpdateAlertStatus moves an alert to a new status and records the
+// transition, made by actor, in the alert's status history. Transitions not
+// allowed by alertStatusTransitions fail with ErrInvalidStatusTransition.
+func (r *AlertRepository) UpdateAlertStatus(ctx context.Context, id uuid.UUID, status models.AlertStatus, actor, reason string) error {
+	return r.inTransaction(ctx, func(tx *sql.Tx) error {
+		var current models.AlertStatus
+		err := tx.QueryRowContext(ctx, `SELECT status FROM alerts WHERE id = $1 FOR UPDATE`, id).Scan(&current)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrAlertNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get alert status: %w", err)
+		}
+
+		if err := checkStatusTransition(current, status, reason); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if _, err := tx.ExecContext(ctx, `UPDATE alerts SET status = $1, updated_at = $2 WHERE id = $3`, status, now, id); err != nil {
+			return fmt.Errorf("failed to update alert status: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO alert_status_history (alert_id, from_status, to_status, actor, reason, at)
+			VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6)
+		`, id, current, status, actor, reason, now)
+		if err != nil {
+			return fmt.Errorf("failed to record alert status change: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetStatusHistory returns an alert's status transitions, oldest first
+func (r *AlertRepository) GetStatusHistory(ctx context.Context, id uuid.UUID) ([]*models.AlertStatusChange, error) {
+	query := `
+		SELECT id, alert_id, from_status, to_status, actor, COALESCE(reason, ''), at
+		FROM alert_status_history
+		WHERE alert_id = $1
+		ORDER BY at, id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alert status history: %w", err)
+	}
+	defer rows.Close()
+
+	history := []*models.AlertStatusChange{}
+	for rows.Next() {
+		var change models.AlertStatusChange
+		if err := rows.Scan(&change.ID, &change.AlertID, &change.FromStatus, &change.ToStatus, &change.Actor, &change.Reason, &change.At); err != nil {
+			return nil, fmt.Errorf("failed to scan alert status change: %w", err)
+		}
+		history = append(history, &change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate alert status history: %w", err)
+	}
+
+	return history, nil
+}
+
+// inTransaction runs fn in a transaction, committing if it returns nil
+func (r *AlertRepository) inTransaction(ctx context.Context, fn func(*sql.Tx) error) error {
+	return inTransaction(ctx, r.db, fn)
+}
+
+// inTransaction runs fn in a transaction, committing if it returns nil
+func inTransaction(ctx context.Context, db *sql.DB, fn func(*sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// DeleteByUser deletes every alert of the user and returns the number of
+// rows removed. Remediation logs of those alerts are kept but detached.
+func (r *AlertRepository) DeleteByUser(ctx context.Context, userID string) (int64, error) {
+	var deleted int64
+	err := r.inTransaction(ctx, func(tx *sql.Tx) error {
+		var err error
+		deleted, err = deleteAlertsByUser(ctx, tx, userID)
+		return err
+	})
+	return deleted, err
+}
+
+func deleteAlertsByUser(ctx context.Context, db execer, userID string) (int64, error) {
+	_, err := db.ExecContext(ctx, `
+		UPDATE remediation_logs SET alert_id = NULL
+		WHERE alert_id IN (SELECT id FROM alerts WHERE user_id = $1)
+	`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to detach remediation logs: %w", err)
+	}
+
+	result, err := db.ExecContext(ctx, `DELETE FROM alerts WHERE user_id = $1`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete alerts: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count deleted alerts: %w", err)
+	}
+	return deleted, nil
+}
+
+// DistinctAlertTypes returns every stored alert type with its alert count
+func (r *AlertRepository) DistinctAlertTypes(ctx context.Context) ([]TypeCount, error) {
+	return countTypes(ctx, r.db, "alerts", "alert_type")
+}
+
+// AlertSummary is an aggregate view of alerts over a time range
+type AlertSummary struct {
+	From       *time.Time       `json:"from,omitempty"`
+	To         *time.Time       `json:"to,omitempty"`
+	Total      int              `json:"total"`
+	BySeverity map[string]int   `json:"by_severity"`
+	ByType     map[string]int   `json:"by_type"`
+	ByStatus   map[string]int   `json:"by_status"`
+	TopUsers   []UserAlertCount `json:"top_users"`
+}
+
+// UserAlertCount is the number of alerts raised for a single user
+type UserAlertCount struct {
+	UserID string `json:"user_id"`
+	Count  int    `json:"count"`
+}
+
+// topUsersLimit caps the number of users returned in an alert summary
+const topUsersLimit = 10
+
+// Summarize aggregates alerts created within [from, to) by severity, type,
+// status and affected user. Either bound may be nil.
+func (r *AlertRepository) Summarize(ctx context.Context, from, to *time.Time) (*AlertSummary, error) {
+	where := " WHERE 1=1"
+	args := []interface{}{}
+	argPos := 1
+
+	if from != nil {
+		where += fmt.Sprintf(" AND created_at >= $%d", argPos)
+		args = append(args, *from)
+		argPos++
+	}
+
+	if to != nil {
+		where += fmt.Sprintf(" AND created_at < $%d", argPos)
+		args = append(args, *to)
+		argPos++
+	}
+
+	summary := &AlertSummary{From: from, To: to}
+
+	var err error
+	if summary.BySeverity, err = r.countBy(ctx, "severity", where, args); err != nil {
+		return nil, err
+	}
+	if summary.ByType, err = r.countBy(ctx, "alert_type", where, args); err != nil {
+		return nil, err
+	}
+	if summary.ByStatus, err = r.countBy(ctx, "status", where, args); err != nil {
+		return nil, err
+	}
+	for _, count := range summary.ByStatus {
+		summary.Total += count
+	}
+
+	query := `SELECT user_id, COUNT(*) FROM alerts` + where +
+		` AND user_id IS NOT NULL AND user_id <> ''` +
+		fmt.Sprintf(` GROUP BY user_id ORDER BY COUNT(*) DESC, user_id LIMIT $%d`, argPos)
+	rows, err := r.db.QueryContext(ctx, query, append(args, topUsersLimit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query top users: %w", err)
+	}
+	defer rows.Close()
+
+	summary.TopUsers = []UserAlertCount{}
+	for rows.Next() {
+		var entry UserAlertCount
+		if err := rows.Scan(&entry.UserID, &entry.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan top user: %w", err)
+		}
+		summary.TopUsers = append(summary.TopUsers, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate top users: %w", err)
+	}
+
+	return summary, nil
+}
+
+// CountOpenBySeverity counts open alerts grouped by severity
+func (r *AlertRepository) CountOpenBySeverity(ctx context.Context) (map[string]int, error) {
+	return r.countBy(ctx, "severity", " WHERE status = $1", []interface{}{models.AlertStatusOpen})
+}
+
+// countBy counts alerts grouped by a single column. column must be a trusted
+// identifier, never user input.
+func (r *AlertRepository) countBy(ctx context.Context, column, where string, args []interface{}) (map[string]int, error) {
+	query := fmt.Sprintf(`SELECT %s, COUNT(*) FROM alerts%s GROUP BY %s`, column, where, column)
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count alerts by %s: %w", column, err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var key sql.NullString
+		var count int
+		if err := rows.Scan(&key, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan alert count: %w", err)
+		}
+		counts[key.String] += count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate alert counts: %w", err)
+	}
+
+	return counts, nil
+}
+
+// OutboxEntry is an alert notification awaiting delivery
+type OutboxEntry struct {
+	ID       uuid.UUID
+	AlertID  uuid.UUID
+	Attempts int
+}
+
+// PendingNotifications returns up to limit unsent notifications that are due,
+// oldest first
+func (r *AlertRepository) PendingNotifications(ctx context.Context, limit int) ([]OutboxEntry, error) {
+	query := `
+		SELECT id, alert_id, attempts
+		FROM notification_outbox
+		WHERE sent_at IS NULL AND next_attempt_at <= NOW()
+		ORDER BY created_at
+		LIMIT $1
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pending notifications: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []OutboxEntry{}
+	for rows.Next() {
+		var entry OutboxEntry
+		if err := rows.Scan(&entry.ID, &entry.AlertID, &entry.Attempts); err != nil {
+			return nil, fmt.Errorf("failed to scan pending notification: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate pending notifications: %w", err)
+	}
+
+	return entries, nil
+}
+
+// MarkNotificationSent records that a notification was delivered
+func (r *AlertRepository) MarkNotificationSent(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE notification_outbox SET sent_at = NOW(), last_error = NULL WHERE id = $1`, id)
 	if err != nil {
-		return fmt.Errorf("failed to update alert status: %w", err)
+		return fmt.Errorf("failed to mark notification sent: %w", err)
+	}
+	return nil
+}
+
+// MarkNotificationFailed records a failed delivery and when to retry it
+func (r *AlertRepository) MarkNotificationFailed(ctx context.Context, id uuid.UUID, nextAttempt time.Time, reason string) error {
+	query := `
+		UPDATE notification_outbox
+		SET attempts = attempts + 1, next_attempt_at = $2, last_error = $3
+		WHERE id = $1
+	`
+	if _, err := r.db.ExecContext(ctx, query, id, nextAttempt, reason); err != nil {
+		return fmt.Errorf("failed to mark notification failed: %w", err)
 	}
 	return nil
 }
@@ -396,10 +1437,15 @@ func (r *RemediationRepository) LogRemediation(ctx context.Context, logEntry *mo
 	if logEntry.Timestamp.IsZero() {
 		logEntry.Timestamp = time.Now()
 	}
+	// Actions not taken on an alert have no alert to reference
+	var alertID interface{}
+	if logEntry.AlertID != uuid.Nil {
+		alertID = logEntry.AlertID
+	}
 
 	_, err = r.db.ExecContext(ctx, query,
 		logEntry.ID,
-		logEntry.AlertID,
+		alertID,
 		logEntry.ActorUser,
 		logEntry.ActionType,
 		payloadJSON,
@@ -413,6 +1459,16 @@ func (r *RemediationRepository) LogRemediation(ctx context.Context, logEntry *mo
 	return nil
 }
 
+// CountRemediationsSince counts remediation actions logged at or after since
+func (r *RemediationRepository) CountRemediationsSince(ctx context.Context, since time.Time) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM remediation_logs WHERE timestamp >= $1", since).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count remediations: %w", err)
+	}
+	return count, nil
+}
+
 // GetRemediationLogs retrieves remediation logs for an alert
 func (r *RemediationRepository) GetRemediationLogs(ctx context.Context, alertID uuid.UUID) ([]*models.RemediationLog, error) {
 	query := `
@@ -452,10 +1508,470 @@ func (r *RemediationRepository) GetRemediationLogs(ctx context.Context, alertID
 
 		logs = append(logs, &logEntry)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate remediation logs: %w", err)
+	}
 
 	return logs, nil
 }
 
+// UserProfileRepository implements user risk profile storage
+type UserProfileRepository struct {
+	db *sql.DB
+}
+
+// NewUserProfileRepository creates a new user profile repository
+func NewUserProfileRepository(db *sql.DB) *UserProfileRepository {
+	return &UserProfileRepository{db: db}
+}
+
+// GetUserProfile retrieves the profile of a Scaleway user, returning
+// ErrUserProfileNotFound if none exists yet
+func (r *UserProfileRepository) GetUserProfile(ctx context.Context, userID string) (*models.UserProfile, error) {
+	query := `
+		SELECT id, scaleway_user_id, last_seen_ip, last_seen_region, risk_score, locked, updated_at
+		FROM user_profiles
+		WHERE scaleway_user_id = $1
+	`
+
+	var profile models.UserProfile
+	var lastSeenIP, lastSeenRegion sql.NullString
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(
+		&profile.ID,
+		&profile.ScalewayUserID,
+		&lastSeenIP,
+		&lastSeenRegion,
+		&profile.RiskScore,
+		&profile.Locked,
+		&profile.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserProfileNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user profile: %w", err)
+	}
+
+	profile.LastSeenIP = lastSeenIP.String
+	profile.LastSeenRegion = lastSeenRegion.String
+	return &profile, nil
+}
+
+// UpsertUserProfile creates or updates the profile keyed by ScalewayUserID
+func (r *UserProfileRepository) UpsertUserProfile(ctx context.Context, profile *models.UserProfile) error {
+	query := `
+		INSERT INTO user_profiles (id, scaleway_user_id, last_seen_ip, last_seen_region, risk_score, locked, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (scaleway_user_id) DO UPDATE SET
+			last_seen_ip = EXCLUDED.last_seen_ip,
+			last_seen_region = EXCLUDED.last_seen_region,
+			risk_score = EXCLUDED.risk_score,
+			locked = EXCLUDED.locked,
+			updated_at = EXCLUDED.updated_at
+		RETURNING id
+	`
+
+	if profile.ID == uuid.Nil {
+		profile.ID = uuid.New()
+	}
+	if profile.UpdatedAt.IsZero() {
+		profile.UpdatedAt = time.Now()
+	}
+
+	err := r.db.QueryRowContext(ctx, query,
+		profile.ID,
+		profile.ScalewayUserID,
+		profile.LastSeenIP,
+		profile.LastSeenRegion,
+		profile.RiskScore,
+		profile.Locked,
+		profile.UpdatedAt,
+	).Scan(&profile.ID)
+	if err != nil {
+		return fmt.Errorf("failed to upsert user profile: %w", err)
+	}
+
+	return nil
+}
+
+// IdempotencyRecord is a request made with an Idempotency-Key and, once
+// complete, its response
+type IdempotencyRecord struct {
+	Key          string
+	RequestHash  string
+	StatusCode   int // 0 while the request is in progress
+	ResponseBody []byte
+	ContentType  string
+	CreatedAt    time.Time
+}
+
+// IdempotencyRepository implements idempotency key storage
+type IdempotencyRepository struct {
+	db *sql.DB
+}
+
+// NewIdempotencyRepository creates a new idempotency key repository
+func NewIdempotencyRepository(db *sql.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+// Reserve claims key for a new request. If the key is already held by a
+// record created after expiry, that record is returned with reserved false;
+// older records are replaced.
+func (r *IdempotencyRepository) Reserve(ctx context.Context, key, requestHash string, expiry time.Time) (*IdempotencyRecord, bool, error) {
+	query := `
+		INSERT INTO idempotency_keys (key, request_hash, created_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (key) DO UPDATE SET
+			request_hash = EXCLUDED.request_hash,
+			status_code = NULL,
+			response_body = NULL,
+			content_type = NULL,
+			created_at = EXCLUDED.created_at
+		WHERE idempotency_keys.created_at < $3
+	`
+	result, err := r.db.ExecContext(ctx, query, key, requestHash, expiry)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+	reserved, err := result.RowsAffected()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+	if reserved > 0 {
+		return nil, true, nil
+	}
+
+	var record IdempotencyRecord
+	var statusCode sql.NullInt64
+	var contentType sql.NullString
+	err = r.db.QueryRowContext(ctx, `
+		SELECT key, request_hash, status_code, response_body, content_type, created_at
+		FROM idempotency_keys
+		WHERE key = $1
+	`, key).Scan(&record.Key, &record.RequestHash, &statusCode, &record.ResponseBody, &contentType, &record.CreatedAt)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+	record.StatusCode = int(statusCode.Int64)
+	record.ContentType = contentType.String
+
+	return &record, false, nil
+}
+
+// Complete stores the response of a reserved request
+func (r *IdempotencyRepository) Complete(ctx context.Context, key string, statusCode int, contentType string, body []byte) error {
+	query := `UPDATE idempotency_keys SET status_code = $2, content_type = $3, response_body = $4 WHERE key = $1`
+	if _, err := r.db.ExecContext(ctx, query, key, statusCode, contentType, body); err != nil {
+		return fmt.Errorf("failed to store idempotent response: %w", err)
+	}
+	return nil
+}
+
+// Release deletes a reservation so the request can be retried with its key
+func (r *IdempotencyRepository) Release(ctx context.Context, key string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE key = $1`, key); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// MuteRepository implements alert mute storage
+type MuteRepository struct {
+	db *sql.DB
+}
+
+// NewMuteRepository creates a new alert mute repository
+func NewMuteRepository(db *sql.DB) *MuteRepository {
+	return &MuteRepository{db: db}
+}
+
+// muteColumns is the column list scanned by scanMute
+const muteColumns = `id, alert_type, COALESCE(user_id, ''), COALESCE(resource, ''), COALESCE(reason, ''),
+	created_by, created_at, expires_at`
+
+// scanMute scans a row selected with muteColumns
+func scanMute(row interface{ Scan(...any) error }) (*models.AlertMute, error) {
+	var mute models.AlertMute
+	err := row.Scan(&mute.ID, &mute.AlertType, &mute.UserID, &mute.Resource, &mute.Reason,
+		&mute.CreatedBy, &mute.CreatedAt, &mute.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &mute, nil
+}
+
+// CreateMute stores a new alert mute
+func (r *MuteRepository) CreateMute(ctx context.Context, mute *models.AlertMute) error {
+	if mute.ID == uuid.Nil {
+		mute.ID = uuid.New()
+	}
+	if mute.CreatedAt.IsZero() {
+		mute.CreatedAt = time.Now()
+	}
+
+	query := `
+		INSERT INTO alert_mutes (id, alert_type, user_id, resource, reason, created_by, created_at, expires_at)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), NULLIF($5, ''), $6, $7, $8)
+	`
+	_, err := r.db.ExecContext(ctx, query, mute.ID, mute.AlertType, mute.UserID, mute.Resource, mute.Reason,
+		mute.CreatedBy, mute.CreatedAt, mute.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to create alert mute: %w", err)
+	}
+	return nil
+}
+
+// GetMute retrieves an alert mute by ID
+func (r *MuteRepository) GetMute(ctx context.Context, id uuid.UUID) (*models.AlertMute, error) {
+	query := `SELECT ` + muteColumns + ` FROM alert_mutes WHERE id = $1`
+
+	mute, err := scanMute(r.db.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrMuteNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alert mute: %w", err)
+	}
+	return mute, nil
+}
+
+// ListMutes returns alert mutes ordered by expiry, leaving out expired ones
+// unless includeExpired is set
+func (r *MuteRepository) ListMutes(ctx context.Context, includeExpired bool) ([]*models.AlertMute, error) {
+	query := `SELECT ` + muteColumns + ` FROM alert_mutes`
+	if !includeExpired {
+		query += ` WHERE expires_at > NOW()`
+	}
+	query += ` ORDER BY expires_at, id`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alert mutes: %w", err)
+	}
+	defer rows.Close()
+
+	mutes := []*models.AlertMute{}
+	for rows.Next() {
+		mute, err := scanMute(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan alert mute: %w", err)
+		}
+		mutes = append(mutes, mute)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate alert mutes: %w", err)
+	}
+
+	return mutes, nil
+}
+
+// DeleteMute removes an alert mute
+func (r *MuteRepository) DeleteMute(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM alert_mutes WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete alert mute: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete alert mute: %w", err)
+	}
+	if deleted == 0 {
+		return ErrMuteNotFound
+	}
+	return nil
+}
+
+// IsMuted reports whether an unexpired mute matches an alert of alertType for
+// userID on resource
+func (r *MuteRepository) IsMuted(ctx context.Context, alertType, userID, resource string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM alert_mutes
+			WHERE alert_type = $1
+			AND (user_id IS NULL OR user_id = $2)
+			AND (resource IS NULL OR resource = $3)
+			AND expires_at > NOW()
+		)
+	`
+	var muted bool
+	if err := r.db.QueryRowContext(ctx, query, alertType, userID, resource).Scan(&muted); err != nil {
+		return false, fmt.Errorf("failed to check alert mutes: %w", err)
+	}
+	return muted, nil
+}
+
+// MaintenanceRepository implements maintenance window storage
+type MaintenanceRepository struct {
+	db *sql.DB
+}
+
+// NewMaintenanceRepository creates a new maintenance window repository
+func NewMaintenanceRepository(db *sql.DB) *MaintenanceRepository {
+	return &MaintenanceRepository{db: db}
+}
+
+// maintenanceColumns is the column list scanned by scanMaintenanceWindow
+const maintenanceColumns = `id, starts_at, ends_at, COALESCE(alert_type, ''), mode, COALESCE(reason, ''),
+	created_by, created_at`
+
+// scanMaintenanceWindow scans a row selected with maintenanceColumns
+func scanMaintenanceWindow(row interface{ Scan(...any) error }) (*models.MaintenanceWindow, error) {
+	var window models.MaintenanceWindow
+	err := row.Scan(&window.ID, &window.StartsAt, &window.EndsAt, &window.AlertType, &window.Mode,
+		&window.Reason, &window.CreatedBy, &window.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &window, nil
+}
+
+// CreateWindow stores a new maintenance window
+func (r *MaintenanceRepository) CreateWindow(ctx context.Context, window *models.MaintenanceWindow) error {
+	if window.ID == uuid.Nil {
+		window.ID = uuid.New()
+	}
+	if window.CreatedAt.IsZero() {
+		window.CreatedAt = time.Now()
+	}
+
+	query := `
+		INSERT INTO maintenance_windows (id, starts_at, ends_at, alert_type, mode, reason, created_by, created_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, NULLIF($6, ''), $7, $8)
+	`
+	_, err := r.db.ExecContext(ctx, query, window.ID, window.StartsAt, window.EndsAt, window.AlertType,
+		window.Mode, window.Reason, window.CreatedBy, window.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create maintenance window: %w", err)
+	}
+	return nil
+}
+
+// ListWindows returns maintenance windows ordered by start, leaving out
+// finished ones unless includePast is set
+func (r *MaintenanceRepository) ListWindows(ctx context.Context, includePast bool) ([]*models.MaintenanceWindow, error) {
+	query := `SELECT ` + maintenanceColumns + ` FROM maintenance_windows`
+	if !includePast {
+		query += ` WHERE ends_at > NOW()`
+	}
+	query += ` ORDER BY starts_at, id`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query maintenance windows: %w", err)
+	}
+	defer rows.Close()
+
+	windows := []*models.MaintenanceWindow{}
+	for rows.Next() {
+		window, err := scanMaintenanceWindow(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan maintenance window: %w", err)
+		}
+		windows = append(windows, window)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate maintenance windows: %w", err)
+	}
+
+	return windows, nil
+}
+
+// DeleteWindow cancels a maintenance window
+func (r *MaintenanceRepository) DeleteWindow(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM maintenance_windows WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete maintenance window: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete maintenance window: %w", err)
+	}
+	if deleted == 0 {
+		return ErrMaintenanceWindowNotFound
+	}
+	return nil
+}
+
+// ActiveWindow returns the maintenance window covering an alert of alertType
+// at the given time, or nil if there is none. Suppressing windows take
+// precedence over tagging ones.
+func (r *MaintenanceRepository) ActiveWindow(ctx context.Context, alertType string, at time.Time) (*models.MaintenanceWindow, error) {
+	query := `SELECT ` + maintenanceColumns + `
+		FROM maintenance_windows
+		WHERE starts_at <= $2 AND ends_at > $2
+		AND (alert_type IS NULL OR alert_type = $1)
+		ORDER BY (mode = 'suppress') DESC, starts_at
+		LIMIT 1`
+
+	window, err := scanMaintenanceWindow(r.db.QueryRowContext(ctx, query, alertType, at))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to check maintenance windows: %w", err)
+	}
+	return window, nil
+}
+
+// ErasureRepository erases a user's personal data
+type ErasureRepository struct {
+	db *sql.DB
+}
+
+// NewErasureRepository creates a new erasure repository
+func NewErasureRepository(db *sql.DB) *ErasureRepository {
+	return &ErasureRepository{db: db}
+}
+
+// ErasureResult counts the rows removed by an erasure
+type ErasureResult struct {
+	ID              uuid.UUID `json:"erasure_id"`
+	Subject         string    `json:"user_id"`
+	EventsDeleted   int64     `json:"events_deleted"`
+	AlertsDeleted   int64     `json:"alerts_deleted"`
+	ProfilesDeleted int64     `json:"profiles_deleted"`
+	ErasedAt        time.Time `json:"erased_at"`
+}
+
+// EraseUserData deletes the user's events, alerts and risk profile and
+// records the erasure in data_erasures, all in one transaction
+func (r *ErasureRepository) EraseUserData(ctx context.Context, userID, requestedBy string) (*ErasureResult, error) {
+	erasure := &ErasureResult{ID: uuid.New(), Subject: userID, ErasedAt: time.Now()}
+
+	err := inTransaction(ctx, r.db, func(tx *sql.Tx) error {
+		var err error
+		if erasure.EventsDeleted, err = deleteEventsByActor(ctx, tx, userID); err != nil {
+			return err
+		}
+		if erasure.AlertsDeleted, err = deleteAlertsByUser(ctx, tx, userID); err != nil {
+			return err
+		}
+
+		result, err := tx.ExecContext(ctx, `DELETE FROM user_profiles WHERE scaleway_user_id = $1`, userID)
+		if err != nil {
+			return fmt.Errorf("failed to delete user profile: %w", err)
+		}
+		if erasure.ProfilesDeleted, err = result.RowsAffected(); err != nil {
+			return fmt.Errorf("failed to count deleted user profiles: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO data_erasures (id, subject, requested_by, events_deleted, alerts_deleted, profiles_deleted, erased_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`, erasure.ID, userID, requestedBy, erasure.EventsDeleted, erasure.AlertsDeleted, erasure.ProfilesDeleted, erasure.ErasedAt)
+		if err != nil {
+			return fmt.Errorf("failed to record erasure: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return erasure, nil
+}
+
 // Helper functions for PostgreSQL array handling
 func pqArray(uuids []uuid.UUID) string {
 	if len(uuids) == 0 {
@@ -468,6 +1984,22 @@ func pqArray(uuids []uuid.UUID) string {
 	return fmt.Sprintf("{%s}", strings.Join(strs, ","))
 }
 
+func pqTextArray(values []string) string {
+	strs := make([]string, len(values))
+	for i, v := range values {
+		v = strings.ReplaceAll(v, `\`, `\\`)
+		v = strings.ReplaceAll(v, `"`, `\"`)
+		strs[i] = fmt.Sprintf(`"%s"`, v)
+	}
+	return fmt.Sprintf("{%s}", strings.Join(strs, ","))
+}
+
+// escapeLike escapes LIKE wildcards so value is matched literally
+func escapeLike(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return replacer.Replace(value)
+}
+
 func parseUUIDArray(str string) []uuid.UUID {
 	// Remove curly braces
 	str = strings.Trim(str, "{}")
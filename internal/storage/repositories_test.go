@@ -0,0 +1,344 @@
+package storage
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/scaleway/audit-sentinel/internal/models"
+	"github.com/scaleway/audit-sentinel/internal/storage/storagetest"
+)
+
+// eventRow is an events row as selected with eventColumns
+func eventRow(id uuid.UUID, eventID string, ts time.Time) []driver.Value {
+	return []driver.Value{
+		id.String(), eventID, []byte(`{"id": "` + eventID + `"}`), "auth.failed", "alice@example.com", "iam",
+		"203.0.113.7", "public", "fr-par", "project-1", "org-1", ts, false, ts,
+	}
+}
+
+// alertRow is an alerts row as selected with alertColumns
+func alertRow(id uuid.UUID, eventRefs []uuid.UUID, ts time.Time) []driver.Value {
+	return []driver.Value{
+		id.String(), pqArray(eventRefs), "failed_login_spike", "HIGH", "alice@example.com", "project-1",
+		"5 failed logins", "OPEN", []byte(`{"count": 5}`), nil, nil, ts, ts,
+	}
+}
+
+func TestCheckStatusTransition(t *testing.T) {
+	const (
+		open          = models.AlertStatusOpen
+		investigating = models.AlertStatusInvestigating
+		resolved      = models.AlertStatusResolved
+		falsePositive = models.AlertStatusFalsePositive
+	)
+	tests := []struct {
+		from, to models.AlertStatus
+		reason   string
+		valid    bool
+	}{
+		{from: open, to: investigating, valid: true},
+		{from: open, to: resolved, valid: true},
+		{from: open, to: falsePositive, valid: true},
+		{from: investigating, to: open, valid: true},
+		{from: investigating, to: resolved, valid: true},
+		{from: investigating, to: falsePositive, valid: true},
+		{from: resolved, to: open, reason: "recurred", valid: true},
+		{from: falsePositive, to: open, reason: "was real", valid: true},
+
+		{from: open, to: open},
+		{from: resolved, to: resolved},
+		{from: resolved, to: open},
+		{from: resolved, to: open, reason: "   "},
+		{from: resolved, to: investigating, reason: "recurred"},
+		{from: resolved, to: falsePositive, reason: "not real"},
+		{from: falsePositive, to: open},
+		{from: falsePositive, to: resolved, reason: "fixed"},
+		{from: "UNKNOWN", to: open, reason: "x"},
+	}
+
+	for _, tt := range tests {
+		name := string(tt.from) + "->" + string(tt.to)
+		if tt.reason != "" {
+			name += " with reason"
+		}
+		t.Run(name, func(t *testing.T) {
+			err := checkStatusTransition(tt.from, tt.to, tt.reason)
+			if tt.valid && err != nil {
+				t.Errorf("checkStatusTransition = %v, want allowed", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidStatusTransition) {
+				t.Errorf("checkStatusTransition = %v, want ErrInvalidStatusTransition", err)
+			}
+		})
+	}
+}
+
+func TestNotFoundErrorsSurviveWrapping(t *testing.T) {
+	ctx := context.Background()
+	db := storagetest.New(t, nil)
+
+	_, err := NewAlertRepository(db.DB).GetAlert(ctx, uuid.New())
+	if wrapped := fmt.Errorf("failed to load alert for remediation: %w", err); !errors.Is(wrapped, ErrAlertNotFound) {
+		t.Errorf("wrapped GetAlert error = %v, want ErrAlertNotFound", wrapped)
+	}
+
+	events := NewEventRepository(db.DB)
+	_, err = events.GetEventByID(ctx, uuid.New())
+	if wrapped := fmt.Errorf("failed to expand alert: %w", err); !errors.Is(wrapped, ErrEventNotFound) {
+		t.Errorf("wrapped GetEventByID error = %v, want ErrEventNotFound", wrapped)
+	}
+	_, err = events.GetEventByEventID(ctx, "evt-missing")
+	if wrapped := fmt.Errorf("failed to reprocess: %w", err); !errors.Is(wrapped, ErrEventNotFound) {
+		t.Errorf("wrapped GetEventByEventID error = %v, want ErrEventNotFound", wrapped)
+	}
+	if errors.Is(err, ErrAlertNotFound) {
+		t.Error("a missing event is reported as a missing alert")
+	}
+}
+
+func TestLookupFailuresAreNotNotFound(t *testing.T) {
+	ctx := context.Background()
+	queryErr := errors.New("connection reset by peer")
+	db := storagetest.New(t, func(storagetest.Query) storagetest.Result {
+		return storagetest.Result{Err: queryErr}
+	})
+
+	_, err := NewAlertRepository(db.DB).GetAlert(ctx, uuid.New())
+	if errors.Is(err, ErrAlertNotFound) || !errors.Is(err, queryErr) {
+		t.Errorf("GetAlert = %v, want the wrapped query error", err)
+	}
+	_, err = NewEventRepository(db.DB).GetEventByID(ctx, uuid.New())
+	if errors.Is(err, ErrEventNotFound) || !errors.Is(err, queryErr) {
+		t.Errorf("GetEventByID = %v, want the wrapped query error", err)
+	}
+}
+
+func TestGetAlert(t *testing.T) {
+	ctx := context.Background()
+	id := uuid.New()
+	refs := []uuid.UUID{uuid.New(), uuid.New()}
+	created := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("not found", func(t *testing.T) {
+		db := storagetest.New(t, nil)
+		alert, err := NewAlertRepository(db.DB).GetAlert(ctx, id)
+		if !errors.Is(err, ErrAlertNotFound) || alert != nil {
+			t.Errorf("GetAlert = %v, %v, want nil and ErrAlertNotFound", alert, err)
+		}
+		if queries := db.Queries(); len(queries) != 1 || queries[0].Args[0] != id.String() {
+			t.Errorf("queries = %+v, want one lookup of %s", queries, id)
+		}
+	})
+
+	t.Run("found", func(t *testing.T) {
+		db := storagetest.New(t, func(storagetest.Query) storagetest.Result {
+			return storagetest.Result{Rows: [][]driver.Value{alertRow(id, refs, created)}}
+		})
+		alert, err := NewAlertRepository(db.DB).GetAlert(ctx, id)
+		if err != nil {
+			t.Fatalf("GetAlert: %v", err)
+		}
+		if alert.ID != id || fmt.Sprint(alert.EventRefs) != fmt.Sprint(refs) || alert.Status != models.AlertStatusOpen {
+			t.Errorf("alert = %s refs %v status %s, want %s refs %v status OPEN", alert.ID, alert.EventRefs, alert.Status, id, refs)
+		}
+		if alert.Evidence["count"] != float64(5) || alert.AcknowledgedAt != nil {
+			t.Errorf("evidence = %v, acknowledged at %v, want the stored evidence and no acknowledgement", alert.Evidence, alert.AcknowledgedAt)
+		}
+	})
+
+	t.Run("unreadable row", func(t *testing.T) {
+		row := alertRow(id, refs, created)
+		row[8] = []byte(`not json`)
+		db := storagetest.New(t, func(storagetest.Query) storagetest.Result {
+			return storagetest.Result{Rows: [][]driver.Value{row}}
+		})
+		alert, err := NewAlertRepository(db.DB).GetAlert(ctx, id)
+		if err == nil || errors.Is(err, ErrAlertNotFound) || alert != nil {
+			t.Errorf("GetAlert = %v, %v, want a decoding error", alert, err)
+		}
+	})
+}
+
+func TestListAlertsFilters(t *testing.T) {
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+	tests := []struct {
+		name        string
+		filter      AlertFilter
+		wantClauses []string
+		wantArgs    []driver.Value
+	}{
+		{
+			name:        "alert type and status",
+			filter:      AlertFilter{AlertType: "failed_login_spike", Status: "OPEN"},
+			wantClauses: []string{"status = $1", "alert_type = $2", "LIMIT $3 OFFSET $4"},
+			wantArgs:    []driver.Value{"OPEN", "failed_login_spike", int64(20), int64(40)},
+		},
+		{
+			name:        "alert type, status and time range",
+			filter:      AlertFilter{AlertType: "failed_login_spike", Status: "OPEN", From: &from, To: &to},
+			wantClauses: []string{"status = $1", "alert_type = $2", "created_at >= $3", "created_at < $4", "LIMIT $5 OFFSET $6"},
+			wantArgs:    []driver.Value{"OPEN", "failed_login_spike", from, to, int64(20), int64(40)},
+		},
+		{
+			name:        "every filter",
+			filter:      AlertFilter{Severity: "HIGH", Status: "OPEN", UserID: "alice", AlertType: "failed_login_spike", ProjectID: "project-1", From: &from},
+			wantClauses: []string{"severity = $1", "status = $2", "user_id = $3", "alert_type = $4", "project_id = $5", "created_at >= $6", "LIMIT $7 OFFSET $8"},
+			wantArgs:    []driver.Value{"HIGH", "OPEN", "alice", "failed_login_spike", "project-1", from, int64(20), int64(40)},
+		},
+	}
+
+	placeholder := regexp.MustCompile(`\$\d+`)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := storagetest.New(t, nil)
+			if _, err := NewAlertRepository(db.DB).ListAlerts(context.Background(), 20, 40, tt.filter, AlertOrderCreatedDesc); err != nil {
+				t.Fatalf("ListAlerts: %v", err)
+			}
+
+			query := db.Queries()[0]
+			for _, clause := range tt.wantClauses {
+				if !strings.Contains(query.SQL, clause) {
+					t.Errorf("query lacks %q:\n%s", clause, query.SQL)
+				}
+			}
+			if got := len(placeholder.FindAllString(query.SQL, -1)); got != len(query.Args) {
+				t.Errorf("query has %d placeholders for %d arguments", got, len(query.Args))
+			}
+			if fmt.Sprint(query.Args) != fmt.Sprint(tt.wantArgs) {
+				t.Errorf("args = %v, want %v", query.Args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestGetEventsByIDs(t *testing.T) {
+	ctx := context.Background()
+	ts := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	first, missing, second := uuid.New(), uuid.New(), uuid.New()
+
+	// The database returns the existing events in its own order
+	db := storagetest.New(t, func(storagetest.Query) storagetest.Result {
+		return storagetest.Result{Rows: [][]driver.Value{
+			eventRow(second, "evt-2", ts),
+			eventRow(first, "evt-1", ts),
+		}}
+	})
+	events, err := NewEventRepository(db.DB).GetEventsByIDs(ctx, []uuid.UUID{first, missing, second})
+	if err != nil {
+		t.Fatalf("GetEventsByIDs: %v", err)
+	}
+
+	if len(events) != 2 || events[0].ID != first || events[1].ID != second {
+		t.Fatalf("got %d events, want evt-1 then evt-2 in request order without the missing ID", len(events))
+	}
+	if events[0].EventID != "evt-1" || events[0].Raw["id"] != "evt-1" || events[0].ProjectID != "project-1" {
+		t.Errorf("first event = %+v, want the scanned evt-1", events[0])
+	}
+
+	queries := db.Queries()
+	if len(queries) != 1 {
+		t.Fatalf("ran %d queries, want a single batch query", len(queries))
+	}
+	if !strings.Contains(queries[0].SQL, "id = ANY($1::uuid[])") {
+		t.Errorf("query = %s, want a single ANY($1) lookup", queries[0].SQL)
+	}
+	if want := fmt.Sprintf(`{"%s","%s","%s"}`, first, missing, second); queries[0].Args[0] != want {
+		t.Errorf("IDs argument = %v, want %s", queries[0].Args[0], want)
+	}
+}
+
+func TestGetEventsByIDsEmpty(t *testing.T) {
+	db := storagetest.New(t, nil)
+	for _, ids := range [][]uuid.UUID{nil, {}} {
+		events, err := NewEventRepository(db.DB).GetEventsByIDs(context.Background(), ids)
+		if err != nil || events == nil || len(events) != 0 {
+			t.Errorf("GetEventsByIDs(%v) = %#v, %v, want an empty slice", ids, events, err)
+		}
+	}
+	if queries := db.Queries(); len(queries) != 0 {
+		t.Errorf("ran %d queries for no IDs, want none", len(queries))
+	}
+}
+
+func TestListingsReportIterationErrors(t *testing.T) {
+	ctx := context.Background()
+	ts := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	iterErr := errors.New("connection reset by peer")
+	alertID := uuid.New()
+
+	// Each listing reads one row before the connection drops
+	failAfter := func(row []driver.Value) *storagetest.DB {
+		return storagetest.New(t, func(storagetest.Query) storagetest.Result {
+			return storagetest.Result{Rows: [][]driver.Value{row}, IterErr: iterErr}
+		})
+	}
+	tests := []struct {
+		name string
+		list func() (int, error)
+	}{
+		{
+			name: "ListEvents",
+			list: func() (int, error) {
+				db := failAfter(eventRow(uuid.New(), "evt-1", ts))
+				events, err := NewEventRepository(db.DB).ListEvents(ctx, 50, 0, EventFilter{}, EventOrderTimestampDesc)
+				return len(events), err
+			},
+		},
+		{
+			name: "ListAlerts",
+			list: func() (int, error) {
+				db := failAfter(alertRow(alertID, nil, ts))
+				alerts, err := NewAlertRepository(db.DB).ListAlerts(ctx, 50, 0, AlertFilter{}, AlertOrderCreatedDesc)
+				return len(alerts), err
+			},
+		},
+		{
+			name: "GetRemediationLogs",
+			list: func() (int, error) {
+				db := failAfter([]driver.Value{
+					uuid.NewString(), alertID.String(), "analyst@example.com", "revoke_api_key", []byte(`{"key_id": "SCWKEY123"}`), "success", ts,
+				})
+				logs, err := NewRemediationRepository(db.DB).GetRemediationLogs(ctx, alertID)
+				return len(logs), err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := tt.list()
+			if !errors.Is(err, iterErr) {
+				t.Errorf("error = %v, want the iteration error", err)
+			}
+			if n != 0 {
+				t.Errorf("returned %d rows alongside the error, want none", n)
+			}
+		})
+	}
+}
+
+func TestSeenBeforeScopesToProject(t *testing.T) {
+	db := storagetest.New(t, func(storagetest.Query) storagetest.Result {
+		return storagetest.Result{Rows: [][]driver.Value{{true, false}}}
+	})
+	actorSeen, ipSeen, err := NewEventRepository(db.DB).SeenBefore(context.Background(), "project-b", "alice@example.com", "203.0.113.7")
+	if err != nil || !actorSeen || ipSeen {
+		t.Fatalf("SeenBefore = %v, %v, %v, want the scanned flags", actorSeen, ipSeen, err)
+	}
+
+	query := db.Queries()[0]
+	if got := strings.Count(query.SQL, "($3::text = '' OR project_id = $3)"); got != 2 {
+		t.Errorf("query scopes %d of 2 lookups to the project:\n%s", got, query.SQL)
+	}
+	if fmt.Sprint(query.Args) != "[alice@example.com 203.0.113.7 project-b]" {
+		t.Errorf("args = %v, want actor, IP and project", query.Args)
+	}
+}
@@ -1,31 +1,96 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	_ "github.com/lib/pq"
+	"github.com/scaleway/audit-sentinel/internal/config"
 )
 
+// defaultConnectTimeout is used when no connect timeout is configured
+const defaultConnectTimeout = 5 * time.Second
+
 // Storage represents the database storage layer
 type Storage struct {
 	db *sql.DB
 }
 
-// NewStorage creates a new storage instance
-func NewStorage(dbURL string) (*Storage, error) {
-	db, err := sql.Open("postgres", dbURL)
+// NewStorage creates a new storage instance with the configured connection pool
+func NewStorage(cfg config.DatabaseConfig) (*Storage, error) {
+	db, err := sql.Open("postgres", withStatementTimeout(cfg.URL, cfg.StatementTimeout))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	if err := db.Ping(); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %w", err)
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	if err := pingWithRetry(db, cfg); err != nil {
+		db.Close()
+		return nil, err
 	}
 
 	return &Storage{db: db}, nil
 }
 
+// pingWithRetry pings db, retrying up to cfg.ConnectRetries times with
+// exponential backoff starting at cfg.ConnectRetryBackoff
+func pingWithRetry(db *sql.DB, cfg config.DatabaseConfig) error {
+	timeout := cfg.ConnectTimeout
+	if timeout <= 0 {
+		timeout = defaultConnectTimeout
+	}
+	backoff := cfg.ConnectRetryBackoff
+
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		err := db.PingContext(ctx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if attempt >= cfg.ConnectRetries {
+			return fmt.Errorf("failed to ping database: %w", err)
+		}
+
+		log.Printf("Database not reachable (attempt %d/%d), retrying in %s: %v", attempt+1, cfg.ConnectRetries+1, backoff, err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// withStatementTimeout adds a statement_timeout run-time parameter to dsn,
+// which lib/pq sends to the server when each connection starts. dsn may be
+// a URL or a key=value connection string.
+func withStatementTimeout(dsn string, timeout time.Duration) string {
+	if timeout <= 0 {
+		return dsn
+	}
+	ms := strconv.FormatInt(timeout.Milliseconds(), 10)
+
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			// Leave it to sql.Open to report the malformed URL
+			return dsn
+		}
+		q := u.Query()
+		q.Set("statement_timeout", ms)
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+	return strings.TrimSpace(dsn + " statement_timeout=" + ms)
+}
+
 // Close closes the database connection
 func (s *Storage) Close() error {
 	return s.db.Close()
@@ -0,0 +1,154 @@
+// Package storagetest provides a scripted database/sql driver, so
+// repositories and the handlers using them can be tested without a
+// PostgreSQL server.
+package storagetest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+)
+
+// Query is a statement run against a DB with its converted arguments
+type Query struct {
+	SQL  string
+	Args []driver.Value
+}
+
+// Result is the scripted answer to one statement
+type Result struct {
+	// Rows are returned in order; each must hold one value per selected column
+	Rows [][]driver.Value
+	// Err fails the statement itself
+	Err error
+	// IterErr is returned once Rows are exhausted, as when the connection
+	// drops partway through a result set
+	IterErr error
+	// RowsAffected is reported for statements run with Exec
+	RowsAffected int64
+}
+
+// DB is a database answering every statement with its respond func and
+// recording the statements it ran
+type DB struct {
+	*sql.DB
+
+	mu      sync.Mutex
+	respond func(Query) Result
+	queries []Query
+}
+
+// New returns a DB answering statements with respond, closed when the test
+// ends. A nil respond answers every statement with no rows.
+func New(t testing.TB, respond func(Query) Result) *DB {
+	t.Helper()
+	if respond == nil {
+		respond = func(Query) Result { return Result{} }
+	}
+	db := &DB{respond: respond}
+	db.DB = sql.OpenDB(connector{db})
+	t.Cleanup(func() { db.DB.Close() })
+	return db
+}
+
+// Queries returns the statements run so far, in order
+func (db *DB) Queries() []Query {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return append([]Query(nil), db.queries...)
+}
+
+func (db *DB) run(query string, args []driver.NamedValue) Result {
+	values := make([]driver.Value, len(args))
+	for idx, arg := range args {
+		values[idx] = arg.Value
+	}
+	q := Query{SQL: query, Args: values}
+
+	db.mu.Lock()
+	db.queries = append(db.queries, q)
+	respond := db.respond
+	db.mu.Unlock()
+	return respond(q)
+}
+
+type connector struct {
+	db *DB
+}
+
+func (c connector) Connect(context.Context) (driver.Conn, error) {
+	return conn{c.db}, nil
+}
+
+func (c connector) Driver() driver.Driver {
+	return fakeDriver{}
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("storagetest: open through storagetest.New")
+}
+
+type conn struct {
+	db *DB
+}
+
+func (c conn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("storagetest: prepared statements are not supported")
+}
+
+func (c conn) Close() error              { return nil }
+func (c conn) Begin() (driver.Tx, error) { return tx{}, nil }
+
+func (c conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	result := c.db.run(query, args)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	return &rows{result: result}, nil
+}
+
+func (c conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	result := c.db.run(query, args)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	return driver.RowsAffected(result.RowsAffected), nil
+}
+
+type tx struct{}
+
+func (tx) Commit() error   { return nil }
+func (tx) Rollback() error { return nil }
+
+type rows struct {
+	result Result
+	next   int
+}
+
+// Columns returns placeholder names; database/sql only needs their count
+func (r *rows) Columns() []string {
+	if len(r.result.Rows) == 0 {
+		return nil
+	}
+	return make([]string, len(r.result.Rows[0]))
+}
+
+func (r *rows) Close() error { return nil }
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.next >= len(r.result.Rows) {
+		if r.result.IterErr != nil {
+			return r.result.IterErr
+		}
+		return io.EOF
+	}
+	copy(dest, r.result.Rows[r.next])
+	r.next++
+	return nil
+}
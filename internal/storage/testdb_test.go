@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// newTestDatabase creates an empty database on the server at
+// TEST_DATABASE_URL, dropped when the test ends, and returns its URL. Tests
+// calling it are skipped when TEST_DATABASE_URL is unset.
+func newTestDatabase(t *testing.T) string {
+	t.Helper()
+	serverURL := os.Getenv("TEST_DATABASE_URL")
+	if serverURL == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	parsed, err := url.Parse(serverURL)
+	if err != nil || parsed.Scheme == "" {
+		t.Fatalf("TEST_DATABASE_URL must be a postgres:// URL: %v", err)
+	}
+
+	admin, err := sql.Open("postgres", serverURL)
+	if err != nil {
+		t.Fatalf("failed to open test server: %v", err)
+	}
+	name := "audit_sentinel_test_" + strings.ReplaceAll(uuid.NewString(), "-", "")[:12]
+	if _, err := admin.Exec(fmt.Sprintf("CREATE DATABASE %s", name)); err != nil {
+		admin.Close()
+		t.Fatalf("failed to create test database: %v", err)
+	}
+	t.Cleanup(func() {
+		defer admin.Close()
+		if _, err := admin.Exec(fmt.Sprintf("DROP DATABASE IF EXISTS %s WITH (FORCE)", name)); err != nil {
+			t.Errorf("failed to drop test database %s: %v", name, err)
+		}
+	})
+
+	parsed.Path = "/" + name
+	return parsed.String()
+}
+
+// newTestMigrator returns a migrator for a fresh test database
+func newTestMigrator(t *testing.T) *Migrator {
+	t.Helper()
+	migrator, err := NewMigrator(newTestDatabase(t))
+	if err != nil {
+		t.Fatalf("NewMigrator: %v", err)
+	}
+	t.Cleanup(func() { migrator.Close() })
+	return migrator
+}
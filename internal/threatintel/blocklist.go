@@ -0,0 +1,139 @@
+// Package threatintel matches event IPs against a blocklist of known-bad
+// addresses and networks.
+package threatintel
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/netip"
+	"strings"
+)
+
+// Blocklist is an immutable set of IP prefixes stored in a binary trie, so
+// a lookup costs at most one step per address bit
+type Blocklist struct {
+	v4      *trieNode
+	v6      *trieNode
+	entries int
+}
+
+type trieNode struct {
+	children [2]*trieNode
+	// prefix is set on nodes terminating a listed network
+	prefix *netip.Prefix
+}
+
+// ParseBlocklist reads one IP or CIDR per line. Blank lines and anything
+// after a '#' are ignored; an unparsable entry fails the whole list.
+func ParseBlocklist(r io.Reader) (*Blocklist, error) {
+	list := &Blocklist{v4: &trieNode{}, v6: &trieNode{}}
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		entry, _, _ := strings.Cut(scanner.Text(), "#")
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		prefix, err := parseEntry(entry)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		list.insert(prefix)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read blocklist: %w", err)
+	}
+
+	return list, nil
+}
+
+// parseEntry parses an IP or CIDR into a masked prefix
+func parseEntry(entry string) (netip.Prefix, error) {
+	if strings.Contains(entry, "/") {
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("invalid CIDR %q: %w", entry, err)
+		}
+		if prefix.Addr().Is4In6() {
+			// A mapped prefix shorter than the ::ffff:0:0/96 block spans
+			// addresses outside IPv4 and has no IPv4 equivalent
+			if prefix.Bits() < 96 {
+				return netip.Prefix{}, fmt.Errorf("invalid CIDR %q: IPv4-mapped prefix shorter than /96", entry)
+			}
+			prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+		}
+		return prefix.Masked(), nil
+	}
+
+	addr, err := netip.ParseAddr(entry)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid IP %q: %w", entry, err)
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+func (b *Blocklist) insert(prefix netip.Prefix) {
+	node := b.root(prefix.Addr())
+	addr := prefix.Addr().AsSlice()
+	for bit := 0; bit < prefix.Bits(); bit++ {
+		next := addrBit(addr, bit)
+		if node.children[next] == nil {
+			node.children[next] = &trieNode{}
+		}
+		node = node.children[next]
+	}
+	if node.prefix == nil {
+		node.prefix = &prefix
+		b.entries++
+	}
+}
+
+// Lookup returns the most specific listed network containing ip
+func (b *Blocklist) Lookup(ip string) (netip.Prefix, bool) {
+	addr, err := netip.ParseAddr(strings.Trim(strings.TrimSpace(ip), "[]"))
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	addr = addr.Unmap()
+
+	var match *netip.Prefix
+	node := b.root(addr)
+	bytes := addr.AsSlice()
+	for bit := 0; node != nil; bit++ {
+		if node.prefix != nil {
+			match = node.prefix
+		}
+		if bit == addr.BitLen() {
+			break
+		}
+		node = node.children[addrBit(bytes, bit)]
+	}
+
+	if match == nil {
+		return netip.Prefix{}, false
+	}
+	return *match, true
+}
+
+// Len returns the number of distinct listed networks
+func (b *Blocklist) Len() int {
+	return b.entries
+}
+
+func (b *Blocklist) root(addr netip.Addr) *trieNode {
+	if addr.Is4() {
+		return b.v4
+	}
+	return b.v6
+}
+
+// addrBit returns bit i of addr, counting from the most significant
+func addrBit(addr []byte, i int) int {
+	return int(addr[i/8]>>(7-uint(i%8))) & 1
+}
@@ -0,0 +1,100 @@
+package threatintel
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestParseEntry(t *testing.T) {
+	tests := []struct {
+		entry   string
+		want    string
+		wantErr bool
+	}{
+		{entry: "192.0.2.7", want: "192.0.2.7/32"},
+		{entry: "192.0.2.7/24", want: "192.0.2.0/24"},
+		{entry: "2001:db8::1", want: "2001:db8::1/128"},
+		{entry: "2001:db8::1/32", want: "2001:db8::/32"},
+		{entry: "::ffff:192.0.2.7", want: "192.0.2.7/32"},
+		{entry: "::ffff:192.0.2.7/120", want: "192.0.2.0/24"},
+		{entry: "::ffff:0:0/96", want: "0.0.0.0/0"},
+		{entry: "::ffff:0:0/80", wantErr: true},
+		{entry: "::ffff:192.0.2.7/95", wantErr: true},
+		{entry: "192.0.2.7/33", wantErr: true},
+		{entry: "not-an-ip", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseEntry(tt.entry)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseEntry(%q) = %s, want error", tt.entry, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseEntry(%q) failed: %v", tt.entry, err)
+			continue
+		}
+		if got != netip.MustParsePrefix(tt.want) {
+			t.Errorf("parseEntry(%q) = %s, want %s", tt.entry, got, tt.want)
+		}
+	}
+}
+
+func TestParseBlocklistRejectsShortMappedPrefix(t *testing.T) {
+	_, err := ParseBlocklist(strings.NewReader("192.0.2.0/24\n::ffff:0:0/80\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Fatalf("ParseBlocklist error = %v, want line 2 rejected", err)
+	}
+}
+
+func TestBlocklistLookup(t *testing.T) {
+	list, err := ParseBlocklist(strings.NewReader(`
+# known-bad networks
+192.0.2.0/24
+192.0.2.128/25   # narrower block inside the /24
+198.51.100.9
+::ffff:203.0.113.0/120
+2001:db8::/32
+2001:db8:1::/48
+192.0.2.0/24     # duplicate
+`))
+	if err != nil {
+		t.Fatalf("ParseBlocklist failed: %v", err)
+	}
+	if got := list.Len(); got != 6 {
+		t.Errorf("Len() = %d, want 6", got)
+	}
+
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{ip: "192.0.2.1", want: "192.0.2.0/24"},
+		{ip: "192.0.2.200", want: "192.0.2.128/25"},
+		{ip: "::ffff:192.0.2.1", want: "192.0.2.0/24"},
+		{ip: "198.51.100.9", want: "198.51.100.9/32"},
+		{ip: "198.51.100.10"},
+		{ip: "203.0.113.5", want: "203.0.113.0/24"},
+		{ip: " 2001:db8::5 ", want: "2001:db8::/32"},
+		{ip: "[2001:db8:1::5]", want: "2001:db8:1::/48"},
+		{ip: "2001:db9::1"},
+		{ip: "10.0.0.1"},
+		{ip: "garbage"},
+	}
+
+	for _, tt := range tests {
+		got, ok := list.Lookup(tt.ip)
+		if tt.want == "" {
+			if ok {
+				t.Errorf("Lookup(%q) = %s, want no match", tt.ip, got)
+			}
+			continue
+		}
+		if !ok || got != netip.MustParsePrefix(tt.want) {
+			t.Errorf("Lookup(%q) = %s, %v, want %s", tt.ip, got, ok, tt.want)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package threatintel
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Match describes a blocklist hit for an IP
+type Match struct {
+	// Source is the file path or URL the blocklist was loaded from
+	Source string `json:"source"`
+	// Entry is the listed network the IP fell into
+	Entry string `json:"matched_entry"`
+}
+
+// Feed keeps a blocklist loaded from a file or URL and reloads it on an
+// interval. Lookups always see a complete list: a failed reload keeps the
+// previous one.
+type Feed struct {
+	source     string
+	interval   time.Duration
+	httpClient *http.Client
+	list       atomic.Pointer[Blocklist]
+}
+
+// NewFeed creates a feed for source, an http(s) URL or a file path. Nothing
+// is matched until the first successful Refresh.
+func NewFeed(source string, interval time.Duration) *Feed {
+	return &Feed{
+		source:     source,
+		interval:   interval,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Lookup reports whether ip is on the blocklist
+func (f *Feed) Lookup(ip string) (Match, bool) {
+	list := f.list.Load()
+	if list == nil {
+		return Match{}, false
+	}
+	prefix, ok := list.Lookup(ip)
+	if !ok {
+		return Match{}, false
+	}
+	return Match{Source: f.source, Entry: prefix.String()}, true
+}
+
+// Refresh loads the blocklist from its source once
+func (f *Feed) Refresh(ctx context.Context) error {
+	body, err := f.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	list, err := ParseBlocklist(body)
+	if err != nil {
+		return fmt.Errorf("failed to parse blocklist %s: %w", f.source, err)
+	}
+
+	f.list.Store(list)
+	log.Printf("Loaded %d threat intel entries from %s", list.Len(), f.source)
+	return nil
+}
+
+// Start loads the blocklist, then reloads it on every interval until ctx is
+// cancelled
+func (f *Feed) Start(ctx context.Context) error {
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := f.Refresh(ctx); err != nil {
+			log.Printf("Threat intel refresh failed, keeping previous list: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (f *Feed) open(ctx context.Context) (io.ReadCloser, error) {
+	if !strings.HasPrefix(f.source, "http://") && !strings.HasPrefix(f.source, "https://") {
+		file, err := os.Open(f.source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open blocklist: %w", err)
+		}
+		return file, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.source, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build blocklist request: %w", err)
+	}
+	resp, err := f.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download blocklist: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to download blocklist: %s", resp.Status)
+	}
+	return resp.Body, nil
+}
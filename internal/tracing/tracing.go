@@ -0,0 +1,65 @@
+// Package tracing configures OpenTelemetry tracing for Audit Sentinel.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/scaleway/audit-sentinel/internal/config"
+)
+
+const serviceName = "audit-sentinel"
+
+// Span attribute keys shared across packages
+const (
+	AttrEventID   = attribute.Key("event_id")
+	AttrRuleName  = attribute.Key("rule_name")
+	AttrAlertType = attribute.Key("alert_type")
+	AttrSource    = attribute.Key("source")
+	AttrTenant    = attribute.Key("tenant")
+)
+
+// Setup installs the global tracer provider exporting spans over OTLP/HTTP
+// and returns a function flushing and stopping it. With tracing disabled
+// the default no-op provider is kept and the returned function does nothing.
+func Setup(ctx context.Context, cfg config.ObservabilityConfig) (func(context.Context) error, error) {
+	if !cfg.TracingEnabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.TracingEndpoint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.TracingSampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the tracer for an instrumented package
+func Tracer(name string) trace.Tracer {
+	return otel.Tracer("github.com/scaleway/audit-sentinel/" + name)
+}
+
+// End records err on span, if any, and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,2 @@
+-- Speeds up lookups of alerts referencing a given event (retention purge)
+CREATE INDEX idx_alerts_event_refs ON alerts USING GIN (event_refs);
@@ -0,0 +1,2 @@
+-- Supports listing events ordered by ingestion time
+CREATE INDEX idx_events_created_at ON events(created_at);
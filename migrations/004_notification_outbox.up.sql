@@ -0,0 +1,13 @@
+-- Alert notifications awaiting delivery, written in the same transaction as
+-- the alert so none are lost if the process stops before sending
+CREATE TABLE notification_outbox (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    alert_id UUID NOT NULL REFERENCES alerts(id) ON DELETE CASCADE,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_error TEXT,
+    sent_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_notification_outbox_pending ON notification_outbox(next_attempt_at) WHERE sent_at IS NULL;
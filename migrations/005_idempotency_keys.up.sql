@@ -0,0 +1,12 @@
+-- Responses of requests made with an Idempotency-Key header, replayed when
+-- the same key is retried. A NULL status_code marks a request in progress.
+CREATE TABLE idempotency_keys (
+    key VARCHAR(255) PRIMARY KEY,
+    request_hash VARCHAR(64) NOT NULL,
+    status_code INTEGER,
+    response_body BYTEA,
+    content_type VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_idempotency_keys_created_at ON idempotency_keys(created_at);
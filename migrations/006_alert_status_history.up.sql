@@ -0,0 +1,11 @@
+-- Every alert status transition and who made it
+CREATE TABLE alert_status_history (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    alert_id UUID NOT NULL REFERENCES alerts(id) ON DELETE CASCADE,
+    from_status VARCHAR(20) NOT NULL,
+    to_status VARCHAR(20) NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_alert_status_history_alert_id ON alert_status_history(alert_id, at);
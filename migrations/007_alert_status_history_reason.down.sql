@@ -0,0 +1 @@
+ALTER TABLE alert_status_history DROP COLUMN IF EXISTS reason;
@@ -0,0 +1,2 @@
+-- Why a status was changed; required when reopening an alert
+ALTER TABLE alert_status_history ADD COLUMN reason TEXT;
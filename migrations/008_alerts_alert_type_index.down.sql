@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_alerts_alert_type_created_at;
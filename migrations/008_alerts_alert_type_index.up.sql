@@ -0,0 +1,2 @@
+-- Supports filtering alerts by type within a time range
+CREATE INDEX idx_alerts_alert_type_created_at ON alerts(alert_type, created_at);
@@ -0,0 +1,2 @@
+ALTER TABLE alerts DROP COLUMN IF EXISTS acknowledged_by;
+ALTER TABLE alerts DROP COLUMN IF EXISTS acknowledged_at;
@@ -0,0 +1,3 @@
+-- Who claimed an alert and when, independent of its status
+ALTER TABLE alerts ADD COLUMN acknowledged_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE alerts ADD COLUMN acknowledged_by VARCHAR(255);
@@ -0,0 +1,13 @@
+-- Suppress alerts of a type, optionally only for one user or resource, until expires_at
+CREATE TABLE alert_mutes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    alert_type VARCHAR(100) NOT NULL,
+    user_id VARCHAR(255),
+    resource VARCHAR(500),
+    reason TEXT,
+    created_by VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_alert_mutes_alert_type ON alert_mutes(alert_type, expires_at);
@@ -0,0 +1,14 @@
+-- Planned maintenance during which alerts are suppressed or only tagged
+CREATE TABLE maintenance_windows (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ends_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    alert_type VARCHAR(100),
+    mode VARCHAR(20) NOT NULL CHECK (mode IN ('suppress', 'tag')),
+    reason TEXT,
+    created_by VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX idx_maintenance_windows_ends_at ON maintenance_windows(ends_at);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_events_ip_scope;
+ALTER TABLE events DROP COLUMN IF EXISTS ip_scope;
@@ -0,0 +1,5 @@
+-- Whether the event's IP is internal (private, loopback) or public. Events
+-- stored before this migration keep a NULL scope.
+ALTER TABLE events ADD COLUMN ip_scope VARCHAR(10);
+
+CREATE INDEX idx_events_ip_scope ON events(ip_scope);
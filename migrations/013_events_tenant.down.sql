@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_events_tenant_timestamp;
+ALTER TABLE events DROP COLUMN IF EXISTS organization_id;
+ALTER TABLE events DROP COLUMN IF EXISTS project_id;
@@ -0,0 +1,9 @@
+-- Tenant (project and organization) each event was ingested for. Events
+-- stored before this migration have NULL tenant columns, so the first cycle
+-- per tenant refetches from the start and relies on event_id deduplication.
+ALTER TABLE events ADD COLUMN project_id VARCHAR(100);
+ALTER TABLE events ADD COLUMN organization_id VARCHAR(100);
+
+-- Matches the per-tenant cursor lookup, which treats NULL as ''
+CREATE INDEX idx_events_tenant_timestamp
+    ON events ((COALESCE(organization_id, '')), (COALESCE(project_id, '')), timestamp);
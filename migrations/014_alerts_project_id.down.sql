@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_events_project_id_actor_timestamp;
+DROP INDEX IF EXISTS idx_alerts_project_id_created_at;
+ALTER TABLE alerts DROP COLUMN IF EXISTS project_id;
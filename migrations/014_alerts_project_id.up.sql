@@ -0,0 +1,5 @@
+-- Project of the events an alert was raised for
+ALTER TABLE alerts ADD COLUMN project_id VARCHAR(100);
+
+CREATE INDEX idx_alerts_project_id_created_at ON alerts(project_id, created_at);
+CREATE INDEX idx_events_project_id_actor_timestamp ON events(project_id, actor, timestamp);
@@ -0,0 +1,12 @@
+-- Audit trail of personal data erasures (GDPR requests)
+CREATE TABLE data_erasures (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    subject VARCHAR(255) NOT NULL,
+    requested_by VARCHAR(255) NOT NULL,
+    events_deleted BIGINT NOT NULL,
+    alerts_deleted BIGINT NOT NULL,
+    profiles_deleted BIGINT NOT NULL,
+    erased_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_data_erasures_erased_at ON data_erasures(erased_at);
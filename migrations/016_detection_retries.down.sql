@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_events_detection_retry;
+ALTER TABLE events DROP COLUMN IF EXISTS detection_retry_at;
+ALTER TABLE events DROP COLUMN IF EXISTS detection_attempts;
+ALTER TABLE events DROP COLUMN IF EXISTS detection_failed_rules;
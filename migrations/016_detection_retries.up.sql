@@ -0,0 +1,9 @@
+-- Dead letter for events whose detection failed. detection_failed_rules is
+-- NULL once detection has succeeded; while set, the event is retried at
+-- detection_retry_at, or never again once that is NULL (dead-lettered).
+ALTER TABLE events ADD COLUMN detection_failed_rules TEXT[];
+ALTER TABLE events ADD COLUMN detection_attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE events ADD COLUMN detection_retry_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX idx_events_detection_retry ON events(detection_retry_at)
+    WHERE detection_failed_rules IS NOT NULL;
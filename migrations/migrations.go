@@ -0,0 +1,10 @@
+// Package migrations embeds the SQL migration files so binaries can apply
+// them without depending on the working directory.
+package migrations
+
+import "embed"
+
+// FS holds every *.sql migration file in this directory
+//
+//go:embed *.sql
+var FS embed.FS
@@ -7,39 +7,179 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
 	defaultPageSize = 100
 	maxPages        = 500
+	// defaultMaxStalePages is how many consecutive pages entirely before
+	// since are paged through before the fetch gives up
+	defaultMaxStalePages = 3
+	// maxSkipReasons caps the sample of reasons kept in a SkipReport
+	maxSkipReasons = 5
 )
 
+// TimestampLayouts lists the layouts tried, in order, when parsing event
+// timestamps. Numeric Unix epochs are handled separately.
+var TimestampLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05",
+}
+
+// tracer records a span per event fetch
+var tracer = otel.Tracer("github.com/scaleway/audit-sentinel/pkg/scaleway")
+
+// ErrMissingAPIKey is returned when events are fetched without an API key
+// while mock mode is off
+var ErrMissingAPIKey = errors.New("scaleway API key is not set (set SCALEWAY_API_KEY, or SCALEWAY_MOCK=true for fake events)")
+
+// ErrSinceIgnored is returned when a fetch gives up after too many
+// consecutive pages entirely before since, as an API ignoring the since
+// filter returns
+var ErrSinceIgnored = errors.New("scaleway API appears to ignore the since filter")
+
 // Client represents a Scaleway API client
 type Client struct {
-	apiKey         string
-	projectID      string
-	organizationID string
-	apiURL         string
-	httpClient     *http.Client
+	apiKey string
+	// tenant scopes the IAM calls that are not made for a specific tenant
+	tenant        Tenant
+	apiURL        string
+	httpClient    *http.Client
+	mock          bool
+	mockGenerator *MockGenerator
+	skipHandler   func(SkipReport)
+	endpoints     Endpoints
+	maxStalePages int
+}
+
+// Endpoint is an event list API, relative to the API URL, and the key its
+// responses list the events under
+type Endpoint struct {
+	Path    string
+	ListKey string
+}
+
+// Endpoints are the event list APIs events are fetched from
+type Endpoints struct {
+	Audit          Endpoint
+	Authentication Endpoint
+}
+
+// DefaultEndpoints are the v1alpha1 audit trail and IAM login log APIs
+var DefaultEndpoints = Endpoints{
+	Audit:          Endpoint{Path: "/audit/v1alpha1/events", ListKey: "events"},
+	Authentication: Endpoint{Path: "/iam/v1alpha1/login-logs", ListKey: "login_logs"},
+}
+
+// Tenant is the project and organization events are fetched for. Either ID
+// may be empty.
+type Tenant struct {
+	ProjectID      string
+	OrganizationID string
+}
+
+// String identifies the tenant in logs
+func (t Tenant) String() string {
+	switch {
+	case t.ProjectID != "" && t.OrganizationID != "":
+		return t.OrganizationID + "/" + t.ProjectID
+	case t.ProjectID != "":
+		return t.ProjectID
+	case t.OrganizationID != "":
+		return t.OrganizationID
+	default:
+		return "default"
+	}
+}
+
+// SkipReport describes the malformed entries dropped while fetching one
+// event source
+type SkipReport struct {
+	Source  string
+	Skipped int
+	// Reasons holds a sample of the distinct parse errors
+	Reasons []string
 }
 
 // NewClient creates a new Scaleway API client
 func NewClient(apiKey, projectID, organizationID, apiURL string) *Client {
 	return &Client{
-		apiKey:         strings.TrimSpace(apiKey),
-		projectID:      strings.TrimSpace(projectID),
-		organizationID: strings.TrimSpace(organizationID),
-		apiURL:         strings.TrimRight(strings.TrimSpace(apiURL), "/"),
+		apiKey: strings.TrimSpace(apiKey),
+		tenant: Tenant{
+			ProjectID:      strings.TrimSpace(projectID),
+			OrganizationID: strings.TrimSpace(organizationID),
+		},
+		apiURL: strings.TrimRight(strings.TrimSpace(apiURL), "/"),
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		mockGenerator: &MockGenerator{},
+		endpoints:     DefaultEndpoints,
+		maxStalePages: defaultMaxStalePages,
+	}
+}
+
+// SetMaxStalePages sets how many consecutive pages entirely before since a
+// fetch pages through before failing with ErrSinceIgnored. Values below 1
+// are ignored.
+func (c *Client) SetMaxStalePages(pages int) {
+	if pages > 0 {
+		c.maxStalePages = pages
+	}
+}
+
+// SetEndpoints points the client at other event list APIs, e.g. a newer
+// API version. Empty fields keep their current value.
+func (c *Client) SetEndpoints(endpoints Endpoints) {
+	c.endpoints.Audit = mergeEndpoint(c.endpoints.Audit, endpoints.Audit)
+	c.endpoints.Authentication = mergeEndpoint(c.endpoints.Authentication, endpoints.Authentication)
+}
+
+func mergeEndpoint(current, override Endpoint) Endpoint {
+	if override.Path != "" {
+		current.Path = override.Path
+	}
+	if override.ListKey != "" {
+		current.ListKey = override.ListKey
+	}
+	return current
+}
+
+// SetMock switches the client to serving built-in mock events instead of
+// calling the API
+func (c *Client) SetMock(enabled bool) {
+	c.mock = enabled
+	if enabled {
+		log.Println("WARNING: Scaleway mock mode is enabled; ingested events are FAKE and not from the Scaleway API")
 	}
 }
 
+// SetMockGenerator replaces the generator used in mock mode, e.g. with a
+// seeded one for reproducible events
+func (c *Client) SetMockGenerator(generator *MockGenerator) {
+	c.mockGenerator = generator
+}
+
+// SetSkipHandler registers a callback invoked after each fetch that dropped
+// malformed entries
+func (c *Client) SetSkipHandler(handler func(SkipReport)) {
+	c.skipHandler = handler
+}
+
 // AuditEvent represents a Scaleway audit trail or authentication event
 type AuditEvent struct {
 	ID        string
@@ -49,150 +189,346 @@ type AuditEvent struct {
 	IP        string
 	Timestamp time.Time
 	Source    string
-	Raw       map[string]any
+	// Tenant is the tenant the event was fetched for
+	Tenant Tenant
+	Raw    map[string]any
 }
 
-// FetchAuditEvents retrieves audit trail events from Scaleway.
-
-func (c *Client) FetchAuditEvents(ctx context.Context, since *time.Time) ([]*AuditEvent, error) {
+// FetchAuditEvents retrieves the tenant's audit trail events from Scaleway.
+func (c *Client) FetchAuditEvents(ctx context.Context, tenant Tenant, since *time.Time) ([]*AuditEvent, error) {
+	if c.mock {
+		log.Println("WARNING: returning mock audit events (SCALEWAY_MOCK)")
+		return withTenant(c.mockGenerator.Events(since, "audit"), tenant), nil
+	}
 	if c.apiKey == "" {
-		return c.mockEvents(since, "audit"), nil
+		return nil, ErrMissingAPIKey
 	}
 
-	return c.fetchEvents(ctx, since, "/audit/v1alpha1/events", "events", "audit")
+	return c.fetchEvents(ctx, tenant, since, nil, c.endpoints.Audit, "audit")
 }
 
-// FetchAuthenticationEvents retrieves IAM authentication logs.
-func (c *Client) FetchAuthenticationEvents(ctx context.Context, since *time.Time) ([]*AuditEvent, error) {
+// FetchAuthenticationEvents retrieves the tenant's IAM authentication logs.
+func (c *Client) FetchAuthenticationEvents(ctx context.Context, tenant Tenant, since *time.Time) ([]*AuditEvent, error) {
+	if c.mock {
+		log.Println("WARNING: returning mock authentication events (SCALEWAY_MOCK)")
+		return withTenant(c.mockGenerator.Events(since, "auth"), tenant), nil
+	}
 	if c.apiKey == "" {
-		return c.mockEvents(since, "auth"), nil
+		return nil, ErrMissingAPIKey
 	}
 
-	return c.fetchEvents(ctx, since, "/iam/v1alpha1/login-logs", "login_logs", "authentication")
+	return c.fetchEvents(ctx, tenant, since, nil, c.endpoints.Authentication, "authentication")
 }
 
-func (c *Client) fetchEvents(ctx context.Context, since *time.Time, relativePath, listKey, source string) ([]*AuditEvent, error) {
-	var events []*AuditEvent
-	page := 1
+// FetchAuditEventsBetween retrieves the tenant's audit trail events after
+// from and before to.
+func (c *Client) FetchAuditEventsBetween(ctx context.Context, tenant Tenant, from, to time.Time) ([]*AuditEvent, error) {
+	if c.mock {
+		log.Println("WARNING: returning mock audit events (SCALEWAY_MOCK)")
+		return withTenant(filterBefore(c.mockGenerator.Events(&from, "audit"), to), tenant), nil
+	}
+	if c.apiKey == "" {
+		return nil, ErrMissingAPIKey
+	}
 
-	for page <= maxPages {
-		req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.apiURL+relativePath, nil)
-		if err != nil {
-			return nil, fmt.Errorf("failed to build request: %w", err)
-		}
+	return c.fetchEvents(ctx, tenant, &from, &to, c.endpoints.Audit, "audit")
+}
 
-		q := req.URL.Query()
-		q.Set("page", strconv.Itoa(page))
-		q.Set("page_size", strconv.Itoa(defaultPageSize))
-		q.Set("order", "asc")
-		q.Set("direction", "asc")
-		if since != nil {
-			q.Set("since", since.UTC().Format(time.RFC3339))
-		}
-		if c.projectID != "" {
-			q.Set("project_id", c.projectID)
-		}
-		if c.organizationID != "" {
-			q.Set("organization_id", c.organizationID)
-		}
-		req.URL.RawQuery = q.Encode()
+// FetchAuthenticationEventsBetween retrieves the tenant's IAM authentication
+// logs after from and before to.
+func (c *Client) FetchAuthenticationEventsBetween(ctx context.Context, tenant Tenant, from, to time.Time) ([]*AuditEvent, error) {
+	if c.mock {
+		log.Println("WARNING: returning mock authentication events (SCALEWAY_MOCK)")
+		return withTenant(filterBefore(c.mockGenerator.Events(&from, "auth"), to), tenant), nil
+	}
+	if c.apiKey == "" {
+		return nil, ErrMissingAPIKey
+	}
 
-		c.setAuthHeaders(req)
+	return c.fetchEvents(ctx, tenant, &from, &to, c.endpoints.Authentication, "authentication")
+}
 
-		resp, err := c.httpClient.Do(req)
-		if err != nil {
-			return nil, fmt.Errorf("failed to query Scaleway %s API: %w", source, err)
+// withTenant tags events with the tenant they were fetched for
+func withTenant(events []*AuditEvent, tenant Tenant) []*AuditEvent {
+	for _, event := range events {
+		event.Tenant = tenant
+	}
+	return events
+}
+
+// filterBefore keeps the events that happened before until
+func filterBefore(events []*AuditEvent, until time.Time) []*AuditEvent {
+	var filtered []*AuditEvent
+	for _, event := range events {
+		if event.Timestamp.Before(until) {
+			filtered = append(filtered, event)
 		}
+	}
+	return filtered
+}
 
-		body, err := io.ReadAll(resp.Body)
-		resp.Body.Close()
+func (c *Client) fetchEvents(ctx context.Context, tenant Tenant, since, until *time.Time, endpoint Endpoint, source string) (_ []*AuditEvent, err error) {
+	ctx, span := tracer.Start(ctx, "scaleway.fetch", trace.WithAttributes(
+		attribute.String("source", source),
+		attribute.String("tenant", tenant.String()),
+	))
+	defer func() {
 		if err != nil {
-			return nil, fmt.Errorf("failed to read Scaleway %s response: %w", source, err)
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
 		}
+		span.End()
+	}()
 
-		if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
-			return nil, fmt.Errorf("scaleway API authentication failed: %s", resp.Status)
-		}
-		if resp.StatusCode >= 300 {
-			return nil, fmt.Errorf("scaleway API error (%s): %s - %s", source, resp.Status, string(body))
+	var events []*AuditEvent
+	seen := make(map[string]bool)
+	skipped := SkipReport{Source: source}
+	page := 1
+	// stalePages counts the consecutive pages entirely before since
+	stalePages := 0
+
+	for page <= maxPages {
+		req, err := c.newPageRequest(ctx, tenant, since, until, endpoint.Path, page)
+		if err != nil {
+			return nil, err
 		}
 
-		list, err := extractItemList(body, listKey)
+		list, err := c.fetchPage(req, endpoint.ListKey, source)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse %s response: %w", source, err)
+			return nil, err
 		}
 
+		// Pages can overlap; a page holding nothing but entries already seen
+		// means the API is not advancing, so stop rather than loop
+		parsed, duplicates, stale := 0, 0, 0
+		// first and last are the timestamps of the page's first and last
+		// entries, giving the order the API actually returned them in
+		var first, last time.Time
 		for _, raw := range list {
-			event, err := mapToAuditEvent(raw)
+			event, err := MapToAuditEvent(raw)
 			if err != nil {
 				// Skip malformed entries but keep ingesting
+				skipped.add(err.Error())
 				continue
 			}
+			parsed++
+			if first.IsZero() {
+				first = event.Timestamp
+			}
+			last = event.Timestamp
 			if since != nil && !event.Timestamp.After(*since) {
+				stale++
+			}
+			if seen[event.ID] {
+				duplicates++
+				continue
+			}
+			seen[event.ID] = true
+
+			// Out-of-window entries are dropped here, but the page still
+			// counts as full below so paging continues past them, unless
+			// their order shows the window is exhausted
+			if since != nil && !event.Timestamp.After(*since) {
+				continue
+			}
+			if until != nil && !event.Timestamp.Before(*until) {
 				continue
 			}
 			event.Source = source
+			event.Tenant = tenant
 			events = append(events, event)
 		}
 
+		if parsed > 0 && duplicates == parsed {
+			log.Printf("Stopping %s fetch at page %d: every entry was already returned by an earlier page", source, page)
+			break
+		}
+		if pageExhaustsWindow(since, until, first, last) {
+			log.Printf("Stopping %s fetch at page %d: the page reaches the end of the requested window", source, page)
+			break
+		}
+		if parsed > 0 && stale == parsed {
+			stalePages++
+			if stalePages >= c.maxStalePages {
+				return nil, fmt.Errorf("%w: %s pages %d-%d all precede %s", ErrSinceIgnored, source, page-stalePages+1, page, since.Format(time.RFC3339))
+			}
+			if stalePages == 1 {
+				log.Printf("WARNING: %s API returned page %d entirely before since; it appears to ignore the since filter, so older events are paged through", source, page)
+			}
+		} else {
+			stalePages = 0
+		}
 		if len(list) < defaultPageSize {
 			break
 		}
 		page++
 	}
 
+	if skipped.Skipped > 0 {
+		log.Printf("Skipped %d malformed %s entries: %s", skipped.Skipped, source, strings.Join(skipped.Reasons, "; "))
+		if c.skipHandler != nil {
+			c.skipHandler(skipped)
+		}
+	}
+
 	return events, nil
 }
 
+// pageExhaustsWindow reports whether no later page can hold events inside the
+// since/until window, judging by the order the page's first and last entries
+// came in. Events are requested in ascending order, but some endpoints return
+// them newest first: then a page reaching back to since ends the window. In
+// ascending order a page reaching until does. An ascending page entirely
+// before since means the API ignored since; newer events may still follow
+// it, so it does not stop paging here. fetchEvents gives up after
+// maxStalePages of them instead.
+func pageExhaustsWindow(since, until *time.Time, first, last time.Time) bool {
+	switch {
+	case last.Before(first):
+		return since != nil && !last.After(*since)
+	case first.Before(last):
+		return until != nil && !last.Before(*until)
+	}
+	return false
+}
+
+// newPageRequest builds the GET request for one page of events at
+// relativePath, filtered to the tenant and the since/until window
+func (c *Client) newPageRequest(ctx context.Context, tenant Tenant, since, until *time.Time, relativePath string, page int) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.apiURL+relativePath, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+
+	q := req.URL.Query()
+	q.Set("page", strconv.Itoa(page))
+	q.Set("page_size", strconv.Itoa(defaultPageSize))
+	q.Set("order", "asc")
+	q.Set("direction", "asc")
+	if since != nil {
+		q.Set("since", since.UTC().Format(time.RFC3339))
+	}
+	if until != nil {
+		q.Set("until", until.UTC().Format(time.RFC3339))
+	}
+	if tenant.ProjectID != "" {
+		q.Set("project_id", tenant.ProjectID)
+	}
+	if tenant.OrganizationID != "" {
+		q.Set("organization_id", tenant.OrganizationID)
+	}
+	req.URL.RawQuery = q.Encode()
+
+	c.setTenantHeaders(req, tenant)
+	return req, nil
+}
+
+// fetchPage sends req and returns the raw entries listed under listKey in
+// the response
+func (c *Client) fetchPage(req *http.Request, listKey, source string) ([]map[string]any, error) {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query Scaleway %s API: %w", source, err)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Scaleway %s response: %w", source, err)
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return nil, fmt.Errorf("scaleway API authentication failed: %s", resp.Status)
+	}
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("failed to query Scaleway %s API: %w", source, newAPIError(resp.StatusCode, body))
+	}
+
+	list, err := extractItemList(body, listKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s response: %w", source, err)
+	}
+	return list, nil
+}
+
+// add counts one skipped entry, keeping its reason if it is new and the
+// sample is not full
+func (r *SkipReport) add(reason string) {
+	r.Skipped++
+	if len(r.Reasons) >= maxSkipReasons {
+		return
+	}
+	for _, existing := range r.Reasons {
+		if existing == reason {
+			return
+		}
+	}
+	r.Reasons = append(r.Reasons, reason)
+}
+
+// fallbackListKeys are the generic envelope keys tried, in order, when a
+// response lacks the list key expected for its endpoint
+var fallbackListKeys = []string{"events", "items", "data", "logs"}
+
+// extractItemList returns the entries listed under listKey in a response
+// envelope. listKey is authoritative: the generic fallbackListKeys are only
+// consulted when it is absent, and a fallback is refused if several of them
+// are present, since it cannot tell which array holds the events.
 func extractItemList(body []byte, listKey string) ([]map[string]any, error) {
 	var envelope map[string]json.RawMessage
 	if err := json.Unmarshal(body, &envelope); err != nil {
 		return nil, err
 	}
 
-	keys := []string{listKey, "events", "items", "data"}
-	for _, key := range keys {
-		if raw, ok := envelope[key]; ok {
-			var items []map[string]any
-			if err := json.Unmarshal(raw, &items); err != nil {
-				return nil, err
+	key := listKey
+	if _, ok := envelope[listKey]; !ok {
+		var present []string
+		for _, fallback := range fallbackListKeys {
+			if _, ok := envelope[fallback]; ok && fallback != listKey {
+				present = append(present, fallback)
 			}
-			return items, nil
 		}
-	}
-
-	// Some endpoints wrap data in "logs" or "login_logs"
-	if raw, ok := envelope["logs"]; ok {
-		var items []map[string]any
-		if err := json.Unmarshal(raw, &items); err != nil {
-			return nil, err
+		switch len(present) {
+		case 0:
+			return nil, fmt.Errorf("no %q list found in response", listKey)
+		case 1:
+			key = present[0]
+			log.Printf("Response has no %q list, falling back to %q", listKey, key)
+		default:
+			return nil, fmt.Errorf("no %q list found in response and several fallbacks are present (%s)", listKey, strings.Join(present, ", "))
 		}
-		return items, nil
 	}
 
-	return nil, errors.New("no events found in response")
+	var items []map[string]any
+	if err := json.Unmarshal(envelope[key], &items); err != nil {
+		return nil, fmt.Errorf("invalid %q list: %w", key, err)
+	}
+	return items, nil
 }
 
-func mapToAuditEvent(raw map[string]any) (*AuditEvent, error) {
+// MapToAuditEvent normalizes a raw event payload into an AuditEvent. It is
+// used for Scaleway API responses and for events pushed to us directly.
+func MapToAuditEvent(raw map[string]any) (*AuditEvent, error) {
 	id := firstString(raw, "id", "event_id", "uuid", "log_id")
 	if id == "" {
 		return nil, errors.New("event missing id")
 	}
 
-	eventType := firstString(raw, "event_type", "type", "category", "action")
+	eventType := firstString(raw, "event_type", "type", "category", "action", "method_name")
 	if eventType == "" {
 		eventType = "unknown"
 	}
 
-	actor := firstString(raw, "actor", "user", "user_email", "principal", "identity")
-	resource := firstString(raw, "resource", "resource_name", "target", "service_name")
-	ip := firstString(raw, "ip", "ip_address", "source_ip", "client_ip")
-	timestampStr := firstString(raw, "timestamp", "occurred_at", "created_at", "time", "last_login_at")
+	actor := firstString(raw, "actor", "user", "user_email", "principal", "identity",
+		"user_info.email", "principal.email", "user_info.id", "principal.id")
+	resource := firstString(raw, "resource", "resource_name", "target", "service_name", "resource.name", "resource.id")
+	ip := firstString(raw, "ip", "ip_address", "source_ip", "client_ip", "request_metadata.ip")
+	timestampStr := firstString(raw, "timestamp", "occurred_at", "created_at", "recorded_at", "time", "last_login_at",
+		"request_metadata.timestamp")
 
 	var timestamp time.Time
 	if timestampStr != "" {
-		parsed, err := time.Parse(time.RFC3339, timestampStr)
-		if err == nil {
+		if parsed, ok := ParseTimestamp(timestampStr); ok {
 			timestamp = parsed
 		}
 	}
@@ -217,9 +553,61 @@ func mapToAuditEvent(raw map[string]any) (*AuditEvent, error) {
 	}, nil
 }
 
+// ParseTimestamp parses a timestamp in any of TimestampLayouts (space-separated
+// layouts without a zone are taken as UTC) or a Unix epoch in seconds or
+// milliseconds
+func ParseTimestamp(value string) (time.Time, bool) {
+	for _, layout := range TimestampLayouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed, true
+		}
+	}
+
+	epoch, err := strconv.ParseFloat(value, 64)
+	if err != nil || epoch <= 0 {
+		return time.Time{}, false
+	}
+	// Anything past 1e11 seconds (year 5138) must be milliseconds
+	if epoch > 1e11 {
+		return time.UnixMilli(int64(epoch)).UTC(), true
+	}
+	sec := int64(epoch)
+	nsec := int64((epoch - float64(sec)) * 1e9)
+	return time.Unix(sec, nsec).UTC(), true
+}
+
+// lookup resolves key in raw, treating dots as a path into nested objects
+// when the literal key is absent (e.g. "user_info.email")
+func lookup(raw map[string]any, key string) (any, bool) {
+	if val, ok := raw[key]; ok {
+		return val, true
+	}
+	if !strings.Contains(key, ".") {
+		return nil, false
+	}
+
+	current := raw
+	parts := strings.Split(key, ".")
+	for idx, part := range parts {
+		val, ok := current[part]
+		if !ok {
+			return nil, false
+		}
+		if idx == len(parts)-1 {
+			return val, true
+		}
+		next, ok := val.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		current = next
+	}
+	return nil, false
+}
+
 func firstString(raw map[string]any, keys ...string) string {
 	for _, key := range keys {
-		if val, ok := raw[key]; ok {
+		if val, ok := lookup(raw, key); ok {
 			switch v := val.(type) {
 			case string:
 				return v
@@ -235,132 +623,6 @@ func firstString(raw map[string]any, keys ...string) string {
 	return ""
 }
 
-func (c *Client) mockEvents(since *time.Time, source string) []*AuditEvent {
-	now := time.Now().UTC()
-	eventIDBase := now.Unix()
-	mock := []*AuditEvent{
-		{
-			ID:        fmt.Sprintf("evt_mock_%d_001", eventIDBase),
-			Type:      "auth.failed",
-			Actor:     "user@example.com",
-			Resource:  "iam",
-			IP:        "203.0.113.1",
-			Timestamp: now.Add(-10 * time.Minute),
-			Source:    source,
-			Raw: map[string]any{
-				"event_id": fmt.Sprintf("evt_mock_%d_001", eventIDBase),
-				"type":     "auth.failed",
-				"actor":    "user@example.com",
-				"reason":   "invalid_credentials",
-			},
-		},
-		{
-			ID:        fmt.Sprintf("evt_mock_%d_002", eventIDBase),
-			Type:      "auth.failed",
-			Actor:     "user@example.com",
-			Resource:  "iam",
-			IP:        "203.0.113.1",
-			Timestamp: now.Add(-8 * time.Minute),
-			Source:    source,
-			Raw: map[string]any{
-				"event_id": fmt.Sprintf("evt_mock_%d_002", eventIDBase),
-				"type":     "auth.failed",
-				"actor":    "user@example.com",
-				"reason":   "invalid_credentials",
-			},
-		},
-		{
-			ID:        fmt.Sprintf("evt_mock_%d_003", eventIDBase),
-			Type:      "auth.failed",
-			Actor:     "user@example.com",
-			Resource:  "iam",
-			IP:        "203.0.113.2",
-			Timestamp: now.Add(-5 * time.Minute),
-			Source:    source,
-			Raw: map[string]any{
-				"event_id": fmt.Sprintf("evt_mock_%d_003", eventIDBase),
-				"type":     "auth.failed",
-				"actor":    "user@example.com",
-				"reason":   "invalid_credentials",
-			},
-		},
-		{
-			ID:        fmt.Sprintf("evt_mock_%d_006", eventIDBase),
-			Type:      "auth.failed",
-			Actor:     "user@example.com",
-			Resource:  "iam",
-			IP:        "203.0.113.1",
-			Timestamp: now.Add(-4 * time.Minute),
-			Source:    source,
-			Raw: map[string]any{
-				"event_id": fmt.Sprintf("evt_mock_%d_006", eventIDBase),
-				"type":     "auth.failed",
-				"actor":    "user@example.com",
-				"reason":   "invalid_credentials",
-			},
-		},
-		{
-			ID:        fmt.Sprintf("evt_mock_%d_007", eventIDBase),
-			Type:      "auth.failed",
-			Actor:     "user@example.com",
-			Resource:  "iam",
-			IP:        "203.0.113.3",
-			Timestamp: now.Add(-2 * time.Minute),
-			Source:    source,
-			Raw: map[string]any{
-				"event_id": fmt.Sprintf("evt_mock_%d_007", eventIDBase),
-				"type":     "auth.failed",
-				"actor":    "user@example.com",
-				"reason":   "invalid_credentials",
-			},
-		},
-		{
-			ID:        fmt.Sprintf("evt_mock_%d_004", eventIDBase),
-			Type:      "apiKey.create",
-			Actor:     "admin@example.com",
-			Resource:  "iam",
-			IP:        "198.51.100.1",
-			Timestamp: now.Add(-3 * time.Minute),
-			Source:    source,
-			Raw: map[string]any{
-				"event_id": fmt.Sprintf("evt_mock_%d_004", eventIDBase),
-				"type":     "apiKey.create",
-				"actor":    "admin@example.com",
-				"key_id":   "key_abc123",
-				"key_name": "Production API Key",
-			},
-		},
-		{
-			ID:        fmt.Sprintf("evt_mock_%d_005", eventIDBase),
-			Type:      "forbidden",
-			Actor:     "attacker@example.com",
-			Resource:  "secrets",
-			IP:        "192.0.2.1",
-			Timestamp: now.Add(-1 * time.Minute),
-			Source:    source,
-			Raw: map[string]any{
-				"event_id": fmt.Sprintf("evt_mock_%d_005", eventIDBase),
-				"type":     "forbidden",
-				"actor":    "attacker@example.com",
-				"resource": "secrets/database-password",
-				"action":   "read",
-			},
-		},
-	}
-
-	if since == nil {
-		return mock
-	}
-
-	var filtered []*AuditEvent
-	for _, evt := range mock {
-		if evt.Timestamp.After(*since) {
-			filtered = append(filtered, evt)
-		}
-	}
-	return filtered
-}
-
 // LockUser locks a user account via Scaleway IAM API
 func (c *Client) LockUser(ctx context.Context, userID string) error {
 	// Scaleway IAM API: Update user status to locked
@@ -409,12 +671,71 @@ func (c *Client) RevokeAPIKey(ctx context.Context, keyID string) error {
 
 	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to revoke API key: status %d, body: %s", resp.StatusCode, string(body))
+		return fmt.Errorf("failed to revoke API key: %w", newAPIError(resp.StatusCode, body))
 	}
 
 	return nil
 }
 
+// APIKey is a Scaleway IAM API key. AccessKey identifies it for RevokeAPIKey.
+type APIKey struct {
+	AccessKey   string     `json:"access_key"`
+	Description string     `json:"description"`
+	UserID      string     `json:"user_id"`
+	CreatedAt   *time.Time `json:"created_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+}
+
+// ListAPIKeys returns every API key held by a user via Scaleway IAM API
+func (c *Client) ListAPIKeys(ctx context.Context, userID string) ([]APIKey, error) {
+	// Scaleway IAM API: List API keys
+	// Endpoint: GET /iam/v1alpha1/api-keys?bearer_id={user_id}&bearer_type=user
+	var keys []APIKey
+	for page := 1; page <= maxPages; page++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.apiURL+"/iam/v1alpha1/api-keys", nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		q := req.URL.Query()
+		q.Set("bearer_id", userID)
+		q.Set("bearer_type", "user")
+		q.Set("page", strconv.Itoa(page))
+		q.Set("page_size", strconv.Itoa(defaultPageSize))
+		req.URL.RawQuery = q.Encode()
+
+		c.setAuthHeaders(req)
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list API keys: %w", err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read API keys response: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("failed to list API keys: %w", newAPIError(resp.StatusCode, body))
+		}
+
+		var list struct {
+			APIKeys    []APIKey `json:"api_keys"`
+			TotalCount int      `json:"total_count"`
+		}
+		if err := json.Unmarshal(body, &list); err != nil {
+			return nil, fmt.Errorf("failed to parse API keys response: %w", err)
+		}
+		keys = append(keys, list.APIKeys...)
+
+		if len(list.APIKeys) < defaultPageSize || len(keys) >= list.TotalCount {
+			break
+		}
+	}
+
+	return keys, nil
+}
+
 // updateUserStatus updates user status via Scaleway IAM API
 func (c *Client) updateUserStatus(ctx context.Context, url string, payload map[string]interface{}) error {
 	jsonData, err := json.Marshal(payload)
@@ -439,23 +760,30 @@ func (c *Client) updateUserStatus(ctx context.Context, url string, payload map[s
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to update user: status %d, body: %s", resp.StatusCode, string(body))
+		return fmt.Errorf("failed to update user: %w", newAPIError(resp.StatusCode, body))
 	}
 
 	return nil
 }
 
-// setAuthHeaders sets authentication headers for Scaleway API requests
+// setAuthHeaders sets authentication headers for Scaleway API requests made
+// for the client's own tenant
 func (c *Client) setAuthHeaders(req *http.Request) {
+	c.setTenantHeaders(req, c.tenant)
+}
+
+// setTenantHeaders sets authentication headers for Scaleway API requests
+// scoped to tenant
+func (c *Client) setTenantHeaders(req *http.Request, tenant Tenant) {
 	// Scaleway API uses X-Auth-Token header for authentication
 	req.Header.Set("X-Auth-Token", c.apiKey)
 
 	// Set project/organization ID if available
-	if c.projectID != "" {
-		req.Header.Set("X-Project-Id", c.projectID)
+	if tenant.ProjectID != "" {
+		req.Header.Set("X-Project-Id", tenant.ProjectID)
 	}
-	if c.organizationID != "" {
-		req.Header.Set("X-Organization-Id", c.organizationID)
+	if tenant.OrganizationID != "" {
+		req.Header.Set("X-Organization-Id", tenant.OrganizationID)
 	}
 
 	// Default JSON accept header
@@ -0,0 +1,561 @@
+package scaleway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	auditPath = "/audit/v1alpha1/events"
+	loginPath = "/iam/v1alpha1/login-logs"
+)
+
+func TestFetchAuditEventsMultiPage(t *testing.T) {
+	fake := newFakeScaleway(t)
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	fake.servePages(auditPath, "events",
+		auditEntries("page1", start, defaultPageSize),
+		auditEntries("page2", start.Add(4*time.Hour), 30),
+	)
+
+	events, err := fake.client().FetchAuditEvents(context.Background(), Tenant{ProjectID: "project-1"}, nil)
+	if err != nil {
+		t.Fatalf("FetchAuditEvents: %v", err)
+	}
+	if len(events) != defaultPageSize+30 {
+		t.Fatalf("got %d events, want %d", len(events), defaultPageSize+30)
+	}
+	if events[0].ID != "page1-0" || events[len(events)-1].ID != "page2-29" {
+		t.Errorf("events out of order: first %s, last %s", events[0].ID, events[len(events)-1].ID)
+	}
+	for _, event := range events {
+		if event.Source != "audit" || event.Tenant.ProjectID != "project-1" {
+			t.Fatalf("event %s tagged %q/%q, want audit/project-1", event.ID, event.Source, event.Tenant.ProjectID)
+		}
+	}
+
+	requests := fake.requests(auditPath)
+	if len(requests) != 2 {
+		t.Fatalf("made %d requests, want 2 (the second page is short)", len(requests))
+	}
+	for idx, query := range requests {
+		if got, want := query.Get("page"), []string{"1", "2"}[idx]; got != want {
+			t.Errorf("request %d asked for page %s, want %s", idx, got, want)
+		}
+		if query.Get("order") != "asc" || query.Get("project_id") != "project-1" {
+			t.Errorf("request %d query = %v, want ascending order for project-1", idx, query)
+		}
+	}
+}
+
+func TestFetchAuditEventsSinceFiltering(t *testing.T) {
+	fake := newFakeScaleway(t)
+	since := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	// The API returns two entries at or before since despite the filter
+	fake.servePages(auditPath, "events", auditEntries("evt", since.Add(-time.Minute), 5))
+
+	events, err := fake.client().FetchAuditEvents(context.Background(), Tenant{}, &since)
+	if err != nil {
+		t.Fatalf("FetchAuditEvents: %v", err)
+	}
+
+	var ids []string
+	for _, event := range events {
+		ids = append(ids, event.ID)
+		if !event.Timestamp.After(since) {
+			t.Errorf("event %s at %s is not after since %s", event.ID, event.Timestamp, since)
+		}
+	}
+	if len(ids) != 3 || ids[0] != "evt-2" {
+		t.Errorf("got events %v, want evt-2..evt-4", ids)
+	}
+	if got := fake.requests(auditPath)[0].Get("since"); got != since.Format(time.RFC3339) {
+		t.Errorf("since query = %q, want %q", got, since.Format(time.RFC3339))
+	}
+}
+
+func TestFetchAuditEventsSkipsMalformedEntries(t *testing.T) {
+	fake := newFakeScaleway(t)
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	entries := auditEntries("evt", start, 3)
+	entries = append(entries,
+		map[string]any{"event_type": "iam.policy.update", "actor": "no-id@example.com"},
+		map[string]any{"timestamp": start.Format(time.RFC3339)},
+	)
+	fake.servePages(auditPath, "events", entries)
+
+	client := fake.client()
+	var reports []SkipReport
+	client.SetSkipHandler(func(report SkipReport) { reports = append(reports, report) })
+
+	events, err := client.FetchAuditEvents(context.Background(), Tenant{}, nil)
+	if err != nil {
+		t.Fatalf("FetchAuditEvents: %v", err)
+	}
+	if len(events) != 3 {
+		t.Errorf("got %d events, want the 3 well-formed ones", len(events))
+	}
+	if len(reports) != 1 {
+		t.Fatalf("got %d skip reports, want 1", len(reports))
+	}
+	if reports[0].Source != "audit" || reports[0].Skipped != 2 {
+		t.Errorf("skip report = %+v, want 2 audit entries skipped", reports[0])
+	}
+	if len(reports[0].Reasons) != 1 {
+		t.Errorf("skip reasons = %v, want the one distinct reason", reports[0].Reasons)
+	}
+}
+
+func TestFetchAuditEventsRateLimited(t *testing.T) {
+	fake := newFakeScaleway(t)
+	fake.failWith(auditPath, http.StatusTooManyRequests,
+		`{"type":"too_many_requests","message":"quota exceeded"}`)
+
+	events, err := fake.client().FetchAuditEvents(context.Background(), Tenant{}, nil)
+	if err == nil {
+		t.Fatalf("FetchAuditEvents returned %d events, want a rate limit error", len(events))
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("error %v is not an *APIError", err)
+	}
+	if apiErr.StatusCode != http.StatusTooManyRequests || apiErr.Code != "too_many_requests" {
+		t.Errorf("APIError = %+v, want status 429 too_many_requests", apiErr)
+	}
+}
+
+func TestFetchAuthenticationEventsReadsLoginLogs(t *testing.T) {
+	fake := newFakeScaleway(t)
+	fake.servePages(loginPath, "login_logs", auditEntries("login", time.Now().Add(-time.Hour), 2))
+
+	events, err := fake.client().FetchAuthenticationEvents(context.Background(), Tenant{}, nil)
+	if err != nil {
+		t.Fatalf("FetchAuthenticationEvents: %v", err)
+	}
+	if len(events) != 2 || events[0].Source != "authentication" {
+		t.Errorf("got %d events from %q, want 2 authentication events", len(events), sourceOf(events))
+	}
+}
+
+func TestFetchAuditEventsRequiresAPIKey(t *testing.T) {
+	fake := newFakeScaleway(t)
+	client := NewClient("", "", "", fake.server.URL)
+
+	if _, err := client.FetchAuditEvents(context.Background(), Tenant{}, nil); !errors.Is(err, ErrMissingAPIKey) {
+		t.Errorf("error = %v, want ErrMissingAPIKey", err)
+	}
+	if n := len(fake.requests(auditPath)); n != 0 {
+		t.Errorf("made %d requests without an API key", n)
+	}
+}
+
+// sourceOf returns the source of the first event, for failure messages
+func sourceOf(events []*AuditEvent) string {
+	if len(events) == 0 {
+		return ""
+	}
+	return events[0].Source
+}
+
+func TestParseTimestamp(t *testing.T) {
+	want := time.Date(2024, 3, 1, 12, 30, 45, 0, time.UTC)
+	tests := []struct {
+		name  string
+		value string
+		want  time.Time
+	}{
+		{name: "RFC3339Nano", value: "2024-03-01T12:30:45.123456789Z", want: want.Add(123456789)},
+		{name: "RFC3339", value: "2024-03-01T14:30:45+02:00", want: want},
+		{name: "space separated fractional with zone", value: "2024-03-01 12:30:45.5Z", want: want.Add(500 * time.Millisecond)},
+		{name: "space separated with zone", value: "2024-03-01 13:30:45+01:00", want: want},
+		{name: "space separated fractional", value: "2024-03-01 12:30:45.25", want: want.Add(250 * time.Millisecond)},
+		{name: "space separated", value: "2024-03-01 12:30:45", want: want},
+		{name: "epoch seconds", value: "1709296245", want: want},
+		{name: "epoch fractional seconds", value: "1709296245.5", want: want.Add(500 * time.Millisecond)},
+		{name: "epoch milliseconds", value: "1709296245000", want: want},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseTimestamp(tt.value)
+			if !ok {
+				t.Fatalf("ParseTimestamp(%q) failed", tt.value)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("ParseTimestamp(%q) = %s, want %s", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTimestampRejectsUnparseable(t *testing.T) {
+	for _, value := range []string{"", "yesterday", "2024-03-01", "03/01/2024 12:30", "0", "-5"} {
+		if got, ok := ParseTimestamp(value); ok {
+			t.Errorf("ParseTimestamp(%q) = %s, want failure", value, got)
+		}
+	}
+}
+
+func TestMapToAuditEventDefaultsUnparseableTimestamp(t *testing.T) {
+	before := time.Now().UTC()
+	event, err := MapToAuditEvent(map[string]any{"id": "evt-1", "timestamp": "yesterday"})
+	if err != nil {
+		t.Fatalf("MapToAuditEvent: %v", err)
+	}
+	if event.Timestamp.Before(before) || event.Timestamp.After(time.Now().UTC()) {
+		t.Errorf("timestamp = %s, want the time of mapping", event.Timestamp)
+	}
+}
+
+func TestMapToAuditEventNestedPayloads(t *testing.T) {
+	want := time.Date(2024, 3, 1, 12, 30, 45, 0, time.UTC)
+	tests := []struct {
+		name                string
+		payload             string
+		actor, ip, resource string
+		wantTimestamp       time.Time
+	}{
+		{
+			name: "login log with user_info and request_metadata",
+			payload: `{"id": "log-1", "event_type": "auth.failed",
+				"user_info": {"id": "11111111-2222-3333-4444-555555555555", "email": "alice@example.com"},
+				"request_metadata": {"ip": "203.0.113.7", "timestamp": "2024-03-01T12:30:45Z"}}`,
+			actor: "alice@example.com", ip: "203.0.113.7", wantTimestamp: want,
+		},
+		{
+			name: "audit event with principal object and epoch milliseconds",
+			payload: `{"id": "evt-1", "method_name": "CreateAPIKey",
+				"principal": {"id": "app-7"},
+				"resource": {"id": "key-1", "name": "SCWKEY123"},
+				"request_metadata": {"ip": "198.51.100.2", "timestamp": 1709296245000}}`,
+			actor: "app-7", ip: "198.51.100.2", resource: "SCWKEY123", wantTimestamp: want,
+		},
+		{
+			name: "epoch seconds as a string",
+			payload: `{"id": "evt-2", "user_info": {"email": "bob@example.com"},
+				"request_metadata": {"timestamp": "1709296245"}}`,
+			actor: "bob@example.com", wantTimestamp: want,
+		},
+		{
+			name: "flat keys win over nested ones",
+			payload: `{"id": "evt-3", "actor": "carol@example.com", "ip": "192.0.2.1", "timestamp": "2024-03-01T12:30:45Z",
+				"user_info": {"email": "other@example.com"},
+				"request_metadata": {"ip": "203.0.113.9", "timestamp": "2020-01-01T00:00:00Z"}}`,
+			actor: "carol@example.com", ip: "192.0.2.1", wantTimestamp: want,
+		},
+		{
+			name:    "literal dotted key",
+			payload: `{"id": "evt-4", "user_info.email": "dave@example.com", "created_at": "2024-03-01 12:30:45"}`,
+			actor:   "dave@example.com", wantTimestamp: want,
+		},
+		{
+			name:    "path through a non-object",
+			payload: `{"id": "evt-5", "user_info": "not-an-object", "principal": {"email": "erin@example.com"}, "time": "2024-03-01T12:30:45Z"}`,
+			actor:   "erin@example.com", wantTimestamp: want,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var raw map[string]any
+			if err := json.Unmarshal([]byte(tt.payload), &raw); err != nil {
+				t.Fatalf("invalid payload: %v", err)
+			}
+			event, err := MapToAuditEvent(raw)
+			if err != nil {
+				t.Fatalf("MapToAuditEvent: %v", err)
+			}
+			if event.Actor != tt.actor || event.IP != tt.ip || event.Resource != tt.resource {
+				t.Errorf("actor/ip/resource = %q/%q/%q, want %q/%q/%q",
+					event.Actor, event.IP, event.Resource, tt.actor, tt.ip, tt.resource)
+			}
+			if !event.Timestamp.Equal(tt.wantTimestamp) {
+				t.Errorf("timestamp = %s, want %s", event.Timestamp, tt.wantTimestamp)
+			}
+		})
+	}
+}
+
+func TestMapToAuditEventDefaultsUnparseableNestedTimestamp(t *testing.T) {
+	before := time.Now().UTC()
+	event, err := MapToAuditEvent(map[string]any{
+		"id":               "evt-1",
+		"request_metadata": map[string]any{"timestamp": "not a time"},
+	})
+	if err != nil {
+		t.Fatalf("MapToAuditEvent: %v", err)
+	}
+	if event.Timestamp.Before(before) || event.Timestamp.After(time.Now().UTC()) {
+		t.Errorf("timestamp = %s, want the time of mapping", event.Timestamp)
+	}
+}
+
+func TestFetchAuditEventsPagesPastIgnoredSince(t *testing.T) {
+	fake := newFakeScaleway(t)
+	since := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	// The API ignores since: the whole first page predates it, and the new
+	// events only come on the next page
+	fake.servePages(auditPath, "events",
+		auditEntries("old", since.Add(-3*time.Hour), defaultPageSize),
+		auditEntries("new", since.Add(time.Minute), 10),
+	)
+
+	events, err := fake.client().FetchAuditEvents(context.Background(), Tenant{}, &since)
+	if err != nil {
+		t.Fatalf("FetchAuditEvents: %v", err)
+	}
+	if len(events) != 10 || events[0].ID != "new-0" {
+		t.Errorf("got %d events starting at %s, want the 10 newer ones", len(events), firstID(events))
+	}
+	if n := len(fake.requests(auditPath)); n != 2 {
+		t.Errorf("made %d requests, want 2 (paging continues past the stale page)", n)
+	}
+}
+
+func TestFetchAuditEventsGivesUpOnIgnoredSince(t *testing.T) {
+	since := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	// Every page predates since: the API ignores it and has a long history
+	var pages [][]map[string]any
+	for idx := 0; idx < 10; idx++ {
+		pages = append(pages, auditEntries(fmt.Sprintf("old%d", idx), since.Add(-time.Duration(48-idx*2)*time.Hour), defaultPageSize))
+	}
+
+	for _, tt := range []struct {
+		maxStalePages int
+		wantRequests  int
+	}{
+		{wantRequests: defaultMaxStalePages},
+		{maxStalePages: 1, wantRequests: 1},
+		{maxStalePages: 5, wantRequests: 5},
+	} {
+		fake := newFakeScaleway(t)
+		fake.servePages(auditPath, "events", pages...)
+		client := fake.client()
+		client.SetMaxStalePages(tt.maxStalePages)
+
+		events, err := client.FetchAuditEvents(context.Background(), Tenant{}, &since)
+		if !errors.Is(err, ErrSinceIgnored) || events != nil {
+			t.Errorf("max %d: FetchAuditEvents = %d events, %v, want ErrSinceIgnored", tt.maxStalePages, len(events), err)
+		}
+		if n := len(fake.requests(auditPath)); n != tt.wantRequests {
+			t.Errorf("max %d: made %d requests, want %d", tt.maxStalePages, n, tt.wantRequests)
+		}
+	}
+}
+
+func TestFetchAuditEventsStopsAtWindowEdge(t *testing.T) {
+	since := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("descending page reaching since", func(t *testing.T) {
+		fake := newFakeScaleway(t)
+		// Newest first, the page ends 50 minutes before since
+		page := reversed(auditEntries("evt", since.Add(-50*time.Minute), defaultPageSize))
+		fake.servePages(auditPath, "events", page, auditEntries("older", since.Add(-5*time.Hour), 10))
+
+		events, err := fake.client().FetchAuditEvents(context.Background(), Tenant{}, &since)
+		if err != nil {
+			t.Fatalf("FetchAuditEvents: %v", err)
+		}
+		if len(events) != defaultPageSize-51 {
+			t.Errorf("got %d events, want the %d after since", len(events), defaultPageSize-51)
+		}
+		if n := len(fake.requests(auditPath)); n != 1 {
+			t.Errorf("made %d requests, want 1", n)
+		}
+	})
+
+	t.Run("ascending page reaching until", func(t *testing.T) {
+		fake := newFakeScaleway(t)
+		until := since.Add(30 * time.Minute)
+		fake.servePages(auditPath, "events",
+			auditEntries("evt", since.Add(time.Minute), defaultPageSize),
+			auditEntries("later", since.Add(3*time.Hour), 10),
+		)
+
+		events, err := fake.client().FetchAuditEventsBetween(context.Background(), Tenant{}, since, until)
+		if err != nil {
+			t.Fatalf("FetchAuditEventsBetween: %v", err)
+		}
+		if len(events) != 29 {
+			t.Errorf("got %d events, want the 29 before until", len(events))
+		}
+		if n := len(fake.requests(auditPath)); n != 1 {
+			t.Errorf("made %d requests, want 1", n)
+		}
+	})
+}
+
+func TestPageExhaustsWindow(t *testing.T) {
+	since := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	until := since.Add(time.Hour)
+	at := func(minutes int) time.Time { return since.Add(time.Duration(minutes) * time.Minute) }
+
+	tests := []struct {
+		name         string
+		since, until *time.Time
+		first, last  time.Time
+		want         bool
+	}{
+		{name: "descending past since", since: &since, first: at(10), last: at(-5), want: true},
+		{name: "descending at since", since: &since, first: at(10), last: at(0), want: true},
+		{name: "descending inside window", since: &since, first: at(10), last: at(1)},
+		{name: "descending without since", first: at(10), last: at(-5)},
+		{name: "ascending past until", until: &until, first: at(10), last: at(70), want: true},
+		{name: "ascending inside window", until: &until, first: at(10), last: at(50)},
+		{name: "ascending entirely before since", since: &since, until: &until, first: at(-90), last: at(-30)},
+		{name: "single timestamp", since: &since, until: &until, first: at(-30), last: at(-30)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pageExhaustsWindow(tt.since, tt.until, tt.first, tt.last); got != tt.want {
+				t.Errorf("pageExhaustsWindow = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// reversed returns entries newest first
+func reversed(entries []map[string]any) []map[string]any {
+	out := make([]map[string]any, 0, len(entries))
+	for idx := len(entries) - 1; idx >= 0; idx-- {
+		out = append(out, entries[idx])
+	}
+	return out
+}
+
+// firstID returns the ID of the first event, for failure messages
+func firstID(events []*AuditEvent) string {
+	if len(events) == 0 {
+		return ""
+	}
+	return events[0].ID
+}
+
+func TestFetchAuditEventsOverlappingPages(t *testing.T) {
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	page1 := auditEntries("evt", start, defaultPageSize)
+
+	t.Run("repeated page stops the fetch", func(t *testing.T) {
+		fake := newFakeScaleway(t)
+		// An API ignoring the page parameter serves the same page forever
+		fake.servePages(auditPath, "events", page1, page1, page1)
+
+		events, err := fake.client().FetchAuditEvents(context.Background(), Tenant{}, nil)
+		if err != nil {
+			t.Fatalf("FetchAuditEvents: %v", err)
+		}
+		if len(events) != defaultPageSize {
+			t.Errorf("got %d events, want %d", len(events), defaultPageSize)
+		}
+		if n := len(fake.requests(auditPath)); n != 2 {
+			t.Errorf("made %d requests, want 2", n)
+		}
+	})
+
+	t.Run("partial overlap is deduplicated", func(t *testing.T) {
+		fake := newFakeScaleway(t)
+		// The second page repeats the last half of the first
+		page2 := append(append([]map[string]any{}, page1[defaultPageSize/2:]...),
+			auditEntries("next", start.Add(3*time.Hour), defaultPageSize/2)...)
+		fake.servePages(auditPath, "events", page1, page2, auditEntries("last", start.Add(6*time.Hour), 5))
+
+		events, err := fake.client().FetchAuditEvents(context.Background(), Tenant{}, nil)
+		if err != nil {
+			t.Fatalf("FetchAuditEvents: %v", err)
+		}
+		if want := defaultPageSize + defaultPageSize/2 + 5; len(events) != want {
+			t.Errorf("got %d events, want %d unique ones", len(events), want)
+		}
+		if n := len(fake.requests(auditPath)); n != 3 {
+			t.Errorf("made %d requests, want 3", n)
+		}
+	})
+
+	t.Run("filtered out page does not stop the fetch", func(t *testing.T) {
+		fake := newFakeScaleway(t)
+		since := start.Add(-time.Minute)
+		// Every entry of the second page is new but outside the window
+		fake.servePages(auditPath, "events",
+			page1,
+			auditEntries("stale", start.Add(-3*time.Hour), defaultPageSize),
+			auditEntries("late", start.Add(5*time.Hour), 5),
+		)
+
+		events, err := fake.client().FetchAuditEvents(context.Background(), Tenant{}, &since)
+		if err != nil {
+			t.Fatalf("FetchAuditEvents: %v", err)
+		}
+		if want := defaultPageSize + 5; len(events) != want {
+			t.Errorf("got %d events, want %d", len(events), want)
+		}
+		if n := len(fake.requests(auditPath)); n != 3 {
+			t.Errorf("made %d requests, want 3", n)
+		}
+	})
+}
+
+func TestExtractItemList(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		listKey string
+		wantIDs []string
+		wantErr string
+	}{
+		{name: "audit list key", body: `{"events": [{"id": "a"}, {"id": "b"}], "total_count": 2}`, listKey: DefaultEndpoints.Audit.ListKey, wantIDs: []string{"a", "b"}},
+		{name: "login logs list key", body: `{"login_logs": [{"id": "l"}]}`, listKey: DefaultEndpoints.Authentication.ListKey, wantIDs: []string{"l"}},
+		{name: "custom list key", body: `{"audit_events": [{"id": "c"}]}`, listKey: "audit_events", wantIDs: []string{"c"}},
+		{name: "list key wins over fallbacks", body: `{"login_logs": [{"id": "l"}], "data": [{"id": "d"}], "items": []}`, listKey: "login_logs", wantIDs: []string{"l"}},
+		{name: "empty list", body: `{"events": []}`, listKey: "events", wantIDs: []string{}},
+		{name: "single fallback", body: `{"items": [{"id": "i"}]}`, listKey: "login_logs", wantIDs: []string{"i"}},
+		{name: "each fallback key", body: `{"logs": [{"id": "g"}]}`, listKey: "events", wantIDs: []string{"g"}},
+		{name: "several fallbacks", body: `{"items": [{"id": "i"}], "data": [{"id": "d"}]}`, listKey: "login_logs", wantErr: "several fallbacks are present (items, data)"},
+		{name: "no list", body: `{"total_count": 0}`, listKey: "events", wantErr: `no "events" list found`},
+		{name: "list is not an array", body: `{"events": {"id": "a"}}`, listKey: "events", wantErr: `invalid "events" list`},
+		{name: "not an object", body: `[{"id": "a"}]`, listKey: "events", wantErr: "cannot unmarshal"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := extractItemList([]byte(tt.body), tt.listKey)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("extractItemList: %v", err)
+			}
+			ids := []string{}
+			for _, item := range items {
+				ids = append(ids, item["id"].(string))
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.wantIDs, ",") {
+				t.Errorf("got items %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestFetchUsesConfiguredListKey(t *testing.T) {
+	fake := newFakeScaleway(t)
+	fake.servePages("/audit/v2/events", "audit_events", auditEntries("evt", time.Now().Add(-time.Hour), 3))
+	client := fake.client()
+	client.SetEndpoints(Endpoints{Audit: Endpoint{Path: "/audit/v2/events", ListKey: "audit_events"}})
+
+	events, err := client.FetchAuditEvents(context.Background(), Tenant{}, nil)
+	if err != nil {
+		t.Fatalf("FetchAuditEvents: %v", err)
+	}
+	if len(events) != 3 {
+		t.Errorf("got %d events, want 3", len(events))
+	}
+}
@@ -0,0 +1,40 @@
+package scaleway
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// APIError is returned when the Scaleway API answers a request with an
+// error status. Code and Message come from the Scaleway error body when it
+// could be parsed.
+type APIError struct {
+	StatusCode int
+	// Code is the Scaleway error type, e.g. "permission_denied"
+	Code    string
+	Message string
+	// Body is the raw response body
+	Body string
+}
+
+func (e *APIError) Error() string {
+	if e.Code != "" || e.Message != "" {
+		return fmt.Sprintf("scaleway API error: status %d (%s): %s", e.StatusCode, e.Code, e.Message)
+	}
+	return fmt.Sprintf("scaleway API error: status %d, body: %s", e.StatusCode, e.Body)
+}
+
+// newAPIError builds an APIError from an error response
+func newAPIError(statusCode int, body []byte) *APIError {
+	apiErr := &APIError{StatusCode: statusCode, Body: string(body)}
+
+	var payload struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &payload); err == nil {
+		apiErr.Code = payload.Type
+		apiErr.Message = payload.Message
+	}
+	return apiErr
+}
@@ -0,0 +1,140 @@
+package scaleway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeScaleway is an httptest server standing in for the Scaleway event APIs.
+// It serves canned pages per path, selected by the page query parameter, and
+// records the query of every request it receives.
+type fakeScaleway struct {
+	t      *testing.T
+	server *httptest.Server
+
+	mu       sync.Mutex
+	pages    map[string][]string
+	listKeys map[string]string
+	failures map[string]fakeFailure
+	queries  map[string][]url.Values
+}
+
+// fakeFailure is an error response served instead of pages
+type fakeFailure struct {
+	status int
+	body   string
+}
+
+// newFakeScaleway starts a fake Scaleway API, closed when the test ends
+func newFakeScaleway(t *testing.T) *fakeScaleway {
+	t.Helper()
+	f := &fakeScaleway{
+		t:        t,
+		pages:    map[string][]string{},
+		listKeys: map[string]string{},
+		failures: map[string]fakeFailure{},
+		queries:  map[string][]url.Values{},
+	}
+	f.server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+// client returns a client for a single tenant pointed at the fake API
+func (f *fakeScaleway) client() *Client {
+	return NewClient("test-secret-key", "project-1", "org-1", f.server.URL)
+}
+
+// servePages serves entries under listKey at path, one page per argument.
+// Pages past the last are served empty.
+func (f *fakeScaleway) servePages(path, listKey string, pages ...[]map[string]any) {
+	bodies := make([]string, 0, len(pages))
+	for _, entries := range pages {
+		body, err := json.Marshal(map[string]any{listKey: entries, "total_count": len(entries)})
+		if err != nil {
+			f.t.Fatalf("failed to marshal fake page: %v", err)
+		}
+		bodies = append(bodies, string(body))
+	}
+	f.serveRaw(path, listKey, bodies...)
+}
+
+// serveRaw serves the given bodies verbatim as the pages of path
+func (f *fakeScaleway) serveRaw(path, listKey string, bodies ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pages[path] = bodies
+	f.listKeys[path] = listKey
+}
+
+// failWith answers every request to path with status and body
+func (f *fakeScaleway) failWith(path string, status int, body string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures[path] = fakeFailure{status: status, body: body}
+}
+
+// requests returns the queries of the requests made to path, in order
+func (f *fakeScaleway) requests(path string) []url.Values {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.queries[path]
+}
+
+func (f *fakeScaleway) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.queries[r.URL.Path] = append(f.queries[r.URL.Path], r.URL.Query())
+
+	if r.Header.Get("X-Auth-Token") == "" {
+		http.Error(w, `{"type":"denied_authentication","message":"missing token"}`, http.StatusUnauthorized)
+		return
+	}
+	if failure, ok := f.failures[r.URL.Path]; ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(failure.status)
+		fmt.Fprint(w, failure.body)
+		return
+	}
+	bodies, ok := f.pages[r.URL.Path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		http.Error(w, "invalid page", http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if page > len(bodies) {
+		fmt.Fprintf(w, `{%q: []}`, f.listKeys[r.URL.Path])
+		return
+	}
+	fmt.Fprint(w, bodies[page-1])
+}
+
+// auditEntries builds n raw audit events with IDs prefix-0..prefix-(n-1), a
+// minute apart starting at start
+func auditEntries(prefix string, start time.Time, n int) []map[string]any {
+	entries := make([]map[string]any, 0, n)
+	for idx := 0; idx < n; idx++ {
+		entries = append(entries, map[string]any{
+			"id":         fmt.Sprintf("%s-%d", prefix, idx),
+			"event_type": "iam.policy.update",
+			"actor":      "user@example.com",
+			"resource":   "iam",
+			"ip":         "203.0.113.10",
+			"timestamp":  start.Add(time.Duration(idx) * time.Minute).UTC().Format(time.RFC3339),
+		})
+	}
+	return entries
+}
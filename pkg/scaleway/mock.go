@@ -0,0 +1,153 @@
+package scaleway
+
+import (
+	"fmt"
+	"time"
+)
+
+// MockGenerator produces the fake event stream served in mock mode: a burst
+// of failed logins, an API key creation and a forbidden secrets access. The
+// zero value uses the wall clock and derives event IDs from it, so each call
+// yields new events.
+type MockGenerator struct {
+	// Now returns the time events are generated relative to; nil means time.Now
+	Now func() time.Time
+	// Seed, when non-zero, fixes the event IDs so repeated calls return the
+	// same events
+	Seed int64
+}
+
+// Events returns the mock events newer than since, tagged with source
+func (g *MockGenerator) Events(since *time.Time, source string) []*AuditEvent {
+	now := time.Now
+	if g.Now != nil {
+		now = g.Now
+	}
+	current := now().UTC()
+
+	eventIDBase := current.Unix()
+	if g.Seed != 0 {
+		eventIDBase = g.Seed
+	}
+	mock := []*AuditEvent{
+		{
+			ID:        fmt.Sprintf("evt_mock_%d_001", eventIDBase),
+			Type:      "auth.failed",
+			Actor:     "user@example.com",
+			Resource:  "iam",
+			IP:        "203.0.113.1",
+			Timestamp: current.Add(-10 * time.Minute),
+			Source:    source,
+			Raw: map[string]any{
+				"event_id": fmt.Sprintf("evt_mock_%d_001", eventIDBase),
+				"type":     "auth.failed",
+				"actor":    "user@example.com",
+				"reason":   "invalid_credentials",
+			},
+		},
+		{
+			ID:        fmt.Sprintf("evt_mock_%d_002", eventIDBase),
+			Type:      "auth.failed",
+			Actor:     "user@example.com",
+			Resource:  "iam",
+			IP:        "203.0.113.1",
+			Timestamp: current.Add(-8 * time.Minute),
+			Source:    source,
+			Raw: map[string]any{
+				"event_id": fmt.Sprintf("evt_mock_%d_002", eventIDBase),
+				"type":     "auth.failed",
+				"actor":    "user@example.com",
+				"reason":   "invalid_credentials",
+			},
+		},
+		{
+			ID:        fmt.Sprintf("evt_mock_%d_003", eventIDBase),
+			Type:      "auth.failed",
+			Actor:     "user@example.com",
+			Resource:  "iam",
+			IP:        "203.0.113.2",
+			Timestamp: current.Add(-5 * time.Minute),
+			Source:    source,
+			Raw: map[string]any{
+				"event_id": fmt.Sprintf("evt_mock_%d_003", eventIDBase),
+				"type":     "auth.failed",
+				"actor":    "user@example.com",
+				"reason":   "invalid_credentials",
+			},
+		},
+		{
+			ID:        fmt.Sprintf("evt_mock_%d_006", eventIDBase),
+			Type:      "auth.failed",
+			Actor:     "user@example.com",
+			Resource:  "iam",
+			IP:        "203.0.113.1",
+			Timestamp: current.Add(-4 * time.Minute),
+			Source:    source,
+			Raw: map[string]any{
+				"event_id": fmt.Sprintf("evt_mock_%d_006", eventIDBase),
+				"type":     "auth.failed",
+				"actor":    "user@example.com",
+				"reason":   "invalid_credentials",
+			},
+		},
+		{
+			ID:        fmt.Sprintf("evt_mock_%d_007", eventIDBase),
+			Type:      "auth.failed",
+			Actor:     "user@example.com",
+			Resource:  "iam",
+			IP:        "203.0.113.3",
+			Timestamp: current.Add(-2 * time.Minute),
+			Source:    source,
+			Raw: map[string]any{
+				"event_id": fmt.Sprintf("evt_mock_%d_007", eventIDBase),
+				"type":     "auth.failed",
+				"actor":    "user@example.com",
+				"reason":   "invalid_credentials",
+			},
+		},
+		{
+			ID:        fmt.Sprintf("evt_mock_%d_004", eventIDBase),
+			Type:      "apiKey.create",
+			Actor:     "admin@example.com",
+			Resource:  "iam",
+			IP:        "198.51.100.1",
+			Timestamp: current.Add(-3 * time.Minute),
+			Source:    source,
+			Raw: map[string]any{
+				"event_id": fmt.Sprintf("evt_mock_%d_004", eventIDBase),
+				"type":     "apiKey.create",
+				"actor":    "admin@example.com",
+				"key_id":   "key_abc123",
+				"key_name": "Production API Key",
+			},
+		},
+		{
+			ID:        fmt.Sprintf("evt_mock_%d_005", eventIDBase),
+			Type:      "forbidden",
+			Actor:     "attacker@example.com",
+			Resource:  "secrets",
+			IP:        "192.0.2.1",
+			Timestamp: current.Add(-1 * time.Minute),
+			Source:    source,
+			Raw: map[string]any{
+				"event_id": fmt.Sprintf("evt_mock_%d_005", eventIDBase),
+				"type":     "forbidden",
+				"actor":    "attacker@example.com",
+				"resource": "secrets/database-password",
+				"action":   "read",
+			},
+		},
+	}
+
+	if since == nil {
+		return mock
+	}
+
+	var filtered []*AuditEvent
+	for _, evt := range mock {
+		if evt.Timestamp.After(*since) {
+			filtered = append(filtered, evt)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,95 @@
+package scaleway
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func fixedClock(t time.Time) func() time.Time {
+	return func() time.Time { return t }
+}
+
+func TestMockGeneratorIsReproducible(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	generator := &MockGenerator{Now: fixedClock(now), Seed: 42}
+
+	first := generator.Events(nil, "audit")
+	second := generator.Events(nil, "audit")
+	if !reflect.DeepEqual(first, second) {
+		t.Fatal("repeated calls with the same clock and seed returned different events")
+	}
+	if len(first) != 7 {
+		t.Fatalf("got %d events, want 7", len(first))
+	}
+	if first[0].ID != "evt_mock_42_001" || !first[0].Timestamp.Equal(now.Add(-10*time.Minute)) {
+		t.Errorf("first event = %s at %s, want evt_mock_42_001 ten minutes before now", first[0].ID, first[0].Timestamp)
+	}
+	for _, event := range first {
+		if event.Source != "audit" {
+			t.Errorf("event %s has source %q, want audit", event.ID, event.Source)
+		}
+		if event.Raw["event_id"] != event.ID {
+			t.Errorf("event %s has raw event_id %v", event.ID, event.Raw["event_id"])
+		}
+	}
+
+	other := (&MockGenerator{Now: fixedClock(now), Seed: 7}).Events(nil, "audit")
+	if other[0].ID == first[0].ID {
+		t.Errorf("seeds 42 and 7 both produced event ID %s", first[0].ID)
+	}
+}
+
+func TestMockGeneratorDerivesIDsFromClockWithoutSeed(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	events := (&MockGenerator{Now: fixedClock(now)}).Events(nil, "auth")
+	if want := "evt_mock_1709294400_001"; events[0].ID != want {
+		t.Errorf("first event ID = %s, want %s", events[0].ID, want)
+	}
+
+	later := (&MockGenerator{Now: fixedClock(now.Add(time.Second))}).Events(nil, "auth")
+	if later[0].ID == events[0].ID {
+		t.Error("unseeded generator reused event IDs a second later")
+	}
+}
+
+func TestMockGeneratorFiltersSince(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	generator := &MockGenerator{Now: fixedClock(now), Seed: 1}
+
+	// Only the events from the last three minutes are strictly after since
+	since := now.Add(-4 * time.Minute)
+	events := generator.Events(&since, "audit")
+	var ids []string
+	for _, event := range events {
+		if !event.Timestamp.After(since) {
+			t.Errorf("event %s at %s is not after since", event.ID, event.Timestamp)
+		}
+		ids = append(ids, event.ID)
+	}
+	want := []string{"evt_mock_1_007", "evt_mock_1_004", "evt_mock_1_005"}
+	if !reflect.DeepEqual(ids, want) {
+		t.Errorf("event IDs = %v, want %v", ids, want)
+	}
+}
+
+func TestClientServesConfiguredMockGenerator(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	client := NewClient("test-secret-key", "project-1", "", "http://127.0.0.1:0")
+	client.SetMock(true)
+	client.SetMockGenerator(&MockGenerator{Now: fixedClock(now), Seed: 42})
+
+	events, err := client.FetchAuthenticationEvents(context.Background(), Tenant{ProjectID: "project-1"}, nil)
+	if err != nil {
+		t.Fatalf("FetchAuthenticationEvents: %v", err)
+	}
+	if len(events) != 7 || events[0].ID != "evt_mock_42_001" {
+		t.Fatalf("got %d events starting with %v, want the seeded mock stream", len(events), firstID(events))
+	}
+	for _, event := range events {
+		if event.Source != "auth" || event.Tenant.ProjectID != "project-1" {
+			t.Errorf("event %s tagged %q/%q, want auth/project-1", event.ID, event.Source, event.Tenant.ProjectID)
+		}
+	}
+}